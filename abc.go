@@ -0,0 +1,73 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+)
+
+// ABCOver is the ABC size threshold, 0 disables the check
+var ABCOver float64
+
+func init() {
+	Analyzer.Flags.Float64Var(&ABCOver, "abcover", 0, "print functions with the ABC size > N (0 disables the check)")
+}
+
+// ABCMetrics returns the assignments, branches and conditions counts of the function
+// along with the ABC size sqrt(A²+B²+C²) rounded to one decimal.
+// Function literals are units on their own and are not counted into the enclosing function.
+func ABCMetrics(fd *ast.FuncDecl) (a, b, c int, size float64) {
+	if fd.Body != nil {
+		a, b, c = countABC(fd.Body)
+	}
+	size = math.Sqrt(float64(a*a + b*b + c*c))
+	size = math.Round(size*10) / 10
+	return
+}
+
+// countABC counts
+//   - assignments: every assigned operand of =, := and op-assign, and ++/--
+//   - branches: function calls, go and defer statements
+//   - conditions: if and for conditions, non-default case clauses, && and || operators and unary !
+func countABC(n ast.Node) (a, b, c int) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			a += len(n.Lhs)
+		case *ast.IncDecStmt:
+			a++
+		case *ast.CallExpr:
+			b++
+		case *ast.GoStmt:
+			b++
+		case *ast.DeferStmt:
+			b++
+		case *ast.IfStmt:
+			c++
+		case *ast.ForStmt:
+			if n.Cond != nil {
+				c++
+			}
+		case *ast.CaseClause:
+			if n.List != nil {
+				c++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				c++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				c++
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.NOT {
+				c++
+			}
+		}
+		return true
+	})
+	return
+}
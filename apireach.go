@@ -0,0 +1,226 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// APIReachType is the complexity transitively reachable from an exported function
+type APIReachType struct {
+	FuncResult
+	// ReachedComplexity is the sum of the Cyclomatic complexities of all package-local
+	// functions reachable from this one, itself included, each counted once
+	ReachedComplexity int
+	// ExternalCalls is the number of distinct functions of other packages called by the reachable functions
+	ExternalCalls int
+}
+
+// callGraph is the intra-package call graph, nodes are indexes of the analyzed functions
+type callGraph struct {
+	calls    [][]int
+	external []map[*types.Func]bool
+}
+
+// buildCallGraph resolves the static calls of each function, closures included
+func buildCallGraph(info *types.Info, pkg *types.Package, decls []*ast.FuncDecl) callGraph {
+	index := map[*types.Func]int{}
+	for i, fd := range decls {
+		if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+			index[fn] = i
+		}
+	}
+	g := callGraph{calls: make([][]int, len(decls)), external: make([]map[*types.Func]bool, len(decls))}
+	for i, fd := range decls {
+		g.external[i] = map[*types.Func]bool{}
+		if fd.Body == nil {
+			continue
+		}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fn := calleeOf(info, call)
+			if fn == nil {
+				return true
+			}
+			if j, ok := index[fn]; ok {
+				g.calls[i] = append(g.calls[i], j)
+			} else if fn.Pkg() != nil && fn.Pkg() != pkg {
+				g.external[i][fn] = true
+			}
+			return true
+		})
+	}
+	return g
+}
+
+// calleeOf returns the statically called function, nil for builtins, conversions and dynamic calls
+func calleeOf(info *types.Info, call *ast.CallExpr) *types.Func {
+	fun := ast.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr: // generic instantiation
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+	var obj types.Object
+	switch f := fun.(type) {
+	case *ast.Ident:
+		obj = info.Uses[f]
+	case *ast.SelectorExpr:
+		obj = info.Uses[f.Sel]
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	return fn.Origin()
+}
+
+// fanIn returns for each function the number of distinct package-local functions calling it
+func (g callGraph) fanIn() []int {
+	res := make([]int, len(g.calls))
+	for i, calls := range g.calls {
+		seen := map[int]bool{}
+		for _, j := range calls {
+			if j != i && !seen[j] {
+				seen[j] = true
+				res[j]++
+			}
+		}
+	}
+	return res
+}
+
+// sccs returns the strongly connected components (Tarjan), in reverse topological order
+func (g callGraph) sccs() [][]int {
+	index, low, onStack := make([]int, len(g.calls)), make([]int, len(g.calls)), make([]bool, len(g.calls))
+	for i := range index {
+		index[i] = -1
+	}
+	stack, comps, next := []int{}, [][]int{}, 0
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.calls[v] {
+			if index[w] == -1 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] == index[v] {
+			comp := []int{}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+			comps = append(comps, comp)
+		}
+	}
+	for v := range g.calls {
+		if index[v] == -1 {
+			visit(v)
+		}
+	}
+	return comps
+}
+
+// reach returns for each function the set of functions reachable from it, itself included.
+// Functions of a cycle share the same set, computed once on the condensed graph.
+func (g callGraph) reach() []map[int]bool {
+	comps := g.sccs()
+	compOf := make([]int, len(g.calls))
+	for c, comp := range comps {
+		for _, v := range comp {
+			compOf[v] = c
+		}
+	}
+	// reverse topological order means callees' components are completed first
+	compReach := make([]map[int]bool, len(comps))
+	for c, comp := range comps {
+		r := map[int]bool{}
+		for _, v := range comp {
+			r[v] = true
+			for _, w := range g.calls[v] {
+				if compOf[w] != c {
+					for x := range compReach[compOf[w]] {
+						r[x] = true
+					}
+				}
+			}
+		}
+		compReach[c] = r
+	}
+	res := make([]map[int]bool, len(g.calls))
+	for v := range res {
+		res[v] = compReach[compOf[v]]
+	}
+	return res
+}
+
+// isAPIFunc tells if the function is part of the package API,
+// i.e. exported function or exported method of an exported type
+func isAPIFunc(fd *ast.FuncDecl) bool {
+	if !fd.Name.IsExported() || isFuncLitDecl(fd) {
+		return false
+	}
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return true
+	}
+	if name := recvTypeName(fd.Recv.List[0].Type); name != "" {
+		return ast.IsExported(name)
+	}
+	return true
+}
+
+// recvTypeName returns the receiver's base type name, without pointer and type parameters
+func recvTypeName(exp ast.Expr) string {
+	switch t := exp.(type) {
+	case *ast.StarExpr:
+		return recvTypeName(t.X)
+	case *ast.ParenExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// calcAPIReach computes the reached complexity of each API function, sorted from the highest
+func calcAPIReach(g callGraph, decls []*ast.FuncDecl, funcs []FuncResult) []APIReachType {
+	reach := g.reach()
+	arr := []APIReachType{}
+	for i, fd := range decls {
+		if !isAPIFunc(fd) {
+			continue
+		}
+		r := APIReachType{FuncResult: funcs[i]}
+		external := map[*types.Func]bool{}
+		for j := range reach[i] {
+			r.ReachedComplexity += funcs[j].CyclomaticComplexity
+			for fn := range g.external[j] {
+				external[fn] = true
+			}
+		}
+		r.ExternalCalls = len(external)
+		arr = append(arr, r)
+	}
+	sort.SliceStable(arr, func(i, j int) bool { return arr[i].ReachedComplexity > arr[j].ReachedComplexity })
+	return arr
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to write a shields.io endpoint badge of the maintainability of all analyzed functions at the end of the run
+var badgePath string
+
+// badgeColors are the colors of the best grades, from the best one, the other grades are red
+var badgeColors = []string{"green", "yellow", "orange"}
+
+// badgeCounts sums the metrics of all analyzed functions, of all packages
+type badgeCounts struct {
+	Functions int
+	Cyclo     int
+	Maint     int
+}
+
+// gathered sums, written as badge when badgePath is set
+var badgeTotals = badgeCounts{}
+
+func (c *badgeCounts) add(s complexity.FuncStatsType) {
+	c.Functions++
+	c.Cyclo += s.CyclomaticComplexity
+	c.Maint += s.MaintenabilityIndex
+}
+
+// shieldsEndpoint is the JSON of a shields.io endpoint badge, see https://shields.io/badges/endpoint-badge
+type shieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// newBadge grades the average Cyclomatic complexity and Maintainability index of the functions,
+// so each function weighs the same whatever its package. Without functions the index is 100.
+func newBadge(c badgeCounts) shieldsEndpoint {
+	cyclo, maint := 1, 100
+	if c.Functions > 0 {
+		cyclo = int(math.Round(float64(c.Cyclo) / float64(c.Functions)))
+		maint = int(math.Round(float64(c.Maint) / float64(c.Functions)))
+	}
+	grade := complexity.GradeOf(cyclo, maint)
+	color := "red"
+	for i, label := range complexity.GradeLabels() {
+		if label == grade && i < len(badgeColors) {
+			color = badgeColors[i]
+			break
+		}
+	}
+	return shieldsEndpoint{SchemaVersion: 1, Label: "maintainability", Message: fmt.Sprintf("%s (%d)", grade, maint), Color: color}
+}
+
+func writeBadge(path string, b shieldsEndpoint) error {
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0o644)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// file of the baseline results, failing the run only on regressions against them
+var baselinePath string
+
+// flag option only in standalone cmdline mode
+// to record the results of all functions in baselinePath, instead of checking against it
+var writeBaselineFile bool
+
+// the baseline results read at start, checked against at the end
+var baseline gobResults
+
+// gathered function stats of the run, written to or checked against the baseline
+var baselineFuncs = []complexity.FuncStatsType{}
+
+// configureBaseline reads the baseline, unless it is to be written
+func configureBaseline() error {
+	if writeBaselineFile && baselinePath == "" {
+		return fmt.Errorf("-write-baseline needs the file given by -baseline")
+	}
+	if baselinePath == "" || writeBaselineFile {
+		return nil
+	}
+	var err error
+	baseline, err = readBaseline(baselinePath)
+	return err
+}
+
+// collectBaseline keeps the function, named like in the outputs so the regressions point to it
+func collectBaseline(stats complexity.FuncStatsType) {
+	stats.Filename = printedPath(stats.Filename, "")
+	baselineFuncs = append(baselineFuncs, stats)
+}
+
+// readBaseline reads the json results written by -write-baseline
+func readBaseline(name string) (gobResults, error) {
+	res := gobResults{}
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(buf, &res); err != nil {
+		return res, fmt.Errorf("decoding %s: %v", name, err)
+	}
+	if res.SchemaVersion != gobSchemaVersion {
+		return res, fmt.Errorf("%s: unsupported schema version %d, expected %d", name, res.SchemaVersion, gobSchemaVersion)
+	}
+	return res, nil
+}
+
+func writeBaseline(name string, res gobResults) error {
+	buf, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(buf, '\n'), 0o644)
+}
+
+// hasRegressions writes the baseline, or tells if the run regressed against it:
+// a function with higher cyclomatic complexity, lower maintainability index or new violations,
+// or an added function with violations, like the compare subcommand. The regressions are printed to stderr,
+// the violations present in the baseline do not fail the run.
+func hasRegressions(arr []foundDiagnosticsStruct) bool {
+	_, failed := countViolations(arr)
+	if writeBaselineFile {
+		if err := writeBaseline(baselinePath, newGobResults(baselineFuncs, false)); err != nil {
+			log.Printf("writing baseline: %v", err)
+			return true
+		}
+		log.Printf("baseline of %d functions written to %s", len(baselineFuncs), baselinePath)
+		return failed
+	}
+	regressions := []funcChange{}
+	for _, c := range compareFuncs(baseline.Functions, baselineFuncs) {
+		if c.Regression() {
+			regressions = append(regressions, c)
+		}
+	}
+	doPrintChanges(os.Stderr, regressions)
+	if len(regressions) > 0 {
+		log.Printf("%d regressions against the baseline %s", len(regressions), baselinePath)
+	}
+	return failed || len(regressions) > 0
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"go/token"
 	"log"
+	"os"
+	"sort"
 	"strings"
 
+	"github.com/fikin/go-complexity-analysis"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/packages"
 )
@@ -15,26 +21,128 @@ type foundDiagnosticsStruct struct {
 	pkg         *packages.Package
 	diagnostics []analysis.Diagnostic
 	err         error
+	// funcViolations are the violations of the functions, one per violated rule of each function,
+	// whether or not -report reports them as diagnostics
+	funcViolations int
 }
 
-func run(args []string, analyzer *analysis.Analyzer) (exitcode int) {
-	pkg, err := load(args)
+// exitPartial is the exit code when the analysis was stopped before all packages were analyzed
+const exitPartial = 4
+
+func run(ctx context.Context, args []string, analyzer *analysis.Analyzer) (exitcode int) {
+	pkg, err := load(ctx, args)
+	timings.mark("load")
 	if err != nil {
+		if ctx.Err() != nil {
+			log.Printf("analysis stopped while loading: %v", stopReason(ctx.Err()))
+			return strictExitCode(exitPartial, []string{fmt.Sprintf("all packages: the analysis was stopped while loading: %v", stopReason(ctx.Err()))})
+		}
 		log.Print(err)
 		return 1 // load errors
 	}
 
+	configureModules(pkg)
+	configureCoupling(pkg)
 	analyzers := deepScanRequires(analyzer)
 
-	foundDiagnostics := analyze(pkg, analyzers)
+	// deterministic order, so runs stopped by the time budget cover the same packages
+	sort.Slice(pkg, func(i, j int) bool { return pkg[i].PkgPath < pkg[j].PkgPath })
+	foundDiagnostics, skipped, err := analyze(ctx, pkg, analyzers)
+	timings.mark("analyze")
+	totals.SkippedPackages = skipped
+	timings.partial, timings.skipped = err != nil, skipped
 
-	printDiagnostics(foundDiagnostics)
+	checkstyles.Partial = err != nil
+	if streaming() {
+		printReports()
+	} else {
+		printDiagnostics(foundDiagnostics)
+	}
+	timings.mark("report")
+	if printStats {
+		printRunStats(timings)
+	} else if timings.partial && outputFormat == jsonFormat {
+		writeJSONLine(jsonOut, newJSONStats(timings))
+	}
 
-	if len(foundDiagnostics) > 0 {
-		return 1
+	if err != nil {
+		log.Printf("partial results, analysis stopped: %v", stopReason(err))
+		log.Printf("skipped packages: %s", strings.Join(skipped, " "))
+		return strictExitCode(exitPartial, notComputed(foundDiagnostics, skipped, err))
 	}
-	return 0
+	failed := hasViolations
+	if baselinePath != "" {
+		failed = hasRegressions
+	}
+	if failed(foundDiagnostics) || outputErr != nil {
+		exitcode = 1
+	}
+	return strictExitCode(exitcode, notComputed(foundDiagnostics, nil, nil))
+
+}
+
+// configureModules classifies the imports of each package by its own module, unless -module-path is given
+func configureModules(pkgs []*packages.Package) {
+	modules := map[string]string{}
+	for _, pkg := range pkgs {
+		if pkg.Module != nil {
+			modules[pkg.PkgPath] = pkg.Module.Path
+		}
+	}
+	complexity.ModuleOf = func(pkgPath string) string {
+		if complexity.ModulePath != "" {
+			return complexity.ModulePath
+		}
+		return modules[pkgPath]
+	}
+}
+
+// stopReason explains why the context was cancelled
+func stopReason(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("time budget of %s exceeded", timeBudget)
+	}
+	return err
+}
 
+// hasViolations tells if the findings fail the run: any analysis error, or more violations than -maxissues.
+// With a budget, the violations are counted against it in a log line.
+func hasViolations(arr []foundDiagnosticsStruct) bool {
+	n, failed := countViolations(arr)
+	if maxIssues > 0 {
+		if n > maxIssues {
+			log.Printf("%d violations, over the budget of %d", n, maxIssues)
+		} else {
+			log.Printf("%d violations (budget %d)", n, maxIssues)
+		}
+	}
+	return failed || n > maxIssues
+}
+
+// countViolations counts the findings affecting the exit code, failed is set on any analysis error.
+// The functions count once per violated rule, like in the -summary, not by their diagnostics,
+// which depend on -report, while the other findings, like of packages and types, count by their diagnostics.
+func countViolations(arr []foundDiagnosticsStruct) (n int, failed bool) {
+	for _, f := range arr {
+		if f.err != nil {
+			failed = true
+		}
+		n += f.funcViolations
+		for _, d := range f.diagnostics {
+			if d.Category != "" && countsAsViolation(d) {
+				n++
+			}
+		}
+	}
+	return n, failed
+}
+
+// funcViolationsOf returns the function violations of the analyzer result, 0 for other analyzers
+func funcViolationsOf(res interface{}) int {
+	if r, ok := res.(*complexity.Result); ok {
+		return r.Violations
+	}
+	return 0
 }
 
 // deepScanRequires deep-scans Requires fields and returns the ordered array of analyzers
@@ -51,16 +159,17 @@ func deepScanRequires(analyzer *analysis.Analyzer) []*analysis.Analyzer {
 }
 
 // load loads the packages.
-func load(patterns []string) ([]*packages.Package, error) {
+func load(ctx context.Context, patterns []string) ([]*packages.Package, error) {
 	conf := packages.Config{
+		Context: ctx,
 		// nolint:staticcheck
-		Mode:       packages.LoadSyntax,
+		Mode:       packages.LoadSyntax | packages.NeedDeps | packages.NeedModule,
 		Tests:      theConfig.Run.Tests,
 		BuildFlags: formBuildTags(theConfig.Run.BuildTags),
 	}
 	pkgs, err := packages.Load(&conf, patterns...)
 	if err == nil {
-		if n := packages.PrintErrors(pkgs); n > 1 {
+		if n := printLoadErrors(pkgs); n > 1 {
 			err = fmt.Errorf("%d errors during loading", n)
 		} else if n == 1 {
 			err = fmt.Errorf("error during loading")
@@ -72,6 +181,36 @@ func load(patterns []string) ([]*packages.Package, error) {
 	return pkgs, err
 }
 
+// printLoadErrors prints the errors of the packages and their dependencies, like packages.PrintErrors does,
+// except for the given packages with files failing to parse. Those are reported as findings by analyze,
+// while the rest of their errors, like type errors, follow from them.
+func printLoadErrors(pkgs []*packages.Package) int {
+	roots := map[*packages.Package]bool{}
+	for _, pkg := range pkgs {
+		roots[pkg] = true
+	}
+	n := 0
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if roots[pkg] && hasParseErrors(pkg) {
+			return
+		}
+		for _, err := range pkg.Errors {
+			fmt.Fprintln(os.Stderr, err)
+			n++
+		}
+	})
+	return n
+}
+
+func hasParseErrors(pkg *packages.Package) bool {
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.ParseError {
+			return true
+		}
+	}
+	return false
+}
+
 func formBuildTags(buildTags []string) []string {
 	if len(buildTags) == 0 {
 		return buildTags
@@ -79,18 +218,34 @@ func formBuildTags(buildTags []string) []string {
 	return []string{"--tags", strings.Join(buildTags, ",")}
 }
 
-func analyze(pkgs []*packages.Package, analyzers []*analysis.Analyzer) []foundDiagnosticsStruct {
+// analyze runs the analyzers over the packages until done or the context is cancelled,
+// in which case the diagnostics found so far are returned along with the paths of the skipped packages
+// and the context error.
+func analyze(ctx context.Context, pkgs []*packages.Package, analyzers []*analysis.Analyzer) ([]foundDiagnosticsStruct, []string, error) {
 	d := []foundDiagnosticsStruct{}
-	for _, pkg := range pkgs {
+	for i, pkg := range pkgs {
+		before := len(d)
+		if err := ctx.Err(); err != nil {
+			skipped := []string{}
+			for _, p := range pkgs[i:] {
+				skipped = append(skipped, p.PkgPath)
+			}
+			return d, skipped, err
+		}
+		if diags := takeParseErrors(pkg); len(diags) > 0 {
+			d = append(d, foundDiagnosticsStruct{pkg: pkg, diagnostics: diags})
+		}
 		analyzerResults := analyzerResultsType{}
 		for _, a := range analyzers {
 			diags, err := analyzePkg(&analyzerResults, pkg, a)
-			if err != nil || len(diags) > 0 {
-				d = append(d, foundDiagnosticsStruct{pkg: pkg, diagnostics: diags, err: err})
+			violations := funcViolationsOf(analyzerResults[a])
+			if err != nil || len(diags) > 0 || violations > 0 {
+				d = append(d, foundDiagnosticsStruct{pkg: pkg, diagnostics: diags, err: err, funcViolations: violations})
 			}
 		}
+		packageAnalyzed(d[before:], i+1, len(pkgs))
 	}
-	return d
+	return d, nil, nil
 }
 
 func analyzePkg(results *analyzerResultsType, pkg *packages.Package, a *analysis.Analyzer) ([]analysis.Diagnostic, error) {
@@ -126,7 +281,39 @@ func doPrintDiagnostics(arr []foundDiagnosticsStruct) {
 			fmt.Printf("%s : %v\n", f.pkg.Name, f.err)
 		}
 		for _, d := range f.diagnostics {
-			fmt.Printf("%s : %d : %s\n", f.pkg.Name, d.Pos, d.Message)
+			msg := printedMessage(diagnosticFilename(f.pkg, d), d.Message, "")
+			if s, ok := coloredFuncOf(f.pkg, d); ok {
+				msg = colorMessage(msg, s, colorOn)
+			}
+			if len(d.Related) == 0 {
+				fmt.Printf("%s : %d : %s\n", f.pkg.Name, d.Pos, msg)
+				continue
+			}
+			// the related information of -explain is listed below the message, before its blank line
+			fmt.Printf("%s : %d : %s\n", f.pkg.Name, d.Pos, strings.TrimSuffix(msg, "\n"))
+			for _, r := range d.Related {
+				fmt.Printf("\t%s: %s\n", relatedPosition(f.pkg, r.Pos), r.Message)
+			}
+			if strings.HasSuffix(msg, "\n") {
+				fmt.Println()
+			}
 		}
 	}
 }
+
+// relatedPosition returns the file:line:column of the related information, like printedPath
+func relatedPosition(pkg *packages.Package, pos token.Pos) string {
+	if pkg.Fset == nil || !pos.IsValid() {
+		return "-"
+	}
+	p := pkg.Fset.Position(pos)
+	return fmt.Sprintf("%s:%d:%d", printedPath(p.Filename, ""), p.Line, p.Column)
+}
+
+// diagnosticFilename returns the name of the file of the diagnostic, "" if unknown
+func diagnosticFilename(pkg *packages.Package, d analysis.Diagnostic) string {
+	if pkg.Fset == nil || !d.Pos.IsValid() {
+		return ""
+	}
+	return pkg.Fset.Position(d.Pos).Filename
+}
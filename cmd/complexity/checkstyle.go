@@ -24,6 +24,7 @@ type checkstyleFileTag struct {
 type checkstyleTag struct {
 	XMLName    xml.Name `xml:"checkstyle"`
 	Version    string   `xml:"version,attr"`
+	Partial    bool     `xml:"partial,attr,omitempty"`
 	filesAsMap map[string]checkstyleFileTag
 	Files      []checkstyleFileTag
 }
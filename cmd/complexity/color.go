@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// color modes of the txt output
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// flag option only in standalone cmdline mode
+// one of : auto, always, never
+var colorMode = colorAuto
+
+// colorOn colors the txt output, resolved from the colorMode
+var colorOn bool
+
+// ANSI escape codes
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+)
+
+// reported functions by their file:line, to color their txt findings by severity
+var coloredFuncs = map[string]complexity.FuncStatsType{}
+
+// configureColor resolves the colorMode: auto colors when stdout is a terminal, unless NO_COLOR is set.
+// Only txt output is colored, the other formats are meant for tools.
+func configureColor() error {
+	switch colorMode {
+	case colorAlways:
+		colorOn = true
+	case colorNever:
+		colorOn = false
+	case colorAuto:
+		colorOn = os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("unknown color mode %q, valid are: %s, %s, %s", colorMode, colorAuto, colorAlways, colorNever)
+	}
+	colorOn = colorOn && outputFormat == "txt"
+	return nil
+}
+
+// isTerminal tells if the file is a character device, like a terminal, and not a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func funcKey(filename string, line int) string {
+	return fmt.Sprintf("%s:%d", filename, line)
+}
+
+// coloredFuncOf returns the function of a function finding, when the output is colored
+func coloredFuncOf(pkg *packages.Package, d analysis.Diagnostic) (complexity.FuncStatsType, bool) {
+	if !colorOn || d.Category != "" || pkg.Fset == nil || !d.Pos.IsValid() {
+		return complexity.FuncStatsType{}, false
+	}
+	p := pkg.Fset.Position(d.Pos)
+	s, ok := coloredFuncs[funcKey(p.Filename, p.Line)]
+	return s, ok
+}
+
+// severe tells if the first violation of the function is far from its threshold, see severeRule
+func severe(s complexity.FuncStatsType) bool {
+	rules := complexity.Violations(s)
+	return len(rules) > 0 && severeRule(s, rules[0])
+}
+
+// severeRule tells if the value of the violated rule is far from its threshold:
+// over twice the threshold, or a Maintainability index under half of it
+func severeRule(s complexity.FuncStatsType, rule string) bool {
+	value, threshold := ruleValue(s, rule)
+	if rule == "maint" {
+		return value < threshold/2
+	}
+	return threshold > 0 && value > 2*threshold
+}
+
+// ruleValue returns the value of the function checked by the rule and the threshold of the rule,
+// 0 and 0 for the rules without threshold, like recursion
+func ruleValue(s complexity.FuncStatsType, rule string) (value, threshold float64) {
+	switch rule {
+	case "cyclo":
+		return float64(s.CyclomaticComplexity), float64(s.CycloOver)
+	case "maint":
+		return float64(s.MaintenabilityIndex), float64(s.MaintUnder)
+	case "cognitive":
+		return float64(s.CognitiveComplexity), float64(complexity.CognitiveOver)
+	case "params":
+		return float64(s.Params), float64(complexity.ParamsOver)
+	case "results":
+		return float64(s.Results), float64(complexity.ResultsOver)
+	case "returns":
+		return float64(s.Returns), float64(complexity.ReturnsOver)
+	case "statements":
+		return float64(s.Statements), float64(complexity.StmtsOver)
+	case "loc":
+		return float64(s.SLOC), float64(complexity.LOCOver)
+	case "effort":
+		return s.HalsteadEffort, complexity.EffortOver
+	case "abc":
+		return s.ABCSize, complexity.ABCOver
+	case "fanout":
+		return float64(s.FanOut), float64(complexity.FanOutOver)
+	case "locals":
+		return float64(s.Locals), float64(complexity.LocalsOver)
+	case "concurrency":
+		return float64(s.ConcurrencyScore), float64(complexity.ConcOver)
+	case "defers":
+		return float64(s.Defers), float64(complexity.DefersOver)
+	case "nesting":
+		return float64(s.MaxNesting), float64(complexity.NestOver)
+	case "score":
+		return s.Score, complexity.ScoreOver
+	}
+	return 0, 0
+}
+
+// colorMessage bolds the function name of the finding and colors its violated value,
+// in red when severe and in yellow otherwise. The message is unchanged when on is false.
+func colorMessage(msg string, s complexity.FuncStatsType, on bool) string {
+	name := "func " + s.FunctionName
+	i := strings.Index(msg, name)
+	if !on || i < 0 {
+		return msg
+	}
+	i += len("func ")
+	j := i + len(s.FunctionName)
+	res := msg[:i] + ansiBold + s.FunctionName + ansiReset
+	from := strings.Index(msg[j:], "(")
+	to := strings.Index(msg[j:], ")")
+	if from < 0 || to < from {
+		return res + msg[j:]
+	}
+	color := ansiYellow
+	if severe(s) {
+		color = ansiRed
+	}
+	from, to = j+from, j+to+1
+	return res + msg[j:from] + color + msg[from:to] + ansiReset + msg[to:]
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// column is a single field of csv output
+type column struct {
+	name  string
+	value func(stats complexity.FuncStatsType) string
+}
+
+func intCol(name string, fnc func(stats complexity.FuncStatsType) int) column {
+	return column{name, func(stats complexity.FuncStatsType) string { return strconv.Itoa(fnc(stats)) }}
+}
+
+func floatCol(name string, fnc func(stats complexity.FuncStatsType) float64) column {
+	return column{name, func(stats complexity.FuncStatsType) string { return fmt.Sprintf("%0.3f", fnc(stats)) }}
+}
+
+func boolCol(name string, fnc func(stats complexity.FuncStatsType) bool) column {
+	return column{name, func(stats complexity.FuncStatsType) string { return strconv.FormatBool(fnc(stats)) }}
+}
+
+// defaultColumns are the columns printed by default, in their order
+var defaultColumns = []column{
+	{"filename", func(s complexity.FuncStatsType) string { return printedPath(s.Filename, currDir) }},
+	intCol("line", func(s complexity.FuncStatsType) int { return s.Line }),
+	{"name", func(s complexity.FuncStatsType) string { return s.FunctionName }},
+	intCol("cyclo", func(s complexity.FuncStatsType) int { return s.CyclomaticComplexity }),
+	intCol("maint", func(s complexity.FuncStatsType) int { return s.MaintenabilityIndex }),
+	floatCol("difficulty", func(s complexity.FuncStatsType) float64 { return s.HalsteadDifficulty }),
+	floatCol("volume", func(s complexity.FuncStatsType) float64 { return s.HalsteadVolume }),
+	floatCol("timetocode", func(s complexity.FuncStatsType) float64 { return s.TimeToCode }),
+	intCol("loc", func(s complexity.FuncStatsType) int { return s.LOC }),
+	intCol("declloc", func(s complexity.FuncStatsType) int { return s.ConstantsLOC }),
+	boolCol("toocomplex", func(s complexity.FuncStatsType) bool { return s.IsTooComplex }),
+	boolCol("notmaintainable", func(s complexity.FuncStatsType) bool { return s.IsNotMaintenable }),
+	boolCol("generated", func(s complexity.FuncStatsType) bool { return s.Generated }),
+	intCol("cognitive", func(s complexity.FuncStatsType) int { return s.CognitiveComplexity }),
+	intCol("params", func(s complexity.FuncStatsType) int { return s.Params }),
+	intCol("results", func(s complexity.FuncStatsType) int { return s.Results }),
+	intCol("returns", func(s complexity.FuncStatsType) int { return s.Returns }),
+	intCol("statements", func(s complexity.FuncStatsType) int { return s.Statements }),
+	intCol("sloc", func(s complexity.FuncStatsType) int { return s.SLOC }),
+	floatCol("effort", func(s complexity.FuncStatsType) float64 { return s.HalsteadEffort }),
+	floatCol("bugs", func(s complexity.FuncStatsType) float64 { return s.HalsteadBugs }),
+	intCol("abc-a", func(s complexity.FuncStatsType) int { return s.ABCAssignments }),
+	intCol("abc-b", func(s complexity.FuncStatsType) int { return s.ABCBranches }),
+	intCol("abc-c", func(s complexity.FuncStatsType) int { return s.ABCConditions }),
+	floatCol("abc", func(s complexity.FuncStatsType) float64 { return s.ABCSize }),
+	{"source", func(s complexity.FuncStatsType) string { return s.GenSource }},
+	intCol("todos", func(s complexity.FuncStatsType) int { return s.TodoMarkers }),
+	boolCol("suppressed", func(s complexity.FuncStatsType) bool { return s.Suppressed }),
+	{"suppressreason", func(s complexity.FuncStatsType) string { return s.SuppressReason }},
+	intCol("cycloover", func(s complexity.FuncStatsType) int { return s.CycloOver }),
+	intCol("maintunder", func(s complexity.FuncStatsType) int { return s.MaintUnder }),
+	{"grade", func(s complexity.FuncStatsType) string { return s.Grade }},
+	intCol("fanout", func(s complexity.FuncStatsType) int { return s.FanOut }),
+	intCol("locals", func(s complexity.FuncStatsType) int { return s.Locals }),
+	intCol("gostmts", func(s complexity.FuncStatsType) int { return s.GoStmts }),
+	intCol("chanops", func(s complexity.FuncStatsType) int { return s.ChanOps }),
+	intCol("selects", func(s complexity.FuncStatsType) int { return s.Selects }),
+	intCol("selectcases", func(s complexity.FuncStatsType) int { return s.SelectCases }),
+	intCol("synccalls", func(s complexity.FuncStatsType) int { return s.SyncCalls }),
+	intCol("concurrency", func(s complexity.FuncStatsType) int { return s.ConcurrencyScore }),
+	boolCol("recursive", func(s complexity.FuncStatsType) bool { return s.Recursive }),
+	boolCol("declonly", func(s complexity.FuncStatsType) bool { return s.DeclarationOnly }),
+	floatCol("score", func(s complexity.FuncStatsType) float64 { return s.Score }),
+	intCol("endline", func(s complexity.FuncStatsType) int { return s.EndLine }),
+	intCol("span", func(s complexity.FuncStatsType) int { return s.Span }),
+	intCol("nameline", func(s complexity.FuncStatsType) int { return s.NameLine }),
+	intCol("namecol", func(s complexity.FuncStatsType) int { return s.NameColumn }),
+	intCol("defers", func(s complexity.FuncStatsType) int { return s.Defers }),
+	intCol("defersinloop", func(s complexity.FuncStatsType) int { return s.DefersInLoop }),
+	intCol("maxlivedefers", func(s complexity.FuncStatsType) int { return s.MaxLiveDefers }),
+	intCol("nesting", func(s complexity.FuncStatsType) int { return s.MaxNesting }),
+}
+
+// typesColumns are the columns needing type information, left empty withoutTypes
+var typesColumns = map[string]bool{"recursive": true}
+
+// miCommentColumns are printed by default only with -mi-with-comments
+var miCommentColumns = []column{
+	intCol("comments", func(s complexity.FuncStatsType) int { return s.CommentLines }),
+	intCol("maintclassic", func(s complexity.FuncStatsType) int { return s.ClassicMaintIndex }),
+}
+
+// halsteadRawColumns are printed by default only with -halstead-raw
+var halsteadRawColumns = []column{
+	intCol("distinctoperators", func(s complexity.FuncStatsType) int { return s.HalsteadDistinctOperators }),
+	intCol("distinctoperands", func(s complexity.FuncStatsType) int { return s.HalsteadDistinctOperands }),
+	intCol("operators", func(s complexity.FuncStatsType) int { return s.HalsteadTotalOperators }),
+	intCol("operands", func(s complexity.FuncStatsType) int { return s.HalsteadTotalOperands }),
+	intCol("vocabulary", func(s complexity.FuncStatsType) int { return s.HalsteadVocabulary }),
+	intCol("length", func(s complexity.FuncStatsType) int { return s.HalsteadLength }),
+}
+
+// allColumns are all known columns, the default ones first
+var allColumns = append(append(append([]column{}, defaultColumns...), miCommentColumns...), halsteadRawColumns...)
+
+// selectedColumns are the columns printed in csv output
+var selectedColumns = defaultColumns
+
+// flag option only in standalone cmdline mode
+// to omit the csv header row, e.g. when appending to an existing file
+var csvNoHeader bool
+
+// flag option only in standalone cmdline mode
+// to print the unexported functions in csv output despite -exported-only, for their raw data
+var includeUnexportedInCSV bool
+
+// csvHeaderPrinted keeps the header to a single row per run, also when the findings are streamed per package
+var csvHeaderPrinted bool
+
+// configureColumns adds the columns of -mi-with-comments and -halstead-raw to the default ones
+func configureColumns() {
+	if explicitFlags()["columns"] {
+		return
+	}
+	cols := append([]column{}, defaultColumns...)
+	if complexity.MIWithComments {
+		cols = append(cols, miCommentColumns...)
+	}
+	if complexity.HalsteadRaw {
+		cols = append(cols, halsteadRawColumns...)
+	}
+	selectedColumns = cols
+}
+
+// columnsFlag is flag.Value selecting, in order, the csv output columns
+type columnsFlag struct{}
+
+func (columnsFlag) String() string {
+	return strings.Join(columnNames(selectedColumns), ",")
+}
+
+func (columnsFlag) Set(val string) error {
+	cols, err := parseColumns(val)
+	if err != nil {
+		return err
+	}
+	selectedColumns = cols
+	return nil
+}
+
+func columnNames(cols []column) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	return names
+}
+
+// parseColumns resolves comma separated column names, in the given order
+func parseColumns(val string) ([]column, error) {
+	cols := []column{}
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range allColumns {
+			if c.name == name {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q, valid columns are: %s", name, strings.Join(columnNames(allColumns), ","))
+		}
+	}
+	return cols, nil
+}
+
+// formatColumns returns the values of the columns of the function
+func formatColumns(cols []column, stats complexity.FuncStatsType) []string {
+	values := make([]string, len(cols))
+	for i, c := range cols {
+		if withoutTypes && typesColumns[c.name] {
+			continue
+		}
+		values[i] = c.value(stats)
+	}
+	return values
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// compareCmd is the subcommand comparing two gob encoded results and reporting the regressions
+const compareCmd = "compare"
+
+// kinds of function changes between two results
+const (
+	changeRegressed = "regressed"
+	changeAdded     = "added"
+	changeRemoved   = "removed"
+)
+
+// funcChange is a function differing between the old and the new results
+type funcChange struct {
+	Change string
+	Key    string
+	// Old and New are nil for added and removed functions respectively
+	Old, New *complexity.FuncStatsType
+	// Reasons of a regression, or the violated rules of an added function
+	Reasons []string
+}
+
+// Regression tells if the change makes things worse: a regressed function,
+// or an added one crossing a threshold
+func (c funcChange) Regression() bool {
+	return c.Change == changeRegressed || c.Change == changeAdded && len(c.Reasons) > 0
+}
+
+// compareKey identifies a function across results by its package and name, ignoring its position,
+// so moved functions are matched. Results written before the Package field fall back to the directory.
+func compareKey(s complexity.FuncStatsType) string {
+	pkg := s.Package
+	if pkg == "" {
+		pkg = filepath.Dir(s.Filename)
+	}
+	return pkg + "." + s.FunctionName
+}
+
+// keyedFuncs indexes the functions by compareKey, numbering the repeated ones like init functions
+func keyedFuncs(arr []complexity.FuncStatsType) map[string]*complexity.FuncStatsType {
+	res := map[string]*complexity.FuncStatsType{}
+	for i := range arr {
+		key := compareKey(arr[i])
+		for n := 2; res[key] != nil; n++ {
+			key = fmt.Sprintf("%s#%d", compareKey(arr[i]), n)
+		}
+		res[key] = &arr[i]
+	}
+	return res
+}
+
+// compareFuncs returns the added, removed and regressed functions, ordered by key.
+// A function regresses when its Cyclomatic complexity increases, its Maintainability index decreases,
+// or it violates rules it did not before.
+func compareFuncs(before, after []complexity.FuncStatsType) []funcChange {
+	oldFuncs, newFuncs := keyedFuncs(before), keyedFuncs(after)
+	res := []funcChange{}
+	for key, o := range oldFuncs {
+		if newFuncs[key] == nil {
+			res = append(res, funcChange{Change: changeRemoved, Key: key, Old: o})
+		}
+	}
+	for key, n := range newFuncs {
+		o := oldFuncs[key]
+		if o == nil {
+			res = append(res, funcChange{Change: changeAdded, Key: key, New: n, Reasons: complexity.Violations(*n)})
+			continue
+		}
+		reasons := []string{}
+		if n.CyclomaticComplexity > o.CyclomaticComplexity {
+			reasons = append(reasons, fmt.Sprintf("cyclomatic complexity %d -> %d", o.CyclomaticComplexity, n.CyclomaticComplexity))
+		}
+		if n.MaintenabilityIndex < o.MaintenabilityIndex {
+			reasons = append(reasons, fmt.Sprintf("maintainability index %d -> %d", o.MaintenabilityIndex, n.MaintenabilityIndex))
+		}
+		if rules := newRules(complexity.Violations(*o), complexity.Violations(*n)); len(rules) > 0 {
+			reasons = append(reasons, "newly over "+strings.Join(rules, ", "))
+		}
+		if len(reasons) > 0 {
+			res = append(res, funcChange{Change: changeRegressed, Key: key, Old: o, New: n, Reasons: reasons})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// newRules returns the rules violated now and not before
+func newRules(before, now []string) []string {
+	res := []string{}
+	for _, r := range now {
+		if !contains(before, r) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func contains(arr []string, s string) bool {
+	for _, a := range arr {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
+
+// doPrintChanges prints a line per change, at the position of the function in the new results,
+// or the old ones for removed functions
+func doPrintChanges(w io.Writer, changes []funcChange) {
+	for _, c := range changes {
+		s := c.New
+		if s == nil {
+			s = c.Old
+		}
+		msg := fmt.Sprintf("%s:%d: %s %s", s.Filename, s.Line, c.Change, c.Key)
+		if c.Change == changeAdded && len(c.Reasons) > 0 {
+			msg += ": over " + strings.Join(c.Reasons, ", ")
+		} else if len(c.Reasons) > 0 {
+			msg += ": " + strings.Join(c.Reasons, ", ")
+		}
+		fmt.Fprintln(w, msg)
+	}
+}
+
+// doPrintChangesCSV prints a csv row per change, with the old and new values, empty when missing
+func doPrintChangesCSV(w io.Writer, changes []funcChange) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"change", "func", "file", "line", "cyclo_old", "cyclo_new", "maint_old", "maint_new", "violations_old", "violations_new", "regression"})
+	value := func(s *complexity.FuncStatsType, f func(s complexity.FuncStatsType) string) string {
+		if s == nil {
+			return ""
+		}
+		return f(*s)
+	}
+	cyclo := func(s complexity.FuncStatsType) string { return strconv.Itoa(s.CyclomaticComplexity) }
+	maint := func(s complexity.FuncStatsType) string { return strconv.Itoa(s.MaintenabilityIndex) }
+	violations := func(s complexity.FuncStatsType) string { return strings.Join(complexity.Violations(s), "|") }
+	for _, c := range changes {
+		s := c.New
+		if s == nil {
+			s = c.Old
+		}
+		cw.Write([]string{
+			c.Change, c.Key, s.Filename, strconv.Itoa(s.Line),
+			value(c.Old, cyclo), value(c.New, cyclo),
+			value(c.Old, maint), value(c.New, maint),
+			value(c.Old, violations), value(c.New, violations),
+			strconv.FormatBool(c.Regression()),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func readGobFile(name string) (gobResults, error) {
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return gobResults{}, err
+	}
+	if strings.HasSuffix(name, ".csv") || looksLikeCSV(buf) {
+		return gobResults{}, fmt.Errorf("%s: csv results cannot be compared, as their rows lack the package paths the functions are matched by; compare -out-format gob results instead", name)
+	}
+	res, err := readGob(bytes.NewReader(buf))
+	if err != nil {
+		return res, fmt.Errorf("decoding %s: %v", name, err)
+	}
+	return res, nil
+}
+
+// looksLikeCSV tells if the results start with a text line of comma separated fields, like the csv header row
+func looksLikeCSV(buf []byte) bool {
+	line, _, _ := bytes.Cut(buf, []byte("\n"))
+	return bytes.ContainsRune(line, ',') && utf8.Valid(line) && !bytes.ContainsFunc(line, func(r rune) bool { return r < ' ' && r != '\t' && r != '\r' })
+}
+
+// runCompare compares two gob encoded results, printing the changed functions on stdout.
+// The exit code is 1 on regressions only, added and removed functions alone do not fail.
+func runCompare(args []string) (exitcode int) {
+	fs := flag.NewFlagSet(compareCmd, flag.ContinueOnError)
+	to := fs.String("to", "txt", "to print the changes as 'txt' or 'csv' (default 'txt')")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 2 {
+		log.Printf("usage: %s %s [-to txt|csv] <old.gob> <new.gob>", complexity.Analyzer.Name, compareCmd)
+		return 1
+	}
+	before, err := readGobFile(fs.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	after, err := readGobFile(fs.Arg(1))
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if before.HalsteadNormalization != after.HalsteadNormalization {
+		log.Printf("warning: the results differ in Halstead normalization, %s and %s", before.HalsteadNormalization, after.HalsteadNormalization)
+	}
+	changes := compareFuncs(before.Functions, after.Functions)
+	switch *to {
+	case "txt":
+		doPrintChanges(os.Stdout, changes)
+	case "csv":
+		if err := doPrintChangesCSV(os.Stdout, changes); err != nil {
+			log.Print(err)
+			return 1
+		}
+	default:
+		log.Printf("unknown -to %q, valid are: txt, csv", *to)
+		return 1
+	}
+	for _, c := range changes {
+		if c.Regression() {
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -14,15 +18,60 @@ import (
 
 var skipFiles []*regexp.Regexp
 var skipDirs []*regexp.Regexp
-var theConfig *ConfigFile
+var theConfig = &ConfigFile{}
 
 // ConfigFile is representing gocomplexity.yml file
 // format is similar to golangci-lint configuration file.
 type ConfigFile struct {
 	LintersSettings struct {
 		Complexity struct {
-			CycloOver  *int `yaml:"cyclo-over,omitempty" json:"cyclo-over,omitempty"`
-			MaintUnder *int `yaml:"maint-under,omitempty" json:"maint-under,omitempty"`
+			CycloOver         *int      `yaml:"cyclo-over,omitempty" json:"cyclo-over,omitempty"`
+			MaintUnder        *int      `yaml:"maint-under,omitempty" json:"maint-under,omitempty"`
+			CognitiveOver     *int      `yaml:"cognitive-over,omitempty" json:"cognitive-over,omitempty"`
+			ParamsOver        *int      `yaml:"params-over,omitempty" json:"params-over,omitempty"`
+			ResultsOver       *int      `yaml:"results-over,omitempty" json:"results-over,omitempty"`
+			ReturnsOver       *int      `yaml:"returns-over,omitempty" json:"returns-over,omitempty"`
+			StmtsOver         *int      `yaml:"stmts-over,omitempty" json:"stmts-over,omitempty"`
+			LOCOver           *int      `yaml:"loc-over,omitempty" json:"loc-over,omitempty"`
+			EffortOver        *float64  `yaml:"effort-over,omitempty" json:"effort-over,omitempty"`
+			ABCOver           *float64  `yaml:"abc-over,omitempty" json:"abc-over,omitempty"`
+			FanOutOver        *int      `yaml:"fanout-over,omitempty" json:"fanout-over,omitempty"`
+			FanOutBuiltins    *bool     `yaml:"fanout-builtins,omitempty" json:"fanout-builtins,omitempty"`
+			LocalsOver        *int      `yaml:"locals-over,omitempty" json:"locals-over,omitempty"`
+			ConcOver          *int      `yaml:"conc-over,omitempty" json:"conc-over,omitempty"`
+			DefersOver        *int      `yaml:"defers-over,omitempty" json:"defers-over,omitempty"`
+			NestOver          *int      `yaml:"nest-over,omitempty" json:"nest-over,omitempty"`
+			WarnDeferInLoop   *bool     `yaml:"warn-defer-in-loop,omitempty" json:"warn-defer-in-loop,omitempty"`
+			FlagRecursion     *bool     `yaml:"flag-recursion,omitempty" json:"flag-recursion,omitempty"`
+			ScoreOver         *float64  `yaml:"score-over,omitempty" json:"score-over,omitempty"`
+			ScoreWeights      *string   `yaml:"score-weights,omitempty" json:"score-weights,omitempty"`
+			TypeStats         *bool     `yaml:"typestats,omitempty" json:"typestats,omitempty"`
+			MethodsOver       *int      `yaml:"methods-over,omitempty" json:"methods-over,omitempty"`
+			FieldsOver        *int      `yaml:"fields-over,omitempty" json:"fields-over,omitempty"`
+			TypeCycloOver     *int      `yaml:"type-cyclo-over,omitempty" json:"type-cyclo-over,omitempty"`
+			SkipEntryPoints   *bool     `yaml:"skip-entrypoints,omitempty" json:"skip-entrypoints,omitempty"`
+			ExportedOnly      *bool     `yaml:"exported-only,omitempty" json:"exported-only,omitempty"`
+			ViolationsPerKLOC *float64  `yaml:"violations-per-kloc,omitempty" json:"violations-per-kloc,omitempty"`
+			DensityMinSLOC    *int      `yaml:"density-min-sloc,omitempty" json:"density-min-sloc,omitempty"`
+			PkgMaintUnder     *int      `yaml:"pkg-maint-under,omitempty" json:"pkg-maint-under,omitempty"`
+			ExtImportsOver    *int      `yaml:"ext-imports-over,omitempty" json:"ext-imports-over,omitempty"`
+			ModulePath        *string   `yaml:"module-path,omitempty" json:"module-path,omitempty"`
+			MIUseStatements   *bool     `yaml:"mi-use-statements,omitempty" json:"mi-use-statements,omitempty"`
+			MIWithComments    *bool     `yaml:"mi-with-comments,omitempty" json:"mi-with-comments,omitempty"`
+			MIScale           *string   `yaml:"mi-scale,omitempty" json:"mi-scale,omitempty"`
+			CycloMode         *string   `yaml:"cyclo-mode,omitempty" json:"cyclo-mode,omitempty"`
+			MICoefficients    []float64 `yaml:"mi-coefficients,omitempty" json:"mi-coefficients,omitempty"`
+			Grades            *string   `yaml:"grades,omitempty" json:"grades,omitempty"`
+			ExcludeFuncs      []string  `yaml:"exclude-funcs,omitempty" json:"exclude-funcs,omitempty"`
+			ExcludeFiles      []string  `yaml:"exclude-files,omitempty" json:"exclude-files,omitempty"`
+			Halstead          struct {
+				FlattenSelectors *bool   `yaml:"flatten-selectors,omitempty" json:"flatten-selectors,omitempty"`
+				MergeLiterals    *bool   `yaml:"merge-literals,omitempty" json:"merge-literals,omitempty"`
+				FoldCase         *bool   `yaml:"fold-case,omitempty" json:"fold-case,omitempty"`
+				Literals         *string `yaml:"literals,omitempty" json:"literals,omitempty"`
+				PkgDecls         *bool   `yaml:"pkg-decls,omitempty" json:"pkg-decls,omitempty"`
+			} `yaml:"halstead" json:"halstead"`
+			Packages []packageOverride `yaml:"packages,omitempty" json:"packages,omitempty"`
 		} `yaml:"complexity" json:"complexity"`
 	} `yaml:"linters-settings" json:"linters-settings"`
 	Run struct {
@@ -31,6 +80,11 @@ type ConfigFile struct {
 		BuildTags []string `yaml:"build-tags" json:"build-tags"`
 		Tests     bool     `yaml:"tests" json:"tests"`
 	} `yaml:"run" json:"run"`
+	Output struct {
+		Format   *string `yaml:"format,omitempty" json:"format,omitempty"`
+		PathMode *string `yaml:"path-mode,omitempty" json:"path-mode,omitempty"`
+		Color    *string `yaml:"color,omitempty" json:"color,omitempty"`
+	} `yaml:"output" json:"output"`
 	Issues struct {
 		ExcludeRules []struct {
 			Path string `yaml:"path" json:"path"`
@@ -44,11 +98,23 @@ func parseConfig(filename string) (*ConfigFile, error) {
 		return nil, err
 	}
 
+	// unknown keys are rejected, naming the key, as they are most likely typos
 	var unmarshal func(in []byte, out interface{}) (err error)
 	if strings.HasSuffix(filename, "yaml") || strings.HasSuffix(filename, "yml") || strings.HasSuffix(filename, "toml") {
-		unmarshal = yaml.Unmarshal
+		unmarshal = func(in []byte, out interface{}) error {
+			dec := yaml.NewDecoder(bytes.NewReader(in))
+			dec.KnownFields(true)
+			if err := dec.Decode(out); err != nil && err != io.EOF {
+				return err
+			}
+			return nil
+		}
 	} else if strings.HasSuffix(filename, ".json") {
-		unmarshal = json.Unmarshal
+		unmarshal = func(in []byte, out interface{}) error {
+			dec := json.NewDecoder(bytes.NewReader(in))
+			dec.DisallowUnknownFields()
+			return dec.Decode(out)
+		}
 	} else {
 		return nil, fmt.Errorf("unsupported file type for configuration file (yaml,yml,toml,json) : %s", filename)
 	}
@@ -62,17 +128,162 @@ func parseConfig(filename string) (*ConfigFile, error) {
 	return c, nil
 }
 
-func configureConfigIfGiven() (err error) {
+// configFileName is the configuration file looked up from the analyzed directory upwards, when none is given
+const configFileName = ".complexity.yaml"
+
+// findConfigFile returns the nearest configFileName in dir or its parents, "" if there is none
+func findConfigFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// analyzedDir returns the directory of the first package pattern or file of the cmdline,
+// the current directory for import paths
+func analyzedDir(args []string) string {
+	if len(args) > 0 && (args[0] == fileCmd || args[0] == decodeCmd || args[0] == compareCmd) {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return "."
+	}
+	p := strings.TrimSuffix(args[0], "/...")
+	if fi, err := os.Stat(p); err == nil {
+		if fi.IsDir() {
+			return p
+		}
+		return filepath.Dir(p)
+	}
+	return "."
+}
+
+// flagAliases maps the short flag names to the flag sharing their option
+var flagAliases = map[string]string{"cogover": "cognitiveover", "halsteffortover": "effortover", "stmtover": "stmtsover"}
+
+// explicitFlags are the flags given on the cmdline, which take precedence over the configuration file
+func explicitFlags() map[string]bool {
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+		if name, ok := flagAliases[f.Name]; ok {
+			explicit[name] = true
+		}
+	})
+	return explicit
+}
+
+// setFromConfig sets the option to the configuration file value, if any, unless the flag was given explicitly
+func setFromConfig[T any](explicit map[string]bool, flagName string, option *T, value *T) {
+	if value != nil && !explicit[flagName] {
+		*option = *value
+	}
+}
+
+func configureConfigIfGiven(args []string) (err error) {
+	if configfile == "" {
+		configfile = findConfigFile(analyzedDir(args))
+	}
 	if configfile != "" {
 		theConfig, err = parseConfig(configfile)
 		if err != nil {
 			return err
 		}
-		if theConfig.LintersSettings.Complexity.CycloOver != nil {
-			complexity.CycloOver = *theConfig.LintersSettings.Complexity.CycloOver
+		explicit := explicitFlags()
+		cfg := theConfig.LintersSettings.Complexity
+		setFromConfig(explicit, "cycloover", &complexity.CycloOver, cfg.CycloOver)
+		setFromConfig(explicit, "maintunder", &complexity.MaintUnder, cfg.MaintUnder)
+		setFromConfig(explicit, "cognitiveover", &complexity.CognitiveOver, cfg.CognitiveOver)
+		setFromConfig(explicit, "paramsover", &complexity.ParamsOver, cfg.ParamsOver)
+		setFromConfig(explicit, "resultsover", &complexity.ResultsOver, cfg.ResultsOver)
+		setFromConfig(explicit, "returnsover", &complexity.ReturnsOver, cfg.ReturnsOver)
+		setFromConfig(explicit, "stmtsover", &complexity.StmtsOver, cfg.StmtsOver)
+		setFromConfig(explicit, "locover", &complexity.LOCOver, cfg.LOCOver)
+		setFromConfig(explicit, "effortover", &complexity.EffortOver, cfg.EffortOver)
+		setFromConfig(explicit, "abcover", &complexity.ABCOver, cfg.ABCOver)
+		setFromConfig(explicit, "fanoutover", &complexity.FanOutOver, cfg.FanOutOver)
+		setFromConfig(explicit, "fanout-builtins", &complexity.FanOutBuiltins, cfg.FanOutBuiltins)
+		setFromConfig(explicit, "localsover", &complexity.LocalsOver, cfg.LocalsOver)
+		setFromConfig(explicit, "concover", &complexity.ConcOver, cfg.ConcOver)
+		setFromConfig(explicit, "defersover", &complexity.DefersOver, cfg.DefersOver)
+		setFromConfig(explicit, "nestover", &complexity.NestOver, cfg.NestOver)
+		setFromConfig(explicit, "warn-defer-in-loop", &complexity.WarnDeferInLoop, cfg.WarnDeferInLoop)
+		setFromConfig(explicit, "flag-recursion", &complexity.FlagRecursion, cfg.FlagRecursion)
+		setFromConfig(explicit, "scoreover", &complexity.ScoreOver, cfg.ScoreOver)
+		setFromConfig(explicit, "typestats", &complexity.TypeStats, cfg.TypeStats)
+		setFromConfig(explicit, "methodsover", &complexity.MethodsOver, cfg.MethodsOver)
+		setFromConfig(explicit, "fieldsover", &complexity.FieldsOver, cfg.FieldsOver)
+		setFromConfig(explicit, "typecycloover", &complexity.TypeCycloOver, cfg.TypeCycloOver)
+		setFromConfig(explicit, "skip-entrypoints", &complexity.SkipEntryPoints, cfg.SkipEntryPoints)
+		setFromConfig(explicit, "exported-only", &complexity.ExportedOnly, cfg.ExportedOnly)
+		setFromConfig(explicit, "violationsperkloc", &complexity.ViolationsPerKLOC, cfg.ViolationsPerKLOC)
+		setFromConfig(explicit, "densityminsloc", &complexity.DensityMinSLOC, cfg.DensityMinSLOC)
+		setFromConfig(explicit, "pkgmaintunder", &complexity.PkgMaintUnder, cfg.PkgMaintUnder)
+		setFromConfig(explicit, "extimportsover", &complexity.ExtImportsOver, cfg.ExtImportsOver)
+		setFromConfig(explicit, "module-path", &complexity.ModulePath, cfg.ModulePath)
+		setFromConfig(explicit, "mi-use-statements", &complexity.MIUseStatements, cfg.MIUseStatements)
+		setFromConfig(explicit, "mi-with-comments", &complexity.MIWithComments, cfg.MIWithComments)
+		setFromConfig(explicit, "halstflatten", &complexity.HalstFlattenSelectors, cfg.Halstead.FlattenSelectors)
+		setFromConfig(explicit, "halstmergelits", &complexity.HalstMergeLiterals, cfg.Halstead.MergeLiterals)
+		setFromConfig(explicit, "halstfoldcase", &complexity.HalstFoldCase, cfg.Halstead.FoldCase)
+		setFromConfig(explicit, "halstead-pkg-decls", &complexity.HalsteadPkgDecls, cfg.Halstead.PkgDecls)
+		setFromConfig(explicit, "out-format", &outputFormat, theConfig.Output.Format)
+		setFromConfig(explicit, "path-mode", &pathMode, theConfig.Output.PathMode)
+		setFromConfig(explicit, "color", &colorMode, theConfig.Output.Color)
+		if cfg.Grades != nil && !explicit["grades"] {
+			if err := complexity.Analyzer.Flags.Set("grades", *cfg.Grades); err != nil {
+				return fmt.Errorf("in file %q: grades: %v", configfile, err)
+			}
+		}
+		if cfg.CycloMode != nil && !explicit["cyclo-mode"] {
+			if err := complexity.Analyzer.Flags.Set("cyclo-mode", *cfg.CycloMode); err != nil {
+				return fmt.Errorf("in file %q: cyclo-mode: %v", configfile, err)
+			}
+		}
+		if cfg.ScoreWeights != nil && !explicit["score-weights"] {
+			if err := complexity.Analyzer.Flags.Set("score-weights", *cfg.ScoreWeights); err != nil {
+				return fmt.Errorf("in file %q: score-weights: %v", configfile, err)
+			}
+		}
+		if cfg.Halstead.Literals != nil && !explicit["halstead-literals"] {
+			if err := complexity.Analyzer.Flags.Set("halstead-literals", *cfg.Halstead.Literals); err != nil {
+				return fmt.Errorf("in file %q: halstead literals: %v", configfile, err)
+			}
 		}
-		if theConfig.LintersSettings.Complexity.MaintUnder != nil {
-			complexity.MaintUnder = *theConfig.LintersSettings.Complexity.MaintUnder
+		if cfg.MIScale != nil && !explicit["mi-scale"] {
+			if err := complexity.Analyzer.Flags.Set("mi-scale", *cfg.MIScale); err != nil {
+				return fmt.Errorf("in file %q: mi-scale: %v", configfile, err)
+			}
+		}
+		if cfg.MICoefficients != nil && !explicit["mi-coefficients"] {
+			values := []string{}
+			for _, c := range cfg.MICoefficients {
+				values = append(values, fmt.Sprint(c))
+			}
+			if err := complexity.Analyzer.Flags.Set("mi-coefficients", strings.Join(values, ",")); err != nil {
+				return fmt.Errorf("in file %q: mi-coefficients: %v", configfile, err)
+			}
+		}
+		for name, patterns := range map[string][]string{"exclude-func": cfg.ExcludeFuncs, "exclude-file": cfg.ExcludeFiles} {
+			if explicit[name] {
+				continue
+			}
+			for _, p := range patterns {
+				if err := complexity.Analyzer.Flags.Set(name, p); err != nil {
+					return fmt.Errorf("in file %q: %s: %v", configfile, name, err)
+				}
+			}
 		}
 		skipFiles, err = stringArrToRegex(theConfig.Run.SkipFiles)
 		if err != nil {
@@ -84,6 +295,10 @@ func configureConfigIfGiven() (err error) {
 		}
 		complexity.SkipFileFnc = filterFile
 	}
+	// the cmdline entries can not fail, they are checked while parsing the flags
+	if err := configurePackageThresholds(append(cmdlinePkgOverrides, theConfig.LintersSettings.Complexity.Packages...), explicitFlags()); err != nil {
+		return fmt.Errorf("in file %q: %v", configfile, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"golang.org/x/tools/go/packages"
+)
+
+// packageCoupling is the coupling of a package with the other analyzed packages
+type packageCoupling struct {
+	// Afferent coupling, Ca, is the number of analyzed packages importing the package
+	Afferent int
+	// Efferent coupling, Ce, is the number of analyzed packages the package imports
+	Efferent int
+	// Instability is Ce/(Ca+Ce), from 0 for a package only depended upon to 1 for one only depending on others,
+	// and 0 for a package coupled with no other
+	Instability float64
+}
+
+// couplings of the analyzed packages, by their import path, set once all packages are loaded
+var couplings = map[string]packageCoupling{}
+
+// configureCoupling counts the imports between the analyzed packages.
+// Packages outside of them, like the standard library, do not count, so the coupling depends on the analyzed patterns.
+func configureCoupling(pkgs []*packages.Package) {
+	analyzed := map[string]bool{}
+	for _, pkg := range pkgs {
+		analyzed[pkg.PkgPath] = true
+	}
+	imports := map[string]map[string]bool{}
+	for _, pkg := range pkgs {
+		if imports[pkg.PkgPath] == nil {
+			imports[pkg.PkgPath] = map[string]bool{}
+		}
+		for _, imp := range pkg.Imports {
+			if analyzed[imp.PkgPath] && imp.PkgPath != pkg.PkgPath {
+				imports[pkg.PkgPath][imp.PkgPath] = true
+			}
+		}
+	}
+	couplings = map[string]packageCoupling{}
+	for path, imps := range imports {
+		c := couplings[path]
+		c.Efferent = len(imps)
+		couplings[path] = c
+		for imp := range imps {
+			c := couplings[imp]
+			c.Afferent++
+			couplings[imp] = c
+		}
+	}
+	for path, c := range couplings {
+		if c.Afferent+c.Efferent > 0 {
+			c.Instability = float64(c.Efferent) / float64(c.Afferent+c.Efferent)
+			couplings[path] = c
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print a row per source file, summarizing its functions, in csv and txt output
+var csvFiles bool
+
+// gathered functions by their file, printed when csvFiles is set
+var fileFuncs = map[string][]complexity.FuncStatsType{}
+
+// gathered Halstead aggregates of the files, of all their functions
+var fileHalstead = map[string]complexity.HalsteadAggregate{}
+
+// fileTotals are the functions of a source file summarized in its row
+type fileTotals struct {
+	Filename  string
+	Functions []complexity.FuncStatsType
+	// Halstead is of the file as a whole, regardless of the selected functions
+	Halstead complexity.HalsteadAggregate
+}
+
+// addFileFuncs groups the reported functions of a package, or all of them when all is set, by their file
+func addFileFuncs(res *complexity.Result, all bool) {
+	for _, f := range selectFuncs(res.Functions, all) {
+		fileFuncs[f.Filename] = append(fileFuncs[f.Filename], f)
+	}
+	for name, h := range res.FileHalstead {
+		fileHalstead[name] = h
+	}
+}
+
+// sortedFileTotals returns the gathered files in the order of their names
+func sortedFileTotals(byFile map[string][]complexity.FuncStatsType, halstead map[string]complexity.HalsteadAggregate) []fileTotals {
+	arr := []fileTotals{}
+	for name, funcs := range byFile {
+		arr = append(arr, fileTotals{Filename: name, Functions: funcs, Halstead: halstead[name]})
+	}
+	sort.Slice(arr, func(i, j int) bool { return arr[i].Filename < arr[j].Filename })
+	return arr
+}
+
+// summary returns the summed and average cyclomatic complexity, the worst maintainability index and the summed loc
+func (t fileTotals) summary() (cyclo int, cycloAvg float64, worstMaint int, loc int) {
+	for i, f := range t.Functions {
+		cyclo += f.CyclomaticComplexity
+		loc += f.LOC
+		if i == 0 || f.MaintenabilityIndex < worstMaint {
+			worstMaint = f.MaintenabilityIndex
+		}
+	}
+	if len(t.Functions) > 0 {
+		cycloAvg = float64(cyclo) / float64(len(t.Functions))
+	}
+	return
+}
+
+// record formats the file row as csv fields, marked by a leading "file" field.
+// It ends with the Halstead volume, difficulty and effort of the file as a whole.
+func (t fileTotals) record() []string {
+	cyclo, cycloAvg, worstMaint, loc := t.summary()
+	return []string{"file", printedPath(t.Filename, currDir), strconv.Itoa(len(t.Functions)),
+		strconv.Itoa(cyclo), fmt.Sprintf("%0.3f", cycloAvg), strconv.Itoa(worstMaint), strconv.Itoa(loc),
+		fmt.Sprintf("%0.3f", t.Halstead.Volume), fmt.Sprintf("%0.3f", t.Halstead.Difficulty), fmt.Sprintf("%0.3f", t.Halstead.Effort)}
+}
+
+func doPrintFileTotals(w io.Writer, arr []fileTotals) error {
+	cw := csv.NewWriter(w)
+	for _, t := range arr {
+		if err := cw.Write(t.record()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func doPrintFileSummaries(w io.Writer, arr []fileTotals) {
+	for _, t := range arr {
+		cyclo, cycloAvg, worstMaint, loc := t.summary()
+		fmt.Fprintf(w, "%s: %d functions, cyclomatic complexity %d (average %0.3f), worst maintainability index %d, %d lines of code\n",
+			printedPath(t.Filename, ""), len(t.Functions), cyclo, cycloAvg, worstMaint, loc)
+	}
+}
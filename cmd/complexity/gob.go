@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// decodeCmd is the subcommand converting gob encoded results back to json or csv
+const decodeCmd = "decode"
+
+// gobSchemaVersion is bumped on every incompatible change of gobResults or complexity.FuncStatsType
+const gobSchemaVersion = 1
+
+// legacyNames is a flag option only in standalone cmdline mode
+// to print the deprecated, misspelled Halstead field names in the json of decode and of -out-format json
+var legacyNames bool
+
+// legacyJSONNames maps the json keys of Halstead metrics to their deprecated spelling
+var legacyJSONNames = strings.NewReplacer(
+	`"HalsteadDifficulty":`, `"HalsbreadDifficulty":`,
+	`"HalsteadVolume":`, `"HalsbreadVolume":`,
+)
+
+// gobResults is the compact binary output of -out-format gob, meant for high-volume pipelines.
+// Unlike csv, it carries the stats of all functions, not only the reported ones.
+type gobResults struct {
+	SchemaVersion         int
+	Analyzer              string
+	HalsteadNormalization string
+	Partial               bool
+	Functions             []complexity.FuncStatsType
+}
+
+func newGobResults(arr []complexity.FuncStatsType, partial bool) gobResults {
+	return gobResults{
+		SchemaVersion:         gobSchemaVersion,
+		Analyzer:              complexity.Analyzer.Name,
+		HalsteadNormalization: complexity.HalsteadNormalization(),
+		Partial:               partial,
+		Functions:             arr,
+	}
+}
+
+func doPrintGob(w io.Writer, res gobResults) {
+	if err := gob.NewEncoder(w).Encode(res); err != nil {
+		log.Print(err)
+	}
+}
+
+func readGob(r io.Reader) (gobResults, error) {
+	res := gobResults{}
+	if err := gob.NewDecoder(r).Decode(&res); err != nil {
+		return res, err
+	}
+	if res.SchemaVersion != gobSchemaVersion {
+		return res, fmt.Errorf("unsupported schema version %d, expected %d", res.SchemaVersion, gobSchemaVersion)
+	}
+	// results written before the rename of the misspelled Halstead fields carry only the old ones
+	for i, f := range res.Functions {
+		if f.HalsteadDifficulty == 0 && f.HalsteadVolume == 0 {
+			res.Functions[i].HalsteadDifficulty, res.Functions[i].HalsteadVolume = f.HalsbreadDifficulty, f.HalsbreadVolume
+		}
+	}
+	return res, nil
+}
+
+// runDecode converts gob encoded results, from given file or stdin, to json or csv on stdout
+func runDecode(args []string) (exitcode int) {
+	fs := flag.NewFlagSet(decodeCmd, flag.ContinueOnError)
+	to := fs.String("to", "json", "to print the results as 'json' or 'csv' (default 'json')")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	r := io.Reader(os.Stdin)
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	res, err := readGob(r)
+	if err != nil {
+		log.Printf("decoding: %v", err)
+		return 1
+	}
+	switch *to {
+	case "json":
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			log.Print(err)
+			return 1
+		}
+		out := buf.String()
+		if legacyNames {
+			out = legacyJSONNames.Replace(out)
+		}
+		fmt.Print(out)
+	case "csv":
+		if err := doPrintFuncStats(os.Stdout, res.Functions); err != nil {
+			log.Print(err)
+			return 1
+		}
+	default:
+		log.Printf("unknown -to %q, valid are: json, csv", *to)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print the distribution of the metrics of all functions at the end of the run (to stderr)
+var printHistogram bool
+
+// flag option only in standalone cmdline mode
+// bounds of the cyclomatic complexity buckets, the first one is the lowest value of the first bucket
+var histogramBuckets = []int{1, 5, 10, 20, 50}
+
+// histogramPercentiles are printed for each metric
+var histogramPercentiles = []int{50, 90, 99}
+
+// functionsDistribution gathers the functions of all packages.
+// Drivers other than this command may analyze packages concurrently, so it is guarded.
+type functionsDistribution struct {
+	mu    sync.Mutex
+	funcs []complexity.FuncStatsType
+}
+
+// gathered functions, printed as histograms when printHistogram is set
+var distribution = &functionsDistribution{}
+
+func (d *functionsDistribution) add(stats complexity.FuncStatsType) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.funcs = append(d.funcs, stats)
+}
+
+func (d *functionsDistribution) functions() []complexity.FuncStatsType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]complexity.FuncStatsType{}, d.funcs...)
+}
+
+// parseHistogramBuckets is the -histogram-buckets flag parser
+func parseHistogramBuckets(val string) error {
+	bounds := []int{}
+	for _, s := range strings.Split(val, ",") {
+		b, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid histogram bucket %q: %v", s, err)
+		}
+		if len(bounds) > 0 && b <= bounds[len(bounds)-1] {
+			return fmt.Errorf("histogram buckets must be increasing, %d follows %d", b, bounds[len(bounds)-1])
+		}
+		bounds = append(bounds, b)
+	}
+	if len(bounds) < 2 {
+		return fmt.Errorf("histogram buckets need at least 2 bounds, got %q", val)
+	}
+	histogramBuckets = bounds
+	return nil
+}
+
+// histogramBucket is a labelled count of functions
+type histogramBucket struct {
+	Label string
+	Count int
+}
+
+// cycloHistogram counts the functions per bucket given by bounds, like 1-5, 6-10, >10 for 1,5,10.
+// Values below the first bound fall into the first bucket.
+func cycloHistogram(funcs []complexity.FuncStatsType, bounds []int) []histogramBucket {
+	buckets := []histogramBucket{}
+	for i := 1; i < len(bounds); i++ {
+		low := bounds[i-1] + 1
+		if i == 1 {
+			low = bounds[0]
+		}
+		buckets = append(buckets, histogramBucket{Label: fmt.Sprintf("%d-%d", low, bounds[i])})
+	}
+	buckets = append(buckets, histogramBucket{Label: fmt.Sprintf(">%d", bounds[len(bounds)-1])})
+	for _, f := range funcs {
+		i := sort.SearchInts(bounds[1:], f.CyclomaticComplexity)
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// maintHistogram counts the functions per maintainability index decile, 0-9, ..., 90-100.
+// Indexes out of 0-100, with -mi-scale=raw, fall into the first or the last decile.
+func maintHistogram(funcs []complexity.FuncStatsType) []histogramBucket {
+	buckets := []histogramBucket{}
+	for low := 0; low < 90; low += 10 {
+		buckets = append(buckets, histogramBucket{Label: fmt.Sprintf("%d-%d", low, low+9)})
+	}
+	buckets = append(buckets, histogramBucket{Label: "90-100"})
+	for _, f := range funcs {
+		i := f.MaintenabilityIndex / 10
+		if i > 9 {
+			i = 9
+		} else if i < 0 {
+			i = 0
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// percentile returns the nearest-rank p-th percentile of the values, 0 if there are none
+func percentile(values []float64, p int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func doPrintHistogram(w io.Writer, funcs []complexity.FuncStatsType, bounds []int) {
+	fmt.Fprintf(w, "cyclomatic complexity of %d functions:\n", len(funcs))
+	for _, b := range cycloHistogram(funcs, bounds) {
+		fmt.Fprintf(w, "\t%s: %d\n", b.Label, b.Count)
+	}
+	fmt.Fprintln(w, "maintainability index:")
+	for _, b := range maintHistogram(funcs) {
+		fmt.Fprintf(w, "\t%s: %d\n", b.Label, b.Count)
+	}
+	names := []string{}
+	for _, p := range histogramPercentiles {
+		names = append(names, fmt.Sprintf("p%d", p))
+	}
+	fmt.Fprintf(w, "percentiles %s:\n", strings.Join(names, "/"))
+	for _, m := range totalsMetrics {
+		values := make([]float64, len(funcs))
+		for i, f := range funcs {
+			values[i] = m.value(f)
+		}
+		ps := []string{}
+		for _, p := range histogramPercentiles {
+			ps = append(ps, m.format(percentile(values, p)))
+		}
+		fmt.Fprintf(w, "\t%s: %s\n", m.name, strings.Join(ps, "/"))
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// jsonFormat is the -out-format printing a json object per line, for each function and each package,
+// as soon as its package is analyzed, so a monorepo run is not buffered
+const jsonFormat = "json"
+
+// jsonSchemaVersion is bumped on every incompatible change of the json lines, along with schemas/json.schema.json
+const jsonSchemaVersion = 1
+
+// jsonOut is where the json lines are written
+var jsonOut io.Writer = os.Stdout
+
+// jsonFunc is the json line of a function, with all its stats and the rules it violates
+type jsonFunc struct {
+	Kind          string
+	SchemaVersion int
+	complexity.FuncStatsType
+	// FanIn and the recursion shadow those of the FuncStatsType, left out withoutTypes
+	FanIn              *int  `json:",omitempty"`
+	Recursive          *bool `json:",omitempty"`
+	CallsItself        *bool `json:",omitempty"`
+	IsFlaggedRecursive *bool `json:",omitempty"`
+	Violations         []string
+}
+
+// jsonPackage is the json line of a package, following the lines of its functions,
+// with its coupling with the other analyzed packages
+type jsonPackage struct {
+	Kind                 string
+	SchemaVersion        int
+	Package              string
+	Functions            int
+	Violations           int
+	SLOC                 int
+	MaintainabilityIndex int
+	Halstead             complexity.HalsteadAggregate
+	Imports              complexity.ImportCounts
+	Afferent             int
+	Efferent             int
+	Instability          float64
+}
+
+// jsonStats is the last json line of the run with -stats, its wall time per phase, peak heap and throughput.
+// It ends the partial runs also without -stats, marking them Partial with the SkippedPackages.
+type jsonStats struct {
+	Kind               string
+	SchemaVersion      int
+	WallSeconds        float64
+	Phases             []jsonPhase
+	PeakHeapBytes      uint64
+	Functions          int
+	FunctionsPerSecond float64
+	Partial            bool
+	SkippedPackages    []string `json:",omitempty"`
+}
+
+type jsonPhase struct {
+	Name    string
+	Seconds float64
+}
+
+func newJSONFunc(s complexity.FuncStatsType) jsonFunc {
+	s.Filename = printedPath(s.Filename, "")
+	j := jsonFunc{Kind: "func", SchemaVersion: jsonSchemaVersion, FuncStatsType: s, Violations: complexity.Violations(s)}
+	if !withoutTypes {
+		j.FanIn = &s.FanIn
+		j.Recursive, j.CallsItself, j.IsFlaggedRecursive = &s.Recursive, &s.CallsItself, &s.IsFlaggedRecursive
+	}
+	return j
+}
+
+// columnFields are the json field names of the csv columns, for projecting the json func lines with -columns
+var columnFields = map[string]string{
+	"filename": "Filename", "line": "Line", "name": "FunctionName", "cyclo": "CyclomaticComplexity",
+	"maint": "MaintenabilityIndex", "difficulty": "HalsteadDifficulty", "volume": "HalsteadVolume",
+	"timetocode": "TimeToCode", "loc": "LOC", "declloc": "ConstantsLOC", "toocomplex": "IsTooComplex",
+	"notmaintainable": "IsNotMaintenable", "generated": "Generated", "cognitive": "CognitiveComplexity",
+	"params": "Params", "results": "Results", "returns": "Returns", "statements": "Statements", "sloc": "SLOC",
+	"effort": "HalsteadEffort", "bugs": "HalsteadBugs", "abc-a": "ABCAssignments", "abc-b": "ABCBranches",
+	"abc-c": "ABCConditions", "abc": "ABCSize", "source": "GenSource", "todos": "TodoMarkers",
+	"suppressed": "Suppressed", "suppressreason": "SuppressReason", "cycloover": "CycloOver",
+	"maintunder": "MaintUnder", "grade": "Grade", "fanout": "FanOut", "locals": "Locals", "gostmts": "GoStmts",
+	"chanops": "ChanOps", "selects": "Selects", "selectcases": "SelectCases", "synccalls": "SyncCalls",
+	"concurrency": "ConcurrencyScore", "recursive": "Recursive", "declonly": "DeclarationOnly", "score": "Score",
+	"endline": "EndLine", "span": "Span", "nameline": "NameLine", "namecol": "NameColumn", "defers": "Defers",
+	"defersinloop": "DefersInLoop", "maxlivedefers": "MaxLiveDefers", "nesting": "MaxNesting",
+	"comments": "CommentLines", "maintclassic": "ClassicMaintIndex",
+	"distinctoperators": "HalsteadDistinctOperators", "distinctoperands": "HalsteadDistinctOperands",
+	"operators": "HalsteadTotalOperators", "operands": "HalsteadTotalOperands",
+	"vocabulary": "HalsteadVocabulary", "length": "HalsteadLength",
+}
+
+// jsonColumns is the json line of a function with its Kind, SchemaVersion and the fields of the columns only, in their order
+type jsonColumns struct {
+	jsonFunc
+	cols []column
+}
+
+func (p jsonColumns) MarshalJSON() ([]byte, error) {
+	buf, err := json.Marshal(p.jsonFunc)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	b.WriteString(`{"Kind":`)
+	b.Write(fields["Kind"])
+	b.WriteString(`,"SchemaVersion":`)
+	b.Write(fields["SchemaVersion"])
+	for _, c := range p.cols {
+		name := columnFields[c.name]
+		v, ok := fields[name]
+		if !ok {
+			continue // left out, like the types-dependent fields in file mode
+		}
+		fmt.Fprintf(b, ",%q:", name)
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// jsonFuncLine is the json line of a function, of the selected columns only when projected by -columns
+func jsonFuncLine(s complexity.FuncStatsType, projected bool) any {
+	if projected {
+		return jsonColumns{jsonFunc: newJSONFunc(s), cols: selectedColumns}
+	}
+	return newJSONFunc(s)
+}
+
+func newJSONPackage(pkgPath string, res *complexity.Result) jsonPackage {
+	c := couplings[pkgPath]
+	return jsonPackage{Kind: "pkg", SchemaVersion: jsonSchemaVersion, Package: pkgPath, Functions: len(res.Functions), Violations: res.Violations,
+		SLOC: res.SLOC, MaintainabilityIndex: res.MaintainabilityIndex, Halstead: res.Halstead, Imports: res.Imports,
+		Afferent: c.Afferent, Efferent: c.Efferent, Instability: c.Instability}
+}
+
+func newJSONStats(s *runStats) jsonStats {
+	phases := []jsonPhase{}
+	for _, p := range s.phases {
+		phases = append(phases, jsonPhase{Name: p.name, Seconds: p.duration.Seconds()})
+	}
+	return jsonStats{Kind: "stats", SchemaVersion: jsonSchemaVersion, WallSeconds: s.wall().Seconds(), Phases: phases, PeakHeapBytes: s.peakHeap,
+		Functions: s.functions, FunctionsPerSecond: s.perSecond(), Partial: s.partial, SkippedPackages: s.skipped}
+}
+
+// writeJSONLine writes v as a single line, with the deprecated Halstead names when legacyNames is set,
+// the first error failing the run
+func writeJSONLine(w io.Writer, v any) {
+	buf, err := json.Marshal(v)
+	if err == nil {
+		line := string(buf) + "\n"
+		if legacyNames {
+			line = legacyJSONNames.Replace(line)
+		}
+		_, err = io.WriteString(w, line)
+	}
+	if err != nil && outputErr == nil {
+		log.Printf("writing json output: %v", err)
+		outputErr = err
+	}
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fikin/go-complexity-analysis"
 	"golang.org/x/tools/go/analysis"
 )
 
 // flag option only in standalone cmdline mode
-// one of : txt, csv, checkstyle
+// one of : txt, csv, checkstyle, gob, pkgsummary
 var outputFormat = "txt"
 
 // flag option only standalone cmdline mode
@@ -20,12 +27,27 @@ var outputFormat = "txt"
 // subject to limited flags support (see README)
 var configfile string
 
-// gathered function stats to be printed at the end when output-format=csv
+// gathered function stats to be printed at the end when output-format=csv or gob
 var funcStats = []complexity.FuncStatsType{}
 
 // gathered function stats to be printed at the end when output-format=stylechek
 var checkstyles = checkstyleTag{filesAsMap: map[string]checkstyleFileTag{}, Files: []checkstyleFileTag{}, Version: "5.0"}
 
+// flag option only in standalone cmdline mode
+// to stop analyzing further packages when the time is over, reporting the partial results
+var timeBudget time.Duration
+
+// flag option only in standalone cmdline mode
+// to tolerate up to N violations before failing the run, 0 fails on any
+var maxIssues int
+
+// flag option only in standalone cmdline mode
+// number of top exported functions per package to summarize by reached complexity
+var apiReachTop int
+
+// gathered per package results, printed in the summary when apiReachTop > 0
+var apiReaches = map[string][]complexity.APIReachType{}
+
 var currDir string
 
 func main() {
@@ -55,22 +77,99 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := configureConfigIfGiven(); err != nil {
+	if err := configureConfigIfGiven(args); err != nil {
 		log.Fatalf("%v", err)
 		os.Exit(1)
 	}
+	if err := configurePaths(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := checkOutputFlags(args); err != nil {
+		log.Fatalf("%v", err)
+	}
+	configureStreaming()
+	if err := configureColor(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	configureColumns()
 	configureOutputFormat()
+	if err := configureBaseline(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if args[0] == fileCmd {
+		os.Exit(runFiles(args[1:]))
+	}
+	if args[0] == decodeCmd {
+		os.Exit(runDecode(args[1:]))
+	}
+	if args[0] == compareCmd {
+		os.Exit(runCompare(args[1:]))
+	}
+	if args[0] == schemaCmd {
+		os.Exit(runSchema(args[1:]))
+	}
 
-	os.Exit(run(args, a))
+	// on interrupt stop analyzing, but still print what was gathered so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeBudget)
+		defer cancel()
+	}
+	exitcode := run(ctx, args, a)
+	stop()
+	os.Exit(exitcode)
 }
 
 func addCmdlineFlags(a *analysis.Analyzer) {
-	flag.StringVar(&outputFormat, "out-format", "txt", "to print the diagnostics as 'csv', 'checkstyle' xml or vet-like 'txt' (default 'txt')")
-	flag.StringVar(&configfile, "c", "", "configuration like golangci")
+	// analyzer's own flags are exposed unprefixed, same as singlechecker does
+	a.Flags.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+	})
+	flag.StringVar(&outputFormat, "out-format", "txt", "to print the diagnostics as 'csv', 'checkstyle' xml, binary 'gob', vet-like 'txt', a 'pkgsummary' line per package, Prometheus 'metrics', a 'json' object per function and package line or a 'sarif' 2.1.0 log (default 'txt')")
+	flag.StringVar(&configfile, "c", "", "configuration like golangci, by default the nearest "+configFileName+" from the analyzed directory upwards")
+	flag.StringVar(&configfile, "config", "", "same as -c")
+	flag.Var(columnsFlag{}, "columns", "comma separated, ordered, list of columns printed in csv output, and of the fields of the function lines of json output")
+	flag.BoolVar(&failOnParseError, "failonparseerror", false, "exit with error code on files failing to parse, which are otherwise only reported")
+	flag.BoolVar(&strict, "strict", false, "exit with code 5 when any metric could not be computed: of the files failing to parse, of the packages skipped by a stopped run, or the types-dependent ones in file mode, listing them (to stderr)")
+	flag.IntVar(&maxIssues, "maxissues", 0, "tolerate up to N violations across all packages before exiting with error code (0 fails on any violation)")
+	flag.StringVar(&baselinePath, "baseline", "", "json file of baseline results, like complexity-baseline.json, exiting with error code only on functions regressed against it or new violating ones, instead of on any violation")
+	flag.BoolVar(&writeBaselineFile, "write-baseline", false, "record the results of all functions in the -baseline file, exiting with error code only on analysis errors")
+	flag.DurationVar(&timeBudget, "timebudget", 0, "stop analyzing further packages after the duration, e.g. 55s, printing the partial results (0 disables the budget)")
+	flag.BoolVar(&printSummary, "summary", false, "print the number of violating functions and of violations per rule at the end (to stderr)")
+	flag.BoolVar(&printStats, "stats", false, "print the wall time per phase, peak heap and functions analyzed per second at the end (to stderr)")
+	flag.BoolVar(&legacyNames, "legacynames", false, "print the deprecated HalsbreadDifficulty and HalsbreadVolume names instead of HalsteadDifficulty and HalsteadVolume in decoded json and -out-format json (removed in the next version)")
+	flag.BoolVar(&printSuppressed, "list-suppressed", false, "list the suppressed functions and those ignoring rules, with the reason and the rules they would violate, flagging the stale suppressions (to stderr)")
+	flag.BoolVar(&printTodoReport, "todoreport", false, "list the functions over any threshold whose comments have -todomarkers, with the marked lines (to stderr)")
+	flag.BoolVar(&forceStream, "stream", false, "print the findings of each package as soon as it is analyzed, also in csv, disabling the checkstyle, gob and metrics formats which need all results")
+	flag.IntVar(&progressEvery, "progress", progressEvery, "while the output is buffered, print a progress line every N analyzed packages (to stderr, 0 disables it)")
+	flag.StringVar(&pathMode, "path-mode", "", "print file names as 'abs' absolute, 'rel' relative to the working directory or 'module' relative to its module root, in all outputs, names outside of the root stay absolute (default: relative in csv and checkstyle, absolute otherwise)")
+	flag.StringVar(&colorMode, "color", colorAuto, "color the txt output: 'auto' when stdout is a terminal and NO_COLOR is not set, 'always' or 'never'")
+	flag.BoolVar(&csvNoHeader, "csv-no-header", false, "omit the header row of csv output, e.g. when appending to an existing file")
+	flag.BoolVar(&includeUnexportedInCSV, "include-unexported-in-csv", false, "with -exported-only, print the rows of the unexported functions in csv output, while they are still neither reported nor counted")
+	flag.BoolVar(&csvTotals, "csvtotals", false, "print a totals row per package after the function rows of csv output")
+	flag.BoolVar(&allFuncs, "allfuncs", false, "sum all functions of a package into its -csvtotals row, not only the reported ones")
+	flag.BoolVar(&csvFiles, "csvfiles", false, "print a row per source file with its function count, summed and average cyclomatic complexity, worst maintainability index and lines of code, in csv and txt output")
+	flag.BoolVar(&csvTypes, "csvtypes", false, "print a row per named type with its methods, struct fields and interface methods after the function rows of csv output, implies -typestats")
+	flag.BoolVar(&csvMethodsByType, "csvmethods-by-type", false, "print a row per named type with methods, with their count, summed and worst cyclomatic complexity, average maintainability index and lines of code, after the type rows of csv output, implies -typestats")
+	flag.Func("pkgthreshold", "override the thresholds of the packages matching the import path or module directory pattern, like 'internal/legacy/** cycloover=25,maintunder=10', where ** matches any path, before the packages entries of the configuration file (repeatable)", parsePackageOverride)
+	flag.Func("sort", "order of the function rows of csv output: 'none' as analyzed, or 'score' the riskiest first (default 'none')", parseSort)
+	flag.Func("totals-mode", "how -csvtotals rows summarize each metric: 'sum' (deprecated) or 'stats', its average, median and maximum (default 'sum')", parseTotalsMode)
+	flag.StringVar(&badgePath, "badge", "", "write a shields.io endpoint badge JSON of the grade and average maintainability index of all functions to the file, at the end")
+	flag.BoolVar(&printHistogram, "histogram", false, "print the distribution of all functions by cyclomatic complexity bucket and maintainability index decile, and percentiles of each metric, at the end (to stderr)")
+	flag.Func("histogram-buckets", "comma separated, increasing, bounds of the -histogram cyclomatic complexity buckets, like 1,5,10 for 1-5, 6-10 and >10 (default 1,5,10,20,50)", parseHistogramBuckets)
+	flag.IntVar(&metricsMinCyclo, "metrics-min-cyclo", 0, "export only the functions with the cyclomatic complexity >= N in -out-format metrics, limiting the number of series (packages are always exported)")
+	flag.IntVar(&apiReachTop, "apireach", 0, "summarize top N exported functions per package by transitively reached cyclomatic complexity (to stderr)")
 	flag.Usage = func() {
 		paras := strings.Split(a.Doc, "\n\n")
 		fmt.Fprintf(os.Stderr, "%s: %s\n\n", a.Name, paras[0])
-		fmt.Fprintf(os.Stderr, "Usage: %s [-flag] [package]\n\n", a.Name)
+		fmt.Fprintf(os.Stderr, "Usage: %s [-flag] [package]\n", a.Name)
+		fmt.Fprintf(os.Stderr, "       %s [-flag] %s [file.go]  (parse-only, tolerating missing imports)\n", a.Name, fileCmd)
+		fmt.Fprintf(os.Stderr, "       %s [-columns ...] [-legacynames] %s [-to json|csv] [results.gob]  (converts -out-format gob results)\n", a.Name, decodeCmd)
+		fmt.Fprintf(os.Stderr, "       %s %s [-to txt|csv] old.gob new.gob  (reports the regressions between -out-format gob results)\n", a.Name, compareCmd)
+		fmt.Fprintf(os.Stderr, "       %s %s json|baseline  (prints the JSON Schema of -out-format json or of the -baseline file)\n\n", a.Name, schemaCmd)
 		if len(paras) > 1 {
 			fmt.Fprintln(os.Stderr, strings.Join(paras[1:], "\n\n"))
 		}
@@ -84,44 +183,284 @@ func configureOutputFormat() {
 	case "checkstyle":
 		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
 			msg := complexity.ToDiagnosticMsg(stats)
-			if msg != "" {
+			if msg != "" && !stats.Suppressed && !stats.Unchanged && !stats.Unexported {
 				i, ok := checkstyles.filesAsMap[stats.Filename]
 				if !ok {
-					i = checkstyleFileTag{FileName: getRelativeFileName(stats.Filename, currDir), Errors: []checkstyleErrorTag{}}
+					i = checkstyleFileTag{FileName: printedPath(stats.Filename, currDir), Errors: []checkstyleErrorTag{}}
 				}
 				i.Errors = append(i.Errors, checkstyleErrorTag{Line: stats.Line, Msg: msg, Severity: "error", Source: "typecheck"})
 				checkstyles.filesAsMap[stats.Filename] = i
 			}
 		}
-	case "csv":
+	case "csv", "gob":
 		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
 			funcStats = append(funcStats, stats)
 		}
+	case pkgSummaryFormat:
+		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
+			pendingSummaryFuncs = append(pendingSummaryFuncs, stats)
+		}
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			pkgSummaries = append(pkgSummaries, packageSummary{Kind: "pkg", Name: pkgPath, Funcs: pendingSummaryFuncs})
+			pendingSummaryFuncs = []complexity.FuncStatsType{}
+		}
+	case metricsFormat:
+		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
+			metricsFuncs = append(metricsFuncs, stats)
+		}
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			metricsPkgs = append(metricsPkgs, newPackageMetrics(pkgPath, res))
+		}
+	case sarifFormat:
+		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
+			sarifResults = append(sarifResults, sarifResultsOf(stats)...)
+		}
+	case jsonFormat:
+		projected := explicitFlags()["columns"]
+		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
+			writeJSONLine(jsonOut, jsonFuncLine(stats, projected))
+		}
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			writeJSONLine(jsonOut, newJSONPackage(pkgPath, res))
+		}
+	}
+	if colorOn {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			coloredFuncs[funcKey(s.Filename, s.Line)] = s
+			collect(s)
+		}
+	}
+	if printSummary {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(stats complexity.FuncStatsType) {
+			totals.add(stats)
+			collect(stats)
+		}
+		collectPkg := complexity.PackageResultCallback
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			totals.SLOC += res.SLOC
+			collectPkg(pkgPath, res)
+		}
+	}
+	if printStats {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			timings.functions++
+			collect(s)
+		}
+	}
+	if badgePath != "" {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			badgeTotals.add(s)
+			collect(s)
+		}
+	}
+	if printHistogram {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			distribution.add(s)
+			collect(s)
+		}
+	}
+	if printSuppressed {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			collectSuppressed(s)
+			collect(s)
+		}
+	}
+	if baselinePath != "" {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			collectBaseline(s)
+			collect(s)
+		}
+	}
+	if printTodoReport {
+		collect := complexity.FuncStatsCallback
+		complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+			collectTodo(s)
+			collect(s)
+		}
+	}
+	if csvTotals && outputFormat == "csv" {
+		if !explicitFlags()["totals-mode"] {
+			log.Printf("-totals-mode=%s is deprecated and will be replaced by -totals-mode=%s as the default", totalsModeSum, totalsModeStats)
+		}
+		collect := complexity.PackageResultCallback
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			pkgTotals = append(pkgTotals, newPackageTotals(pkgPath, res, allFuncs))
+			collect(pkgPath, res)
+		}
+	}
+	if csvFiles {
+		collect := complexity.PackageResultCallback
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			addFileFuncs(res, allFuncs)
+			collect(pkgPath, res)
+		}
+	}
+	if (csvTypes || csvMethodsByType) && outputFormat == "csv" {
+		complexity.TypeStats = true
+		complexity.TypeStatsCallback = func(s complexity.TypeStatsType) {
+			typeStats = append(typeStats, s)
+		}
+	}
+	if apiReachTop > 0 {
+		collect := complexity.PackageResultCallback
+		complexity.PackageResultCallback = func(pkgPath string, res *complexity.Result) {
+			apiReaches[pkgPath] = res.APIReach
+			collect(pkgPath, res)
+		}
 	}
 }
 
+// outputErr is the first error writing the findings, failing the run
+var outputErr error
+
 func printDiagnostics(arr []foundDiagnosticsStruct) {
+	printFindings(arr)
+	printReports()
+}
+
+// printFindings prints the findings in the output format
+func printFindings(arr []foundDiagnosticsStruct) {
+	routeParseErrors(arr)
 	switch outputFormat {
 	case "checkstyle":
 		doPrintcheckstyles(checkstyles)
 	case "csv":
-		doPrintFuncStats(funcStats)
+		for _, err := range []error{
+			doPrintFuncStats(os.Stdout, sortedFuncStats(funcStats, sortBy)),
+			doPrintTotals(os.Stdout, pkgTotals),
+			doPrintFileTotals(os.Stdout, sortedFileTotals(fileFuncs, fileHalstead)),
+			doPrintTypeStats(os.Stdout, typeStats),
+		} {
+			if err != nil && outputErr == nil {
+				log.Printf("writing csv output: %v", err)
+				outputErr = err
+			}
+		}
+	case "gob":
+		doPrintGob(os.Stdout, newGobResults(printedPaths(funcStats), checkstyles.Partial))
+	case pkgSummaryFormat:
+		doPrintPackageSummaries(os.Stdout, summariesOf(pkgSummaries, pendingSummaryFuncs))
+	case metricsFormat:
+		if err := doPrintMetrics(os.Stdout, metricsFuncs, metricsPkgs, metricsMinCyclo); err != nil && outputErr == nil {
+			log.Printf("writing metrics output: %v", err)
+			outputErr = err
+		}
+	case sarifFormat:
+		sarifResults = append(sarifResults, sarifFindingsOf(arr)...)
+		if err := doPrintSarif(os.Stdout, sarifResults, checkstyles.Partial); err != nil && outputErr == nil {
+			log.Printf("writing sarif output: %v", err)
+			outputErr = err
+		}
+	case jsonFormat:
+		// written by the callbacks as the packages are analyzed
 	default:
 		doPrintDiagnostics(arr)
+		doPrintFileSummaries(os.Stdout, sortedFileTotals(fileFuncs, fileHalstead))
+	}
+}
+
+// printReports prints the summaries gathered over the whole run, to stderr
+func printReports() {
+	doPrintAPIReach(os.Stderr, apiReaches, apiReachTop)
+	for _, name := range complexity.UnmatchedDiffFiles() {
+		log.Printf("-diff file %s matches no analyzed file, its changes are not checked", name)
+	}
+	if printTodoReport {
+		doPrintTodoReport(os.Stderr, todoFuncs)
+	}
+	if printSuppressed {
+		doPrintSuppressed(os.Stderr, suppressedFuncs)
+	}
+	if printSummary {
+		doPrintSummary(os.Stderr, totals)
 	}
+	if printHistogram {
+		doPrintHistogram(os.Stderr, distribution.functions(), histogramBuckets)
+	}
+	if badgePath != "" {
+		if err := writeBadge(badgePath, newBadge(badgeTotals)); err != nil && outputErr == nil {
+			log.Printf("writing badge: %v", err)
+			outputErr = err
+		}
+	}
+	if complexity.DebugCoverage {
+		for _, l := range complexity.UnhandledNodesReport(complexity.UnhandledNodes()) {
+			log.Print(l)
+		}
+	}
+}
+
+func doPrintAPIReach(w io.Writer, reaches map[string][]complexity.APIReachType, top int) {
+	pkgs := []string{}
+	for pkg := range reaches {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		for i, r := range reaches[pkg] {
+			if i >= top {
+				break
+			}
+			fmt.Fprintf(w, "%s: func %s transitively reaches functions with combined cyclomatic complexity %d, external calls: %d\n",
+				pkg, r.FunctionName, r.ReachedComplexity, r.ExternalCalls)
+		}
+	}
+}
+
+// orders of the function rows of csv output
+const (
+	sortNone  = "none"
+	sortScore = "score"
+)
+
+// flag option only in standalone cmdline mode
+// one of : none, score
+var sortBy = sortNone
+
+func parseSort(val string) error {
+	if val != sortNone && val != sortScore {
+		return fmt.Errorf("unknown sort %q, valid are: %s, %s", val, sortNone, sortScore)
+	}
+	sortBy = val
+	return nil
 }
 
-func doPrintFuncStats(arr []complexity.FuncStatsType) {
+// sortedFuncStats returns the functions by descending risk score with sortScore, otherwise as analyzed
+func sortedFuncStats(arr []complexity.FuncStatsType, by string) []complexity.FuncStatsType {
+	if by != sortScore {
+		return arr
+	}
+	res := append([]complexity.FuncStatsType{}, arr...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	return res
+}
+
+// doPrintFuncStats prints the reported functions as csv rows, preceded by a header row
+// with the names of the selected columns on the first call, unless csvNoHeader is set
+func doPrintFuncStats(w io.Writer, arr []complexity.FuncStatsType) error {
+	cw := csv.NewWriter(w)
+	if !csvNoHeader && !csvHeaderPrinted {
+		if err := cw.Write(columnNames(selectedColumns)); err != nil {
+			return err
+		}
+		csvHeaderPrinted = true
+	}
 	for _, stats := range arr {
-		if stats.IsNotMaintenable || stats.IsTooComplex {
-			fmt.Printf("%s,%d,%s,%d,%d,%0.3f,%0.3f,%0.3f,%d,%d,%t,%t\n",
-				getRelativeFileName(stats.Filename, currDir), stats.Line, stats.FunctionName,
-				stats.CyclomaticComplexity, stats.MaintenabilityIndex, stats.HalsbreadDifficulty,
-				stats.HalsbreadVolume, stats.TimeToCode,
-				stats.LOC, stats.ConstantsLOC,
-				stats.IsTooComplex, stats.IsNotMaintenable)
+		if complexity.ToDiagnosticMsg(stats) != "" && !stats.Unchanged && (!stats.Unexported || includeUnexportedInCSV) {
+			if err := cw.Write(formatColumns(selectedColumns, stats)); err != nil {
+				return err
+			}
 		}
 	}
+	cw.Flush()
+	return cw.Error()
 }
 
 func getRelativeFileName(filename string, basePath string) string {
@@ -1,9 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
 
 	"github.com/fikin/go-complexity-analysis"
 )
@@ -19,6 +35,1674 @@ func TestIt(t *testing.T) {
 		funcsCnt++
 		oldFnc(s)
 	}
-	assert.Equal(t, 1, run([]string{"./../../testdata/src/..."}, complexity.Analyzer))
-	assert.Equal(t, 19, funcsCnt)
+	assert.Equal(t, 1, run(context.Background(), []string{"./../../testdata/src/..."}, complexity.Analyzer))
+	assert.Equal(t, 124, funcsCnt)
+}
+
+// binDir holds the command binary shared by the tests, built once by buildCmd
+var binDir string
+
+var (
+	buildOnce sync.Once
+	buildOut  []byte
+	buildErr  error
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "complexity-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// buildCmd returns the command binary, building it on the first call only
+func buildCmd(t *testing.T) string {
+	bin := filepath.Join(binDir, "complexity")
+	buildOnce.Do(func() {
+		buildOut, buildErr = exec.Command("go", "build", "-o", bin, ".").CombinedOutput()
+	})
+	assert.NoError(t, buildErr, string(buildOut))
+	return bin
+}
+
+func TestCmdEndToEnd(t *testing.T) {
+	bin := buildCmd(t)
+
+	cmd := exec.Command(bin, "./../../testdata/src/a")
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+	assert.Empty(t, string(out))
+
+	cmd = exec.Command(bin, "-cycloover", "5", "./../../testdata/src/...")
+	out, err = cmd.CombinedOutput()
+	assert.Error(t, err)
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "a.go:16: func f2 seems to be complex (cyclomatic complexity=8)")
+	assert.Contains(t, string(out), "a.go:23: func f4 seems to be complex (cyclomatic complexity=8)")
+
+	out, _ = exec.Command(bin).CombinedOutput()
+	assert.Contains(t, string(out), "maintainability index")
+	assert.Contains(t, string(out), "halstead volume")
+}
+
+func TestPrintAPIReach(t *testing.T) {
+	reaches := map[string][]complexity.APIReachType{
+		"b": {{FuncResult: complexity.FuncResult{FuncStatsType: complexity.FuncStatsType{FunctionName: "Run"}}, ReachedComplexity: 3}},
+		"a": {
+			{FuncResult: complexity.FuncResult{FuncStatsType: complexity.FuncStatsType{FunctionName: "ServeHTTP"}}, ReachedComplexity: 412, ExternalCalls: 7},
+			{FuncResult: complexity.FuncResult{FuncStatsType: complexity.FuncStatsType{FunctionName: "Close"}}, ReachedComplexity: 2},
+		},
+	}
+	buf := &strings.Builder{}
+	doPrintAPIReach(buf, reaches, 1)
+	assert.Equal(t, "a: func ServeHTTP transitively reaches functions with combined cyclomatic complexity 412, external calls: 7\n"+
+		"b: func Run transitively reaches functions with combined cyclomatic complexity 3, external calls: 0\n", buf.String())
+}
+
+func TestCmdFile(t *testing.T) {
+	bin := buildCmd(t)
+	snippet := filepath.Join(t.TempDir(), "snippet.go")
+	assert.NoError(t, os.WriteFile(snippet, []byte(`package snippet
+
+import "example.com/not/on/disk"
+
+func f(a, b int) {
+	if a > b && disk.Check(a) {
+		disk.Do()
+	}
+}
+`), 0o600))
+
+	cmd := exec.Command(bin, "-cycloover", "2", "file", snippet)
+	out, err := cmd.CombinedOutput()
+	assert.Error(t, err)
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "snippet.go:5: func f seems to be complex (cyclomatic complexity=3)")
+	assert.NotContains(t, string(out), "types-dependent")
+
+	out, _ = exec.Command(bin, "-cycloover", "2", "-apireach", "3", "file", snippet).CombinedOutput()
+	assert.Contains(t, string(out), "types-dependent metrics are not available in file mode: fan-in, recursive (-flag-recursion), api reach (-apireach) and hotspots (-hotspots)")
+	out, _ = exec.Command(bin, "-out-format", "json", "-cycloover", "2", "file", snippet).Output()
+	assert.Contains(t, string(out), `"FunctionName":"f"`)
+	assert.NotContains(t, string(out), `"FanIn"`)
+	assert.NotContains(t, string(out), `"Recursive"`)
+
+	// recursion is resolved with type information only
+	recursive := filepath.Join(t.TempDir(), "recursive.go")
+	assert.NoError(t, os.WriteFile(recursive, []byte("package snippet\n\nfunc f(n int) int {\n\tif n > 0 {\n\t\treturn f(n - 1)\n\t}\n\treturn 0\n}\n"), 0o600))
+	out, _ = exec.Command(bin, "-cycloover", "1", "-out-format", "csv", "-columns", "name,recursive", "file", recursive).Output()
+	assert.Equal(t, "name,recursive\nf,\n", string(out))
+	out, _ = exec.Command(bin, "-flag-recursion", "file", recursive).CombinedOutput()
+	assert.Contains(t, string(out), "recursive (-flag-recursion)")
+
+	out, err = exec.Command(bin, "-out-format", "csv", "-cycloover", "2", "file", snippet).Output()
+	assert.Error(t, err)
+	assert.Contains(t, string(out), "snippet.go,5,f,3,")
+
+	// the functions are collected like in package mode
+	out, _ = exec.Command(bin, "-out-format", "csv", "-columns", "name,generated", "-cycloover", "0", "file", "./../../testdata/src/genregions/a.go").Output()
+	assert.Equal(t, "name,generated\nhand,false\ngen1,true\ngen2,true\nhand2,false\n", string(out))
+	out, _ = exec.Command(bin, "-out-format", "csv", "-columns", "name", "-cycloover", "0", "-skipgenregions", "file", "./../../testdata/src/genregions/a.go").Output()
+	assert.Equal(t, "name\nhand\nhand2\n", string(out))
+	out, _ = exec.Command(bin, "-out-format", "csv", "-columns", "name,source", "-cycloover", "0", "-gensource", "file", "./../../testdata/src/gensource/mocks.go").Output()
+	assert.Contains(t, string(out), "kindString,stringer -type=Kind\n")
+	out, _ = exec.Command(bin, "-out-format", "csv", "-columns", "name", "-cycloover", "0", "-funclit", "file", "./../../testdata/src/funclit/funclit.go").Output()
+	assert.Contains(t, string(out), "\nspawn$1\n")
+	assert.Contains(t, string(out), "\nglob$1\n")
+
+	assert.NoError(t, os.WriteFile(snippet, []byte("package snippet\nfunc f( {"), 0o600))
+	cmd = exec.Command(bin, "file", snippet)
+	out, err = cmd.CombinedOutput()
+	assert.NoError(t, err) // reported, see TestParseErrors
+	assert.Contains(t, string(out), "snippet.go:2: file does not parse")
+}
+
+func TestRunCancelled(t *testing.T) {
+	defer func(old string) { outputFormat = old }(outputFormat)
+	defer func(old func(complexity.FuncStatsType)) { complexity.FuncStatsCallback = old }(complexity.FuncStatsCallback)
+	defer func(old checkstyleTag) { checkstyles = old }(checkstyles)
+	defer complexity.Analyzer.Flags.Set("cycloover", "10")
+	assert.NoError(t, complexity.Analyzer.Flags.Set("cycloover", "0"))
+	checkstyles = checkstyleTag{filesAsMap: map[string]checkstyleFileTag{}, Files: []checkstyleFileTag{}, Version: "5.0"}
+	outputFormat = "checkstyle"
+	configureOutputFormat()
+
+	// cancel once the first package is being analyzed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collect := complexity.FuncStatsCallback
+	complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+		cancel()
+		collect(s)
+	}
+
+	out, err := os.CreateTemp(t.TempDir(), "out")
+	assert.NoError(t, err)
+	defer func(old *os.File) { os.Stdout = old }(os.Stdout)
+	os.Stdout = out
+	exitcode := run(ctx, []string{"./../../testdata/src/..."}, complexity.Analyzer)
+	os.Stdout.Close()
+
+	assert.Equal(t, exitPartial, exitcode)
+	buf, err := os.ReadFile(out.Name())
+	assert.NoError(t, err)
+	var doc struct {
+		Partial bool `xml:"partial,attr"`
+		Files   []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"file"`
+	}
+	assert.NoError(t, xml.Unmarshal(buf, &doc), string(buf))
+	assert.True(t, doc.Partial)
+	assert.Len(t, doc.Files, 1)
+	assert.Equal(t, "a.go", filepath.Base(doc.Files[0].Name)) // packages are analyzed in path order
+	assert.Contains(t, totals.SkippedPackages, "github.com/fikin/go-complexity-analysis/testdata/src/halstead")
+	assert.NotContains(t, totals.SkippedPackages, "github.com/fikin/go-complexity-analysis/testdata/src/a")
+}
+
+func TestRunCancelledJSON(t *testing.T) {
+	defer func(old string) { outputFormat = old }(outputFormat)
+	defer func(old func(complexity.FuncStatsType)) { complexity.FuncStatsCallback = old }(complexity.FuncStatsCallback)
+	defer func(old func(string, *complexity.Result)) { complexity.PackageResultCallback = old }(complexity.PackageResultCallback)
+	defer func(old io.Writer) { jsonOut = old }(jsonOut)
+	defer func(old *runStats) { timings = old }(timings)
+	outputFormat = jsonFormat
+	configureOutputFormat()
+	buf := &bytes.Buffer{}
+	jsonOut = buf
+	timings = newRunStats()
+
+	// cancel once the first package is being analyzed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collect := complexity.FuncStatsCallback
+	complexity.FuncStatsCallback = func(s complexity.FuncStatsType) {
+		cancel()
+		collect(s)
+	}
+	assert.Equal(t, exitPartial, run(ctx, []string{"./../../testdata/src/..."}, complexity.Analyzer))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, l := range lines {
+		assert.True(t, json.Valid([]byte(l)), l)
+	}
+	last := jsonStats{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, "stats", last.Kind)
+	assert.True(t, last.Partial)
+	assert.Contains(t, last.SkippedPackages, "github.com/fikin/go-complexity-analysis/testdata/src/halstead")
+}
+
+func TestTimeBudget(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-timebudget", "1ns", "./../../testdata/src/...")
+	out, err := cmd.CombinedOutput()
+	assert.Error(t, err)
+	assert.Equal(t, exitPartial, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "time budget of 1ns exceeded")
+
+	cmd = exec.Command(bin, "-timebudget", "1m", "./../../testdata/src/a")
+	out, err = cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestColumns(t *testing.T) {
+	stats := complexity.FuncStatsType{Filename: "a.go", Line: 3, FunctionName: "f", CyclomaticComplexity: 12, MaintenabilityIndex: 40, HalsteadVolume: 1.5}
+	assert.Len(t, formatColumns(allColumns, stats), len(allColumns))
+	assert.Equal(t, "a.go,3,f,12,40,0.000,1.500", strings.Join(formatColumns(allColumns[:7], stats), ","))
+
+	cols, err := parseColumns("name,cyclo,filename")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"f", "12", "a.go"}, formatColumns(cols, stats))
+
+	_, err = parseColumns("name,complexity")
+	assert.EqualError(t, err, `unknown column "complexity", valid columns are: `+strings.Join(columnNames(allColumns), ","))
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	res := newGobResults([]complexity.FuncStatsType{
+		{Filename: "a.go", Line: 3, FunctionName: "f", CyclomaticComplexity: 12, HalsteadVolume: 1.5, IsTooComplex: true},
+		{Filename: "b.go", Line: 7, FunctionName: "g", SLOC: 4, Generated: true},
+	}, true)
+	assert.Equal(t, gobSchemaVersion, res.SchemaVersion)
+
+	buf := &bytes.Buffer{}
+	doPrintGob(buf, res)
+	decoded, err := readGob(buf)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(res)
+	actual, _ := json.Marshal(decoded)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	res.SchemaVersion = gobSchemaVersion + 1
+	buf.Reset()
+	assert.NoError(t, gob.NewEncoder(buf).Encode(res))
+	_, err = readGob(buf)
+	assert.EqualError(t, err, fmt.Sprintf("unsupported schema version %d, expected %d", gobSchemaVersion+1, gobSchemaVersion))
+}
+
+func TestCmdGobDecode(t *testing.T) {
+	bin := buildCmd(t)
+
+	csv, _ := exec.Command(bin, "-cycloover", "5", "-out-format", "csv", "./../../testdata/src/a").Output()
+	assert.NotEmpty(t, string(csv))
+
+	gobFile := filepath.Join(t.TempDir(), "results.gob")
+	out, _ := exec.Command(bin, "-cycloover", "5", "-out-format", "gob", "./../../testdata/src/a").Output()
+	assert.NoError(t, os.WriteFile(gobFile, out, 0o600))
+
+	decoded, err := exec.Command(bin, "decode", "-to", "csv", gobFile).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, string(csv), string(decoded))
+
+	decoded, err = exec.Command(bin, "decode", gobFile).Output()
+	assert.NoError(t, err)
+	res := gobResults{}
+	assert.NoError(t, json.Unmarshal(decoded, &res))
+	assert.Equal(t, gobSchemaVersion, res.SchemaVersion)
+	assert.Len(t, res.Functions, 6)
+}
+
+func TestLegacyNames(t *testing.T) {
+	bin := buildCmd(t)
+
+	gobFile := filepath.Join(t.TempDir(), "results.gob")
+	out, _ := exec.Command(bin, "-out-format", "gob", "./../../testdata/src/a").Output()
+	assert.NoError(t, os.WriteFile(gobFile, out, 0o600))
+
+	current, err := exec.Command(bin, "decode", gobFile).Output()
+	assert.NoError(t, err)
+	assert.Contains(t, string(current), `"HalsteadDifficulty":`)
+	assert.Contains(t, string(current), `"HalsteadVolume":`)
+	assert.NotContains(t, string(current), "Halsbread")
+
+	legacy, err := exec.Command(bin, "-legacynames", "decode", gobFile).Output()
+	assert.NoError(t, err)
+	assert.Contains(t, string(legacy), `"HalsbreadDifficulty":`)
+	assert.Contains(t, string(legacy), `"HalsbreadVolume":`)
+	assert.NotContains(t, string(legacy), "HalsteadDifficulty")
+	assert.Equal(t, strings.Count(string(current), "\n"), strings.Count(string(legacy), "\n"))
+
+	currentCsv, err := exec.Command(bin, "decode", "-to", "csv", gobFile).Output()
+	assert.NoError(t, err)
+	legacyCsv, err := exec.Command(bin, "-legacynames", "decode", "-to", "csv", gobFile).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, string(currentCsv), string(legacyCsv))
+
+	lines, _ := exec.Command(bin, "-legacynames", "-out-format", "json", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(lines), `"HalsbreadDifficulty":`)
+	assert.Contains(t, string(lines), `"HalsbreadVolume":`)
+	assert.NotContains(t, string(lines), "HalsteadDifficulty")
+
+	// results written before the rename carry only the old fields
+	buf := &bytes.Buffer{}
+	old := newGobResults([]complexity.FuncStatsType{{FunctionName: "f", HalsbreadDifficulty: 2.5, HalsbreadVolume: 18}}, false)
+	assert.NoError(t, gob.NewEncoder(buf).Encode(old))
+	res, err := readGob(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, res.Functions[0].HalsteadDifficulty)
+	assert.Equal(t, 18.0, res.Functions[0].HalsteadVolume)
+}
+
+func TestTodoReport(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-todoreport", "-cycloover", "2", "./../../testdata/src/todo")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.True(t, strings.HasSuffix(stderr.String(), "todo.go:4: func branchy is over cyclo and has 3 markers\n"+
+		"\tTODO: split up the branches, FIXME the order\n"+
+		"\tHACK until the comparator lands\n"), stderr.String())
+	assert.Equal(t, 1, strings.Count(stderr.String(), " markers\n"))
+}
+
+func TestStream(t *testing.T) {
+	bin := buildCmd(t)
+
+	buffered, _ := exec.Command(bin, "-cycloover", "5", "-out-format", "csv", "./../../testdata/src/...").Output()
+	streamed, _ := exec.Command(bin, "-stream", "-cycloover", "5", "-out-format", "csv", "./../../testdata/src/...").Output()
+	assert.NotEmpty(t, string(streamed))
+	assert.Equal(t, string(buffered), string(streamed))
+	// a single header row, though printed per package
+	assert.Equal(t, 1, strings.Count(string(streamed), "filename,line,name,"))
+
+	cmd := exec.Command(bin, "-stream", "-cycloover", "5", "-out-format", "checkstyle", "./../../testdata/src/a")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), "-stream disables: -out-format checkstyle")
+	assert.Contains(t, stdout.String(), "seems to be complex")
+	assert.NotContains(t, stdout.String(), "<checkstyle")
+
+	// the riskiest first needs the rows of all packages
+	cmd = exec.Command(bin, "-stream", "-sort", "score", "-cycloover", "5", "-out-format", "csv", "./../../testdata/src/...")
+	stdout.Reset()
+	stderr.Reset()
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	_ = cmd.Run()
+	assert.Contains(t, stderr.String(), "-stream disables: -sort score")
+	assert.Equal(t, string(buffered), stdout.String())
+
+	// buffered output reports the progress
+	cmd = exec.Command(bin, "-progress", "2", "-out-format", "csv", "./../../testdata/src/...")
+	stderr.Reset()
+	cmd.Stderr = stderr
+	_ = cmd.Run()
+	assert.Contains(t, stderr.String(), "analyzed 2 of ")
+	assert.NotContains(t, stderr.String(), "analyzed 1 of ")
+}
+
+func TestSuppressed(t *testing.T) {
+	bin := buildCmd(t)
+
+	out, err := exec.Command(bin, "-cycloover", "1", "./../../testdata/src/suppress").Output()
+	assert.Error(t, err)
+	// clamp ignores only the maint rule
+	assert.Equal(t, 2, strings.Count(string(out), " seems to "), string(out))
+	assert.Contains(t, string(out), "func encode seems to be complex")
+	assert.Contains(t, string(out), "func clamp seems to be complex")
+
+	out, _ = exec.Command(bin, "-cycloover", "1", "-out-format", "csv", "-columns", "name,suppressed,suppressreason", "./../../testdata/src/suppress").Output()
+	assert.Equal(t, "name,suppressed,suppressreason\ndispatch,true,one case per opcode\ndecode,true,generated from the opcode table\nencode,false,\nclamp,false,\n", string(out))
+}
+
+func TestThresholdDirectives(t *testing.T) {
+	bin := buildCmd(t)
+	out, err := exec.Command(bin, "-cycloover", "2", "-maintunder", "0", "./../../testdata/src/thresholds").Output()
+	assert.Error(t, err)
+	assert.Contains(t, string(out), "func strict seems to be complex (cyclomatic complexity=2)")
+	assert.NotContains(t, string(out), "func loop")
+	assert.Contains(t, string(out), "unknown threshold directive")
+
+	out, _ = exec.Command(bin, "-cycloover", "2", "-maintunder", "0", "-out-format", "csv", "-columns", "name,cyclo,cycloover,maint,maintunder", "./../../testdata/src/thresholds").Output()
+	assert.Contains(t, string(out), "strict,2,1,")
+	assert.True(t, strings.HasSuffix(string(out), ",100\n"), string(out))
+}
+
+func TestConfigFile(t *testing.T) {
+	bin := buildCmd(t)
+
+	// found walking up from the package
+	out, err := exec.Command(bin, "-columns", "name,cyclo", "./testdata/config/nested").Output()
+	assert.Error(t, err)
+	assert.Equal(t, "name,cyclo\nBranchy,2\n", string(out))
+
+	// flags override the file
+	out, err = exec.Command(bin, "-cycloover", "5", "-csv-no-header", "./testdata/config/nested").Output()
+	assert.NoError(t, err)
+	assert.Empty(t, string(out))
+	out, _ = exec.Command(bin, "-out-format", "txt", "-exclude-func", "Nothing", "./testdata/config/nested").Output()
+	assert.Contains(t, string(out), "func Branchy seems to be complex")
+	assert.Contains(t, string(out), "func Skipped seems to be complex")
+
+	// an explicit file replaces the discovered one
+	cfg := filepath.Join(t.TempDir(), "complexity.yml")
+	assert.NoError(t, os.WriteFile(cfg, []byte("linters-settings:\n  complexity:\n    cyclo-over: 1\n"), 0o600))
+	out, _ = exec.Command(bin, "-config", cfg, "./testdata/config/nested").Output()
+	assert.Contains(t, string(out), "func Skipped seems to be complex")
+
+	// unknown keys are named
+	assert.NoError(t, os.WriteFile(cfg, []byte("linters-settings:\n  complexity:\n    cyclo-ovr: 1\n"), 0o600))
+	cmd := exec.Command(bin, "-c", cfg, "./testdata/config/nested")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), "field cyclo-ovr not found")
+
+	// an alias given on the cmdline overrides the file value of its flag
+	assert.NoError(t, os.WriteFile(cfg, []byte("linters-settings:\n  complexity:\n    cognitive-over: 1\n"), 0o600))
+	out, _ = exec.Command(bin, "-c", cfg, "-out-format", "txt", "./../../testdata/src/cognitive").Output()
+	assert.Contains(t, string(out), "seems to be hard to understand")
+	out, _ = exec.Command(bin, "-c", cfg, "-cogover", "100", "-out-format", "txt", "./../../testdata/src/cognitive").Output()
+	assert.NotContains(t, string(out), "seems to be hard to understand")
+
+	jsonCfg := filepath.Join(t.TempDir(), "complexity.json")
+	assert.NoError(t, os.WriteFile(jsonCfg, []byte(`{"run": {"test": true}}`), 0o600))
+	_, err = parseConfig(jsonCfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "test"`)
+}
+
+func TestPackageTotals(t *testing.T) {
+	funcs := []complexity.FuncResult{
+		{FuncStatsType: complexity.FuncStatsType{FunctionName: "complex", CyclomaticComplexity: 12, MaintenabilityIndex: 40, LOC: 30, HalsteadVolume: 100, Locals: 4, Score: 0.6, IsTooComplex: true, Grade: "C"}},
+		{FuncStatsType: complexity.FuncStatsType{FunctionName: "simple", CyclomaticComplexity: 1, MaintenabilityIndex: 90, LOC: 3, HalsteadVolume: 10, Score: 0.1, Grade: "A"}},
+		{FuncStatsType: complexity.FuncStatsType{FunctionName: "accepted", CyclomaticComplexity: 20, MaintenabilityIndex: 30, LOC: 50, HalsteadVolume: 200, Locals: 2, Score: 0.8, IsTooComplex: true, Suppressed: true, Grade: "D"}},
+	}
+	res := &complexity.Result{Functions: funcs, MaintainabilityIndex: 35, Halstead: complexity.HalsteadAggregate{Difficulty: 2, Volume: 400, Effort: 800}}
+	reported := newPackageTotals("p", res, false)
+	assert.Equal(t, []string{"totals", "p", "1", "12", "40", "0.000", "100.000", "0.000", "30", "0", "0", "0", "4", "0.600", "35", "0", "0", "1", "0", "0", "0", "400.000", "2.000", "800.000", "0", "0", "0", "0", "0", "0.000"}, reported.record(totalsModeSum))
+	all := newPackageTotals("p", res, true)
+	assert.Equal(t, []string{"totals", "p", "3", "33", "160", "0.000", "310.000", "0.000", "83", "0", "0", "0", "6", "1.500", "35", "1", "0", "1", "1", "0", "0", "400.000", "2.000", "800.000", "0", "0", "0", "0", "0", "0.000"}, all.record(totalsModeSum))
+	// average, median and maximum, or minimum for the maintainability index
+	assert.Equal(t, []string{"totals", "p", "3",
+		"11.000", "12.000", "20", "53.333", "40.000", "30", "0.000", "0.000", "0.000", "103.333", "100.000", "200.000",
+		"0.000", "0.000", "0.000", "27.667", "30.000", "50", "0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0", "2.000", "2.000", "4", "0.500", "0.600", "0.800", "35", "1", "0", "1", "1", "0", "0", "400.000", "2.000", "800.000", "0", "0", "0", "0", "0", "0.000"},
+		all.record(totalsModeStats))
+	assert.Equal(t, []string{"totals", "p", "0",
+		"0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0.000", "0.000", "0.000", "0.000",
+		"0.000", "0.000", "0.000", "0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0", "0.000", "0.000", "0.000", "100", "0", "0", "0", "0", "0", "0", "0.000", "0.000", "0.000", "0", "0", "0", "0", "0", "0.000"},
+		newPackageTotals("p", &complexity.Result{MaintainabilityIndex: 100}, true).record(totalsModeStats))
+	assert.Equal(t, 2.5, median([]float64{4, 1, 3, 2}))
+
+	bin := buildCmd(t)
+	lastRow := func(args ...string) string {
+		out, _ := exec.Command(bin, append(args, "-out-format", "csv", "-csvtotals", "./../../testdata/src/a")...).Output()
+		rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+		return rows[len(rows)-1]
+	}
+	assert.True(t, strings.HasPrefix(lastRow("-cycloover", "5"), "totals,github.com/fikin/go-complexity-analysis/testdata/src/a,1,8,57,"), lastRow("-cycloover", "5"))
+	assert.True(t, strings.HasPrefix(lastRow("-cycloover", "5", "-allfuncs"), "totals,github.com/fikin/go-complexity-analysis/testdata/src/a,6,"))
+	// a clean package has no reported functions, but all of them count with -allfuncs
+	assert.True(t, strings.HasPrefix(lastRow(), "totals,github.com/fikin/go-complexity-analysis/testdata/src/a,0,0,0,"))
+	assert.True(t, strings.HasPrefix(lastRow("-allfuncs"), "totals,github.com/fikin/go-complexity-analysis/testdata/src/a,6,"))
+	assert.Equal(t, "totals,github.com/fikin/go-complexity-analysis/testdata/src/a,6,"+
+		"3.167,2.500,8,69.500,70.500,57,4.398,3.943,11.000,63.038,37.932,144.000,0.006,0.002,0.024,9.667,7.000,20,8.500,6.500,16,2.833,1.500,10,4.500,2.000,12,0.333,0.000,1,0.231,0.235,0.408,42,1,3,2,0,0,0,532.502,17.882,9522.394,1,0,0,0,0,0.000",
+		lastRow("-allfuncs", "-totals-mode", "stats"))
+
+	cmd := exec.Command(bin, "-totals-mode", "avg", "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), `unknown totals mode "avg", valid are: sum, stats`)
+}
+
+func TestFileTotals(t *testing.T) {
+	ft := fileTotals{Filename: "a.go", Functions: []complexity.FuncStatsType{
+		{FunctionName: "complex", CyclomaticComplexity: 12, MaintenabilityIndex: 40, LOC: 30},
+		{FunctionName: "simple", CyclomaticComplexity: 1, MaintenabilityIndex: 90, LOC: 3},
+	}}
+	assert.Equal(t, []string{"file", "a.go", "2", "13", "6.500", "40", "33", "0.000", "0.000", "0.000"}, ft.record())
+
+	bin := buildCmd(t)
+	fileRows := func(args ...string) []string {
+		out, _ := exec.Command(bin, append(args, "-out-format", "csv", "-csvfiles", "./../../testdata/src/halstead")...).Output()
+		rows := []string{}
+		for _, r := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if strings.HasPrefix(r, "file,") {
+				rows = append(rows, r)
+			}
+		}
+		return rows
+	}
+	rows := fileRows("-allfuncs")
+	assert.Len(t, rows, 2)
+	assert.True(t, strings.HasSuffix(rows[0], "halstead/a.go,5,14,2.800,57,39,411.198,19.000,7812.768"), rows)
+	assert.True(t, strings.HasSuffix(rows[1], "halstead/b.go,8,20,2.500,62,52,856.536,37.583,32191.487"), rows)
+	// only the reported functions by default, and excluded files have no row
+	rows = fileRows("-cycloover", "3")
+	assert.Len(t, rows, 2)
+	assert.True(t, strings.HasSuffix(rows[1], "halstead/b.go,3,12,4.000,62,28,856.536,37.583,32191.487"), rows)
+	rows = fileRows("-allfuncs", "-exclude-file", `b\.go$`)
+	assert.Len(t, rows, 1)
+
+	out, _ := exec.Command(bin, "-csvfiles", "-cycloover", "3", "./../../testdata/src/halstead").Output()
+	assert.Contains(t, string(out), "halstead/a.go: 1 functions, cyclomatic complexity 8 (average 8.000), worst maintainability index 57, 20 lines of code\n")
+}
+
+func TestHistogram(t *testing.T) {
+	funcs := []complexity.FuncStatsType{}
+	for _, c := range []int{1, 5, 6, 20, 21, 51} {
+		funcs = append(funcs, complexity.FuncStatsType{CyclomaticComplexity: c, MaintenabilityIndex: c * 2})
+	}
+	assert.Equal(t, []histogramBucket{{"1-5", 2}, {"6-10", 1}, {"11-20", 1}, {"21-50", 1}, {">50", 1}}, cycloHistogram(funcs, []int{1, 5, 10, 20, 50}))
+	assert.Equal(t, []histogramBucket{{"0-3", 1}, {"4-10", 2}, {">10", 3}}, cycloHistogram(funcs, []int{0, 3, 10}))
+	maint := maintHistogram(append(funcs, complexity.FuncStatsType{MaintenabilityIndex: 100}))
+	assert.Len(t, maint, 10)
+	assert.Equal(t, histogramBucket{"0-9", 1}, maint[0])
+	assert.Equal(t, histogramBucket{"90-100", 2}, maint[9])
+
+	values := []float64{}
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+	assert.Equal(t, 50.0, percentile(values, 50))
+	assert.Equal(t, 90.0, percentile(values, 90))
+	assert.Equal(t, 99.0, percentile(values, 99))
+	assert.Equal(t, 7.0, percentile([]float64{7}, 99))
+	assert.Equal(t, 0.0, percentile(nil, 50))
+
+	defer func() { histogramBuckets = []int{1, 5, 10, 20, 50} }()
+	assert.NoError(t, parseHistogramBuckets("1, 3,7"))
+	assert.Equal(t, []int{1, 3, 7}, histogramBuckets)
+	assert.EqualError(t, parseHistogramBuckets("1,5,5"), "histogram buckets must be increasing, 5 follows 5")
+	assert.EqualError(t, parseHistogramBuckets("1"), `histogram buckets need at least 2 bounds, got "1"`)
+	assert.Error(t, parseHistogramBuckets("1,x"))
+
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-histogram", "-histogram-buckets", "1,2,5", "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.NoError(t, cmd.Run())
+	assert.Contains(t, stderr.String(), "cyclomatic complexity of 6 functions:\n\t1-2: 3\n\t3-5: 2\n\t>5: 1\n")
+	assert.Contains(t, stderr.String(), "\t70-79: 3\n")
+	assert.Contains(t, stderr.String(), "percentiles p50/p90/p99:\n\tcyclo: 2/8/8\n\tmaint: 70/86/86\n")
+}
+
+func TestSummaryFormat(t *testing.T) {
+	c := summaryCounts{}
+	c.add(complexity.FuncStatsType{FunctionName: "both", CyclomaticComplexity: 41, IsTooComplex: true, IsNotMaintenable: true})
+	c.add(complexity.FuncStatsType{FunctionName: "long", CyclomaticComplexity: 3, IsTooManyStatements: true})
+	c.add(complexity.FuncStatsType{FunctionName: "accepted", CyclomaticComplexity: 50, IsTooComplex: true, Suppressed: true})
+	c.add(complexity.FuncStatsType{FunctionName: "untouched", CyclomaticComplexity: 60, IsTooComplex: true, Unchanged: true})
+	c.add(complexity.FuncStatsType{FunctionName: "clean", CyclomaticComplexity: 1})
+	assert.Equal(t, "1/4 functions over cyclo threshold, 1 under maintainability, 1 over other thresholds, worst: accepted cyclo=50", c.String())
+	assert.Equal(t, "0/0 functions over cyclo threshold, 0 under maintainability", summaryCounts{}.String())
+
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-out-format", "pkgsummary", "-cycloover", "5", "-maintunder", "60", "./../../testdata/src/a", "./../../testdata/src/apireach")
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	assert.Error(t, cmd.Run(), "violations still fail the run")
+	assert.Equal(t, "pkg github.com/fikin/go-complexity-analysis/testdata/src/a: 1/6 functions over cyclo threshold, 2 under maintainability, worst: f2 cyclo=8\n"+
+		"pkg github.com/fikin/go-complexity-analysis/testdata/src/apireach: 0/6 functions over cyclo threshold, 0 under maintainability, worst: (*Server).ServeHTTP cyclo=2\n"+
+		"total: 1/12 functions over cyclo threshold, 2 under maintainability, worst: f2 cyclo=8\n", stdout.String())
+
+	out, err := exec.Command(bin, "-out-format", "pkgsummary", "./../../testdata/src/a").Output()
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "total: 0/6 functions over cyclo threshold")
+	out, _ = exec.Command(bin, "-out-format", "pkgsummary", "-cycloover", "5", "file", "../../testdata/src/a/a.go").Output()
+	assert.Equal(t, "file ../../testdata/src/a/a.go: 1/6 functions over cyclo threshold, 0 under maintainability, worst: f2 cyclo=8\n"+
+		"total: 1/6 functions over cyclo threshold, 0 under maintainability, worst: f2 cyclo=8\n", string(out))
+
+	for _, conflict := range [][]string{{"-csvtotals"}, {"-columns", "name"}, {"-stream"}} {
+		cmd := exec.Command(bin, append(append([]string{"-out-format", "pkgsummary"}, conflict...), "./../../testdata/src/a")...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		assert.Error(t, cmd.Run())
+		assert.Contains(t, stderr.String(), conflict[0]+" has no effect with -out-format pkgsummary", conflict)
+	}
+}
+
+func TestOutputFlagConflicts(t *testing.T) {
+	bin := buildCmd(t)
+	for _, args := range [][]string{{"-out-format", "checkstyle", "-csvtotals"}, {"-sort", "score"}, {"-out-format", "sarif", "-columns", "name"}} {
+		cmd := exec.Command(bin, append(args, "./../../testdata/src/a")...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		assert.Error(t, cmd.Run())
+		assert.Contains(t, stderr.String(), "has no effect with -out-format", args)
+	}
+	_, err := exec.Command(bin, "-out-format", "json", "-columns", "name", "./../../testdata/src/a").Output()
+	assert.NoError(t, err)
+	_, err = exec.Command(bin, "-csvfiles", "./../../testdata/src/a").Output()
+	assert.NoError(t, err)
+}
+
+func TestMaxIssues(t *testing.T) {
+	arr := []foundDiagnosticsStruct{
+		{diagnostics: []analysis.Diagnostic{{Message: "a"}, {Message: "b"}, {Message: "broken", Category: parseErrorRule}}, funcViolations: 2},
+		{diagnostics: []analysis.Diagnostic{{Message: "c", Category: complexity.PkgMaintCategory}}},
+	}
+	n, failed := countViolations(arr)
+	assert.Equal(t, 3, n)
+	assert.False(t, failed)
+	_, failed = countViolations(append(arr, foundDiagnosticsStruct{err: errors.New("failed")}))
+	assert.True(t, failed)
+
+	bin := buildCmd(t)
+	runWith := func(args ...string) (int, string) {
+		cmd := exec.Command(bin, append(args, "./../../testdata/src/a")...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		_ = cmd.Run()
+		return cmd.ProcessState.ExitCode(), stderr.String()
+	}
+	code, stderr := runWith("-cycloover", "5", "-maxissues", "1")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "complexity: 1 violations (budget 1)\n")
+	code, stderr = runWith("-cycloover", "1", "-maxissues", "1")
+	assert.Equal(t, 1, code)
+	assert.Regexp(t, `complexity: \d+ violations, over the budget of 1\n`, stderr)
+	// a function counts once per violated rule, like in the summary
+	code, stderr = runWith("-summary", "-cycloover", "5", "-maintunder", "60", "-maxissues", "2")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "3 violations in 2 functions")
+	assert.Contains(t, stderr, "complexity: 3 violations, over the budget of 2\n")
+	// without a budget any violation fails, silently
+	code, stderr = runWith("-cycloover", "5")
+	assert.Equal(t, 1, code)
+	assert.NotContains(t, stderr, "budget")
+}
+
+func TestMethodNames(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-cycloover", "0", "./../../testdata/src/exclude").Output()
+	assert.Contains(t, string(out), "server.go:7: func (*Server).Close seems to be complex (cyclomatic complexity=1)")
+	assert.Contains(t, string(out), "server.go:9: func (Server).Name seems to be complex (cyclomatic complexity=1)")
+	assert.Contains(t, string(out), "server.go:11: func (*List[T]).Len seems to be complex (cyclomatic complexity=1)")
+	out, _ = exec.Command(bin, "-cycloover", "0", "-out-format", "csv", "-columns", "name", "./../../testdata/src/exclude").Output()
+	assert.Contains(t, strings.Split(string(out), "\n"), "(*Server).Close")
+	assert.Contains(t, strings.Split(string(out), "\n"), "Close")
+}
+
+func TestPathMode(t *testing.T) {
+	defer func(mode, root string) { pathMode, pathRoot = mode, root }(pathMode, pathRoot)
+	pathMode, pathRoot = "", ""
+	assert.Equal(t, "a/b.go", printedPath("/src/a/b.go", "/src"))
+	assert.Equal(t, "/src/a/b.go", printedPath("/src/a/b.go", ""))
+	pathMode, pathRoot = pathModeRel, "/src/a"
+	assert.Equal(t, "b.go", printedPath("/src/a/b.go", ""))
+	// outside of the root
+	assert.Equal(t, "/src/c/d.go", printedPath("/src/c/d.go", "/src"))
+	assert.Equal(t, "b.go:3: func f seems to be complex", printedMessage("/src/a/b.go", "/src/a/b.go:3: func f seems to be complex", ""))
+	assert.Equal(t, "no file", printedMessage("/src/a/b.go", "no file", ""))
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	root := findModuleRoot(wd)
+	assert.Equal(t, filepath.Dir(filepath.Dir(wd)), root)
+
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-path-mode", "module", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), " : testdata/src/a/a.go:16: func f2 seems to be complex")
+	out, _ = exec.Command(bin, "-path-mode", "rel", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), " : "+filepath.Join(root, "testdata/src/a/a.go")+":16: func f2")
+	out, _ = exec.Command(bin, "-path-mode", "module", "-cycloover", "5", "-out-format", "csv", "-columns", "filename,line", "./../../testdata/src/a").Output()
+	assert.Equal(t, "filename,line\ntestdata/src/a/a.go,16\n", string(out))
+	out, _ = exec.Command(bin, "-path-mode", "abs", "-cycloover", "5", "-out-format", "csv", "-columns", "filename,line", "./../../testdata/src/a").Output()
+	assert.Equal(t, "filename,line\n"+filepath.Join(root, "testdata/src/a/a.go")+",16\n", string(out))
+
+	cmd := exec.Command(bin, "-path-mode", "home", "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), `unknown path mode "home", valid are: abs, rel, module`)
+}
+
+func TestGradeColumn(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "csv", "-allfuncs", "-columns", "name,cyclo,maint,grade", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.Equal(t, "name,cyclo,maint,grade\nf2,8,57,C\n", string(out))
+	out, _ = exec.Command(bin, "-out-format", "csv", "-grades", "ok:8:50,bad", "-columns", "name,grade", "-cycloover", "5", "-csvtotals", "-allfuncs", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), "f2,ok\n")
+	assert.True(t, strings.HasSuffix(string(out), ",42,6,0,532.502,17.882,9522.394,1,0,0,0,0,0.000\n"), string(out))
+}
+
+func TestMICommentColumns(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "csv", "-maintunder", "75", "./../../testdata/src/micomments").Output()
+	header := strings.SplitN(string(out), "\n", 2)[0]
+	assert.True(t, strings.HasSuffix(header, ",grade,fanout,locals,gostmts,chanops,selects,selectcases,synccalls,concurrency,recursive,declonly,score,endline,span,nameline,namecol,defers,defersinloop,maxlivedefers,nesting"), header)
+	assert.Equal(t, 3, strings.Count(string(out), "\n"), "both fail without the comment bonus")
+	out, _ = exec.Command(bin, "-out-format", "csv", "-mi-with-comments", "-maintunder", "75", "./../../testdata/src/micomments").Output()
+	assert.True(t, strings.HasSuffix(strings.SplitN(string(out), "\n", 2)[0], ",grade,fanout,locals,gostmts,chanops,selects,selectcases,synccalls,concurrency,recursive,declonly,score,endline,span,nameline,namecol,defers,defersinloop,maxlivedefers,nesting,comments,maintclassic"), string(out))
+	out, _ = exec.Command(bin, "-out-format", "csv", "-mi-with-comments", "-maintunder", "75", "-columns", "name,maint,maintclassic,comments", "./../../testdata/src/micomments").Output()
+	assert.Equal(t, "name,maint,maintclassic,comments\nrouteTerse,67,53,1\n", string(out))
+}
+
+func TestMIScaleOutputs(t *testing.T) {
+	bin := buildCmd(t)
+	csvOf := func(args ...string) string {
+		out, _ := exec.Command(bin, append(args, "-out-format", "csv", "-columns", "name,maint", "-csvtotals", "-totals-mode", "stats", "-allfuncs", "-cycloover", "5", "./../../testdata/src/a")...).Output()
+		return string(out)
+	}
+	vs := csvOf()
+	assert.Contains(t, vs, "f2,57\n")
+	// the average, median and worst maintainability index of the functions, then the one of the package
+	assert.Contains(t, vs, ",69.500,70.500,57,")
+	assert.Contains(t, vs, ",0.494,42,")
+	raw := csvOf("-mi-scale", "raw")
+	assert.Contains(t, raw, "f2,98\n")
+	assert.Contains(t, raw, ",119.333,121.000,98,")
+	assert.Contains(t, raw, ",0.493,72,")
+	out, _ := exec.Command(bin, "-mi-scale", "raw", "-maintunder", "100", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), "a.go:16: func f2 seems to have low maintainability (maintainability index=98)")
+	assert.Equal(t, 1, strings.Count(string(out), " seems to "))
+}
+
+func TestHalsteadRawOutputs(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "csv", "-cycloover", "5", "-csvtotals", "-totals-mode", "stats", "-allfuncs", "./../../testdata/src/a").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.True(t, strings.HasSuffix(rows[0], ",grade,fanout,locals,gostmts,chanops,selects,selectcases,synccalls,concurrency,recursive,declonly,score,endline,span,nameline,namecol,defers,defersinloop,maxlivedefers,nesting"), "default layout")
+	assert.True(t, strings.HasSuffix(rows[2], ",42,1,3,2,0,0,0,532.502,17.882,9522.394,1,0,0,0,0,0.000"), rows[2])
+
+	out, _ = exec.Command(bin, "-halstead-raw", "-out-format", "csv", "-cycloover", "5", "-csvtotals", "-totals-mode", "stats", "-allfuncs", "./../../testdata/src/a").Output()
+	rows = strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.True(t, strings.HasSuffix(rows[0], ",grade,fanout,locals,gostmts,chanops,selects,selectcases,synccalls,concurrency,recursive,declonly,score,endline,span,nameline,namecol,defers,defersinloop,maxlivedefers,nesting,distinctoperators,distinctoperands,operators,operands,vocabulary,length"), rows[0])
+	assert.True(t, strings.HasSuffix(rows[1], ",C,0,1,0,0,0,0,0,0,false,false,0.494,35,20,16,6,0,0,0,3,11,5,26,10,16,36"), rows[1])
+	// distinct counts summed per function
+	assert.True(t, strings.HasSuffix(rows[2], ",42,1,3,2,0,0,0,532.502,17.882,9522.394,1,0,0,0,0,0.000,40,23,71,32"), rows[2])
+
+	gob, _ := exec.Command(bin, "-halstead-raw", "-out-format", "gob", "-cycloover", "5", "./../../testdata/src/a").Output()
+	cmd := exec.Command(bin, "decode")
+	cmd.Stdin = bytes.NewReader(gob)
+	decoded, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.Contains(t, string(decoded), `"HalsteadLength": 36`)
+}
+
+func TestCSVTypes(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "csv", "-csvtypes", "./../../testdata/src/typestats").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	// no function is reported, only the header precedes the type rows
+	assert.Len(t, rows, 7)
+	assert.True(t, strings.HasPrefix(rows[0], "filename,"), rows[0])
+	for i, suffix := range []string{
+		"/typestats/store.go,5,base,struct,0,2,0",
+		"/typestats/store.go,11,Store,struct,3,5,0",
+		"/typestats/store.go,26,Reader,interface,0,0,1",
+		"/typestats/store.go,31,ReadWriter,interface,0,0,3",
+		"/typestats/store.go,37,Names,other,1,0,0",
+		"/typestats/store_more.go,11,List,struct,1,1,0",
+	} {
+		assert.True(t, strings.HasPrefix(rows[i+1], "type,"), rows[i+1])
+		assert.True(t, strings.HasSuffix(rows[i+1], suffix), rows[i+1])
+	}
+
+	cmd := exec.Command(bin, "-typestats", "-fieldsover", "4", "./../../testdata/src/typestats")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "store.go:11: type Store seems to hold too much (fields=5)")
+	assert.Equal(t, 1, strings.Count(string(out), "\n"), string(out))
+
+	out, _ = exec.Command(bin, "-out-format", "pkgsummary", "-csvtypes", "./../../testdata/src/typestats").CombinedOutput()
+	assert.Contains(t, string(out), "-csvtypes has no effect with -out-format pkgsummary")
+}
+
+func TestCSVMethodsByType(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "csv", "-csvmethods-by-type", "./../../testdata/src/typestats").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	// only the types with methods, without the type rows
+	assert.Len(t, rows, 4)
+	for i, suffix := range []string{
+		"/typestats/store.go,11,Store,3,3,1,",
+		"/typestats/store.go,37,Names,1,1,1,",
+		"/typestats/store_more.go,11,List,1,1,1,",
+	} {
+		assert.True(t, strings.HasPrefix(rows[i+1], "methods,"), rows[i+1])
+		assert.Contains(t, rows[i+1], suffix)
+	}
+	assert.True(t, strings.HasSuffix(rows[1], ",9"), rows[1])
+
+	out, _ = exec.Command(bin, "-out-format", "csv", "-csvtypes", "-csvmethods-by-type", "./../../testdata/src/typestats").Output()
+	rows = strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, rows, 10)
+	assert.True(t, strings.HasPrefix(rows[6], "type,"), rows[6])
+	assert.True(t, strings.HasPrefix(rows[7], "methods,"), rows[7])
+
+	cmd := exec.Command(bin, "-typestats", "-typecycloover", "2", "./../../testdata/src/typestats")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "store.go:11: type Store seems to be complex (methods cyclomatic complexity=3)")
+}
+
+func TestColorMessage(t *testing.T) {
+	s := complexity.FuncStatsType{FunctionName: "(*T).f", CyclomaticComplexity: 12, CycloOver: 10, MaintenabilityIndex: 50, MaintUnder: 20, IsTooComplex: true, Grade: "C"}
+	msg := "a.go:3: " + complexity.ToDiagnosticMsg(s) + "\n"
+	assert.Equal(t, msg, colorMessage(msg, s, false))
+	assert.Equal(t, "a.go:3: func \033[1m(*T).f\033[0m seems to be complex \033[33m(cyclomatic complexity=12)\033[0m, grade C\n", colorMessage(msg, s, true))
+
+	s.CyclomaticComplexity = 21
+	assert.Contains(t, colorMessage(complexity.ToDiagnosticMsg(s), s, true), "\033[31m(cyclomatic complexity=21)\033[0m")
+	s.CyclomaticComplexity, s.IsTooComplex, s.IsNotMaintenable = 5, false, true
+	s.MaintenabilityIndex = 15
+	assert.Contains(t, colorMessage(complexity.ToDiagnosticMsg(s), s, true), "\033[33m(maintainability index=15)")
+	s.MaintenabilityIndex = 9
+	assert.Contains(t, colorMessage(complexity.ToDiagnosticMsg(s), s, true), "\033[31m(maintainability index=9)")
+	assert.Equal(t, "other message", colorMessage("other message", s, true))
+
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-color", "always", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), "func \033[1mf2\033[0m seems to be complex \033[33m(cyclomatic complexity=8)\033[0m")
+	for _, args := range [][]string{
+		{"-cycloover", "5"},
+		{"-color", "always", "-out-format", "csv", "-cycloover", "5"},
+		{"-color", "always", "-out-format", "checkstyle", "-cycloover", "5"},
+	} {
+		out, _ = exec.Command(bin, append(args, "./../../testdata/src/a")...).Output()
+		assert.NotContains(t, string(out), "\033[", args)
+	}
+	cmd := exec.Command(bin, "-color", "auto", "-cycloover", "5", "./../../testdata/src/a")
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+	out, _ = cmd.Output()
+	assert.NotContains(t, string(out), "\033[")
+	out, _ = exec.Command(bin, "-color", "sometimes", "./../../testdata/src/a").CombinedOutput()
+	assert.Contains(t, string(out), `unknown color mode "sometimes", valid are: auto, always, never`)
+}
+
+func TestCompareFuncs(t *testing.T) {
+	before := []complexity.FuncStatsType{
+		{Package: "p", Filename: "a.go", Line: 3, FunctionName: "f", CyclomaticComplexity: 4, MaintenabilityIndex: 60},
+		{Package: "p", Filename: "a.go", Line: 9, FunctionName: "g", CyclomaticComplexity: 12, MaintenabilityIndex: 30, IsTooComplex: true},
+		{Package: "p", Filename: "a.go", Line: 20, FunctionName: "gone", CyclomaticComplexity: 1},
+		{Package: "p", Filename: "a.go", Line: 30, FunctionName: "same", CyclomaticComplexity: 2, MaintenabilityIndex: 50},
+	}
+	after := []complexity.FuncStatsType{
+		{Package: "p", Filename: "b.go", Line: 5, FunctionName: "f", CyclomaticComplexity: 11, MaintenabilityIndex: 55, IsTooComplex: true},
+		{Package: "p", Filename: "a.go", Line: 9, FunctionName: "g", CyclomaticComplexity: 8, MaintenabilityIndex: 40},
+		{Package: "p", Filename: "a.go", Line: 40, FunctionName: "same", CyclomaticComplexity: 2, MaintenabilityIndex: 50},
+		{Package: "p", Filename: "a.go", Line: 50, FunctionName: "h", CyclomaticComplexity: 1},
+		{Package: "p", Filename: "a.go", Line: 60, FunctionName: "big", CyclomaticComplexity: 15, IsTooComplex: true},
+	}
+	changes := compareFuncs(before, after)
+
+	buf := &bytes.Buffer{}
+	doPrintChanges(buf, changes)
+	assert.Equal(t, `a.go:60: added p.big: over cyclo
+b.go:5: regressed p.f: cyclomatic complexity 4 -> 11, maintainability index 60 -> 55, newly over cyclo
+a.go:20: removed p.gone
+a.go:50: added p.h
+`, buf.String())
+
+	regressions := []string{}
+	for _, c := range changes {
+		if c.Regression() {
+			regressions = append(regressions, c.Key)
+		}
+	}
+	assert.Equal(t, []string{"p.big", "p.f"}, regressions)
+
+	buf.Reset()
+	assert.NoError(t, doPrintChangesCSV(buf, changes))
+	assert.Equal(t, `change,func,file,line,cyclo_old,cyclo_new,maint_old,maint_new,violations_old,violations_new,regression
+added,p.big,a.go,60,,15,,0,,cyclo,true
+regressed,p.f,b.go,5,4,11,60,55,,cyclo,true
+removed,p.gone,a.go,20,1,,0,,,,false
+added,p.h,a.go,50,,1,,0,,,false
+`, buf.String())
+}
+
+func TestCmdCompare(t *testing.T) {
+	bin := buildCmd(t)
+	dir := t.TempDir()
+	writeGob := func(name string, arr []complexity.FuncStatsType) string {
+		buf := &bytes.Buffer{}
+		doPrintGob(buf, newGobResults(arr, false))
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(file, buf.Bytes(), 0o600))
+		return file
+	}
+	oldFile := writeGob("old.gob", []complexity.FuncStatsType{{Package: "p", Filename: "a.go", Line: 3, FunctionName: "f", CyclomaticComplexity: 4}})
+	movedFile := writeGob("moved.gob", []complexity.FuncStatsType{{Package: "p", Filename: "b.go", Line: 8, FunctionName: "f", CyclomaticComplexity: 4}})
+	worseFile := writeGob("worse.gob", []complexity.FuncStatsType{{Package: "p", Filename: "a.go", Line: 3, FunctionName: "f", CyclomaticComplexity: 6}})
+
+	out, err := exec.Command(bin, "compare", oldFile, movedFile).Output()
+	assert.NoError(t, err)
+	assert.Empty(t, string(out))
+
+	out, err = exec.Command(bin, "compare", oldFile, worseFile).Output()
+	assert.Error(t, err)
+	assert.Equal(t, "a.go:3: regressed p.f: cyclomatic complexity 4 -> 6\n", string(out))
+
+	out, _ = exec.Command(bin, "compare", "-to", "csv", worseFile, oldFile).Output()
+	assert.Equal(t, "change,func,file,line,cyclo_old,cyclo_new,maint_old,maint_new,violations_old,violations_new,regression\n", string(out))
+
+	csvFile := filepath.Join(dir, "old.txt")
+	assert.NoError(t, os.WriteFile(csvFile, []byte("filename,line,name,cyclo\na.go,3,f,4\n"), 0o600))
+	cmd := exec.Command(bin, "compare", csvFile, oldFile)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), "old.txt: csv results cannot be compared")
+}
+
+func TestMetricsOutput(t *testing.T) {
+	funcs := []complexity.FuncStatsType{
+		{Package: "p", FunctionName: "init", CyclomaticComplexity: 3, MaintenabilityIndex: 70, LOC: 5},
+		{Package: "p", FunctionName: "init", CyclomaticComplexity: 4, MaintenabilityIndex: 60, LOC: 8},
+		{Package: "p", FunctionName: "small", CyclomaticComplexity: 1, MaintenabilityIndex: 90, LOC: 2},
+	}
+	pkgs := []packageMetrics{{Path: `odd"pkg\`, Functions: 3, SLOC: 20, Violations: 1, MaxCyclo: 4, Maint: 65}}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, doPrintMetrics(buf, funcs, pkgs, 2))
+	assert.Equal(t, `# HELP code_cyclomatic_complexity Cyclomatic complexity of the function.
+# TYPE code_cyclomatic_complexity gauge
+code_cyclomatic_complexity{package="p",function="init"} 3
+code_cyclomatic_complexity{package="p",function="init#2"} 4
+# HELP code_maintainability_index Maintainability index of the function.
+# TYPE code_maintainability_index gauge
+code_maintainability_index{package="p",function="init"} 70
+code_maintainability_index{package="p",function="init#2"} 60
+# HELP code_function_loc Lines of code of the function.
+# TYPE code_function_loc gauge
+code_function_loc{package="p",function="init"} 5
+code_function_loc{package="p",function="init#2"} 8
+# HELP code_package_functions Number of functions of the package.
+# TYPE code_package_functions gauge
+code_package_functions{package="odd\"pkg\\"} 3
+# HELP code_package_sloc Source lines of code of the package.
+# TYPE code_package_sloc gauge
+code_package_sloc{package="odd\"pkg\\"} 20
+# HELP code_package_violations Number of violations of the functions of the package.
+# TYPE code_package_violations gauge
+code_package_violations{package="odd\"pkg\\"} 1
+# HELP code_package_max_cyclomatic_complexity Highest Cyclomatic complexity of the functions of the package.
+# TYPE code_package_max_cyclomatic_complexity gauge
+code_package_max_cyclomatic_complexity{package="odd\"pkg\\"} 4
+# HELP code_package_maintainability_index Maintainability index of the package taken as a whole.
+# TYPE code_package_maintainability_index gauge
+code_package_maintainability_index{package="odd\"pkg\\"} 65
+`, buf.String())
+}
+
+func TestCmdMetrics(t *testing.T) {
+	bin := buildCmd(t)
+
+	out, _ := exec.Command(bin, "-out-format", "metrics", "./../../testdata/src/a", "./../../testdata/src/locals").Output()
+	assert.Equal(t, 1, strings.Count(string(out), "# TYPE code_cyclomatic_complexity gauge\n"))
+	assert.Equal(t, 1, strings.Count(string(out), "# TYPE code_package_functions gauge\n"))
+	assert.Contains(t, string(out), `code_package_functions{package="github.com/fikin/go-complexity-analysis/testdata/src/a"} 6`)
+	assert.Contains(t, string(out), `code_cyclomatic_complexity{package="github.com/fikin/go-complexity-analysis/testdata/src/locals",`)
+
+	out, _ = exec.Command(bin, "-out-format", "metrics", "-metrics-min-cyclo", "100", "./../../testdata/src/a").Output()
+	assert.NotContains(t, string(out), "code_cyclomatic_complexity")
+	assert.Contains(t, string(out), `code_package_functions{package="github.com/fikin/go-complexity-analysis/testdata/src/a"} 6`)
+}
+
+func TestSortByScore(t *testing.T) {
+	bin := buildCmd(t)
+	names := func(out []byte) []string {
+		res := []string{}
+		for _, row := range strings.Split(strings.TrimSpace(string(out)), "\n")[1:] {
+			res = append(res, strings.Split(row, ",")[2])
+		}
+		return res
+	}
+	out, _ := exec.Command(bin, "-out-format", "csv", "-scoreover", "0.01", "./../../testdata/src/a").Output()
+	assert.Equal(t, []string{"f0", "f1", "f2", "f3", "f4", "f5"}, names(out))
+
+	out, _ = exec.Command(bin, "-out-format", "csv", "-scoreover", "0.01", "-sort", "score", "./../../testdata/src/a").Output()
+	assert.Equal(t, []string{"f2", "f3", "f4", "f1", "f5", "f0"}, names(out))
+
+	_, err := exec.Command(bin, "-sort", "cyclo", "./../../testdata/src/a").Output()
+	assert.Error(t, err)
+	_, err = exec.Command(bin, "-score-weights", "cyclo=0.9", "./../../testdata/src/a").Output()
+	assert.Error(t, err)
+}
+
+func TestWarnDeferInLoop(t *testing.T) {
+	bin := buildCmd(t)
+	out, err := exec.Command(bin, "-warn-defer-in-loop", "./../../testdata/src/defers").Output()
+	// informational, it does not fail the run
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "defers.go:33: defer in a loop of func branches runs only when the function returns\n")
+	assert.Equal(t, 3, strings.Count(string(out), "defer in a loop"))
+
+	cmd := exec.Command(bin, "-defersover", "2", "./../../testdata/src/defers")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "func switches seems to juggle too many resources (defer statements=4)")
+}
+
+func TestExportedOnly(t *testing.T) {
+	bin := buildCmd(t)
+	rows := func(args ...string) []string {
+		out, _ := exec.Command(bin, append(append([]string{"-out-format", "csv", "-columns", "name,cyclo", "-cycloover", "1"}, args...), "./../../testdata/src/exported")...).Output()
+		return strings.Split(strings.TrimSpace(string(out)), "\n")[1:]
+	}
+	assert.Len(t, rows(), 5)
+	assert.Equal(t, []string{"New,2", "(*Store).Add,2"}, rows("-exported-only"))
+	assert.Len(t, rows("-exported-only", "-include-unexported-in-csv"), 5)
+	// the totals sum the exported functions only, also with -allfuncs
+	totals := rows("-exported-only", "-include-unexported-in-csv", "-csvtotals", "-allfuncs")
+	assert.True(t, strings.HasPrefix(totals[len(totals)-1], "totals,github.com/fikin/go-complexity-analysis/testdata/src/exported,2,4,"), totals)
+
+	cmd := exec.Command(bin, "-exported-only", "-cycloover", "1", "./../../testdata/src/exported")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Equal(t, 2, strings.Count(string(out), " seems to "), string(out))
+	assert.NoError(t, exec.Command(bin, "-exported-only", "-cycloover", "1", "-exclude-func", `\.New$|\.Add$`, "./../../testdata/src/exported").Run())
+}
+
+func TestBadge(t *testing.T) {
+	// grade boundaries, on the averages of the functions
+	for _, c := range []struct {
+		counts badgeCounts
+		want   shieldsEndpoint
+	}{
+		{badgeCounts{2, 10, 170}, shieldsEndpoint{1, "maintainability", "A (85)", "green"}},
+		{badgeCounts{2, 10, 168}, shieldsEndpoint{1, "maintainability", "B (84)", "yellow"}},
+		{badgeCounts{2, 12, 170}, shieldsEndpoint{1, "maintainability", "B (85)", "yellow"}},
+		{badgeCounts{1, 20, 40}, shieldsEndpoint{1, "maintainability", "C (40)", "orange"}},
+		{badgeCounts{1, 20, 39}, shieldsEndpoint{1, "maintainability", "D (39)", "red"}},
+		{badgeCounts{1, 60, 5}, shieldsEndpoint{1, "maintainability", "F (5)", "red"}},
+		{badgeCounts{}, shieldsEndpoint{1, "maintainability", "A (100)", "green"}},
+	} {
+		assert.Equal(t, c.want, newBadge(c.counts), c.counts)
+	}
+
+	bin := buildCmd(t)
+	path := filepath.Join(t.TempDir(), "badge.json")
+	readBadge := func() map[string]interface{} {
+		buf, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		b := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(buf, &b))
+		return b
+	}
+	// the 6 functions of a average a maintainability index of 69.5, the 5 of exported 70.8, 771 / 11 functions
+	assert.NoError(t, exec.Command(bin, "-badge", path, "./../../testdata/src/a", "./../../testdata/src/exported").Run())
+	assert.Equal(t, map[string]interface{}{"schemaVersion": 1.0, "label": "maintainability", "message": "B (70)", "color": "yellow"}, readBadge())
+	assert.NoError(t, exec.Command(bin, "-badge", path, "-grades", "A:5:60,F", "./../../testdata/src/a", "./../../testdata/src/exported").Run())
+	assert.Equal(t, "green", readBadge()["color"])
+	assert.Error(t, exec.Command(bin, "-badge", filepath.Join(t.TempDir(), "missing", "badge.json"), "./../../testdata/src/a").Run())
+}
+
+func TestImportClasses(t *testing.T) {
+	bin := buildCmd(t)
+	// plugin imports strings, the analyzer of this module and golang.org/x/tools/go/analysis
+	out, _ := exec.Command(bin, "-out-format", "csv", "-csvtotals", "-allfuncs", "./../../plugin").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.True(t, strings.HasSuffix(rows[len(rows)-1], ",1,1,1,0,0,0.000"), rows[len(rows)-1])
+	// the analyzer is external to another module
+	out, _ = exec.Command(bin, "-out-format", "csv", "-csvtotals", "-allfuncs", "-module-path", "example.com/other", "./../../plugin").Output()
+	rows = strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.True(t, strings.HasSuffix(rows[len(rows)-1], ",1,0,2,0,0,0.000"), rows[len(rows)-1])
+
+	cmd := exec.Command(bin, "-extimportsover", "1", "./../../plugin", "./../complexityvet")
+	out, _ = cmd.Output()
+	assert.Equal(t, 0, cmd.ProcessState.ExitCode(), string(out))
+	cmd = exec.Command(bin, "-extimportsover", "1", "-module-path", "example.com/other", "./../../plugin")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "package main seems to depend on too many external packages (external imports=2), over 1")
+}
+
+func TestCoupling(t *testing.T) {
+	bin := buildCmd(t)
+	// top imports mid and base, mid imports base
+	out, _ := exec.Command(bin, "-out-format", "csv", "-csvtotals", "-allfuncs", "./testdata/coupling/...").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, rows, 4)
+	for i, suffix := range []string{"/base,", "/mid,", "/top,"} {
+		assert.Contains(t, rows[1+i], suffix)
+		assert.True(t, strings.HasSuffix(rows[1+i], []string{",2,0,0.000", ",1,1,0.500", ",0,2,1.000"}[i]), rows[1+i])
+	}
+	// only the analyzed packages count
+	out, _ = exec.Command(bin, "-out-format", "csv", "-csvtotals", "-allfuncs", "./testdata/coupling/mid").Output()
+	rows = strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.True(t, strings.HasSuffix(rows[len(rows)-1], ",0,1,0,0,0,0.000"), rows[len(rows)-1])
+
+	out, _ = exec.Command(bin, "-out-format", "json", "./testdata/coupling/...").Output()
+	pkg := jsonPackage{}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-3]), &pkg))
+	assert.Equal(t, []any{1, 1, 0.5}, []any{pkg.Afferent, pkg.Efferent, pkg.Instability}, lines[len(lines)-3])
+}
+
+func TestLOCOver(t *testing.T) {
+	bin := buildCmd(t)
+	assert.NoError(t, exec.Command(bin, "./../../testdata/src/long").Run())
+
+	cmd := exec.Command(bin, "-locover", "33", "./../../testdata/src/long")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "long.go:6: func render seems to be too long (source lines of code=34)")
+
+	// printed and summed into the totals, though under all other thresholds
+	out, _ = exec.Command(bin, "-out-format", "csv", "-columns", "name,cyclo,sloc", "-csvtotals", "-locover", "33", "./../../testdata/src/long").Output()
+	rows := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Equal(t, "render,1,34", rows[1])
+	assert.True(t, strings.HasPrefix(rows[2], "totals,github.com/fikin/go-complexity-analysis/testdata/src/long,1,1,"), rows[2])
+}
+
+func TestJSONOutput(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-out-format", "json", "-cycloover", "5", "./../../testdata/src/a")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	// all functions, then their package
+	assert.Len(t, lines, 7)
+	f := struct {
+		Kind                 string
+		Filename             string
+		Line                 int
+		FunctionName         string
+		CyclomaticComplexity int
+		MaintenabilityIndex  int
+		LOC                  int
+		Violations           []string
+	}{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[2]), &f))
+	assert.Equal(t, "func", f.Kind)
+	assert.True(t, strings.HasSuffix(f.Filename, "/testdata/src/a/a.go"), f.Filename)
+	assert.Equal(t, []any{16, "f2", 8, 57, 20, []string{"cyclo"}}, []any{f.Line, f.FunctionName, f.CyclomaticComplexity, f.MaintenabilityIndex, f.LOC, f.Violations})
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &f))
+	assert.Empty(t, f.Violations)
+	assert.Contains(t, lines[0], `"FanIn":0`)
+
+	pkg := jsonPackage{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[6]), &pkg))
+	assert.Equal(t, jsonPackage{Kind: "pkg", SchemaVersion: 1, Package: "github.com/fikin/go-complexity-analysis/testdata/src/a", Functions: 6, Violations: 1, SLOC: 53, MaintainabilityIndex: 42,
+		Halstead: pkg.Halstead, Imports: complexity.ImportCounts{Stdlib: 1}}, pkg)
+	assert.InDelta(t, 532.502, pkg.Halstead.Volume, 0.001)
+}
+
+func TestJSONColumns(t *testing.T) {
+	fields := reflect.TypeOf(jsonFunc{})
+	for _, c := range allColumns {
+		_, ok := fields.FieldByName(columnFields[c.name])
+		assert.True(t, ok, c.name)
+	}
+
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-out-format", "json", "-columns", "name,cyclo", "-cycloover", "5", "./../../testdata/src/a").Output()
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, `{"Kind":"func","SchemaVersion":1,"FunctionName":"f2","CyclomaticComplexity":8}`, lines[2])
+	// the package lines are not projected
+	assert.Contains(t, lines[6], `"Kind":"pkg"`)
+	assert.Contains(t, lines[6], `"SLOC":53`)
+
+	out, _ = exec.Command(bin, "-out-format", "json", "-legacynames", "-columns", "volume,name", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.True(t, strings.HasPrefix(string(out), `{"Kind":"func","SchemaVersion":1,"HalsbreadVolume":`), string(out))
+}
+
+// validateSchema checks v, decoded json, against the subset of JSON Schema used by the embedded schemas,
+// returning the first mismatch
+func validateSchema(schema map[string]any, v any, at string) error {
+	if alts, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, alt := range alts {
+			if validateSchema(alt.(map[string]any), v, at) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: %d of the oneOf schemas match %v", at, matched, v)
+		}
+		return nil
+	}
+	if c, ok := schema["const"]; ok && c != v {
+		return fmt.Errorf("%s: %v is not %v", at, v, c)
+	}
+	if typ, ok := schema["type"]; ok {
+		types := []any{typ}
+		if list, ok := typ.([]any); ok {
+			types = list
+		}
+		valid := false
+		for _, t := range types {
+			switch t {
+			case "object":
+				_, valid = v.(map[string]any)
+			case "array":
+				_, valid = v.([]any)
+			case "string":
+				_, valid = v.(string)
+			case "boolean":
+				_, valid = v.(bool)
+			case "number":
+				_, valid = v.(float64)
+			case "integer":
+				f, ok := v.(float64)
+				valid = ok && f == float64(int64(f))
+			case "null":
+				valid = v == nil
+			}
+			if valid {
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%s: %v is not of type %v", at, v, typ)
+		}
+	}
+	if obj, ok := v.(map[string]any); ok {
+		props, _ := schema["properties"].(map[string]any)
+		for _, r := range schema["required"].([]any) {
+			if _, ok := obj[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing %s", at, r)
+			}
+		}
+		for k, fv := range obj {
+			prop, ok := props[k].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: unexpected %s", at, k)
+			}
+			if err := validateSchema(prop, fv, at+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	if arr, ok := v.([]any); ok {
+		for i, item := range arr {
+			if err := validateSchema(schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestSchemas(t *testing.T) {
+	bin := buildCmd(t)
+	assert.Equal(t, []string{"baseline", "json"}, schemaNames())
+	loadSchema := func(name string) map[string]any {
+		out, err := exec.Command(bin, "schema", name).Output()
+		assert.NoError(t, err)
+		schema := map[string]any{}
+		assert.NoError(t, json.Unmarshal(out, &schema))
+		return schema
+	}
+
+	jsonSchema := loadSchema("json")
+	assert.Error(t, validateSchema(jsonSchema, map[string]any{"Kind": "func"}, "line"), "no SchemaVersion")
+	assert.Error(t, validateSchema(jsonSchema, map[string]any{"Kind": "func", "SchemaVersion": 1.0, "Line": "3"}, "line"), "string Line")
+	for _, args := range [][]string{
+		{"-stats", "-halstead-raw", "-flag-recursion", "./../../testdata/src/..."},
+		{"-columns", "name,cyclo,recursive", "./../../testdata/src/a"},
+		{"file", "./../../testdata/src/a/a.go"},
+	} {
+		out, _ := exec.Command(bin, append([]string{"-out-format", "json"}, args...)...).Output()
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		assert.Greater(t, len(lines), 1, args)
+		for _, l := range lines {
+			var v any
+			assert.NoError(t, json.Unmarshal([]byte(l), &v))
+			assert.NoError(t, validateSchema(jsonSchema, v, "line"), args)
+		}
+	}
+
+	baselineFile := filepath.Join(t.TempDir(), "baseline.json")
+	_ = exec.Command(bin, "-baseline", baselineFile, "-write-baseline", "./../../testdata/src/...").Run()
+	buf, err := os.ReadFile(baselineFile)
+	assert.NoError(t, err)
+	var v any
+	assert.NoError(t, json.Unmarshal(buf, &v))
+	assert.NoError(t, validateSchema(loadSchema("baseline"), v, "baseline"))
+
+	_, err = exec.Command(bin, "schema", "csv").Output()
+	assert.Error(t, err)
+}
+
+func TestSarifOutput(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-out-format", "sarif", "-cycloover", "3", "-maintunder", "60", "./../../testdata/src/a")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	doc := sarifLog{}
+	assert.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, "2.1.0", doc.Version)
+	assert.Len(t, doc.Runs, 1)
+	run := doc.Runs[0]
+	assert.Equal(t, []sarifInvocation{{ExecutionSuccessful: true}}, run.Invocations)
+	assert.Equal(t, "complexity/cyclomatic", run.Tool.Driver.Rules[0].ID)
+	summary := []string{}
+	for _, r := range run.Results {
+		assert.Equal(t, r.RuleID, run.Tool.Driver.Rules[r.RuleIndex].ID)
+		loc := r.Locations[0].PhysicalLocation
+		assert.True(t, strings.HasSuffix(loc.ArtifactLocation.URI, "/testdata/src/a/a.go"), loc.ArtifactLocation.URI)
+		summary = append(summary, fmt.Sprintf("%d-%d %s %s: %s", loc.Region.StartLine, loc.Region.EndLine, r.RuleID, r.Level, r.Message.Text))
+	}
+	// f2 is over twice the cyclo threshold, and violates both rules
+	assert.Equal(t, []string{
+		"16-35 complexity/cyclomatic error: func f2 is over the cyclo threshold (8 > 3)",
+		"16-35 complexity/maintainability warning: func f2 is under the maint threshold (57 < 60)",
+		"37-43 complexity/cyclomatic warning: func f3 is over the cyclo threshold (4 > 3)",
+		"45-59 complexity/maintainability warning: func f4 is under the maint threshold (59 < 60)",
+	}, summary)
+
+	// the type findings fail the run too
+	cmd = exec.Command(bin, "-out-format", "sarif", "-typestats", "-methodsover", "1", "./../../testdata/src/typestats")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	doc = sarifLog{}
+	assert.NoError(t, json.Unmarshal(out, &doc))
+	assert.NotEmpty(t, doc.Runs[0].Results)
+	for _, r := range doc.Runs[0].Results {
+		assert.Equal(t, "complexity/typestats", r.RuleID)
+		assert.Equal(t, "warning", r.Level)
+		assert.Contains(t, r.Message.Text, "seems to")
+		assert.NotContains(t, r.Message.Text, ".go:")
+	}
+
+	defer func(dir string) { currDir = dir }(currDir)
+	currDir = "/src"
+	assert.Equal(t, "pkg/a.go", sarifLocationOf("/src/pkg/a.go", sarifRegion{})[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "file:///other/a.go", sarifLocationOf("/other/a.go", sarifRegion{})[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestPackageThresholdsConfig(t *testing.T) {
+	for pattern, matches := range map[string][]bool{
+		"example.com/app":             {true, false, false},
+		"example.com/app/...":         {true, true, false},
+		"example.com/.../legacy":      {false, true, false},
+		"example.com/app.../internal": {false, false, false},
+		"example.com/**":              {true, true, true},
+		"example.com/*":               {true, false, true},
+		"*/app/*":                     {false, true, false},
+	} {
+		re, err := packagePatternRe(pattern)
+		assert.NoError(t, err)
+		for i, pkg := range []string{"example.com/app", "example.com/app/legacy", "example.com/application"} {
+			assert.Equal(t, matches[i], re.MatchString(pkg), pattern+" "+pkg)
+		}
+	}
+
+	bin := buildCmd(t)
+	cfg := filepath.Join(t.TempDir(), "complexity.yml")
+	assert.NoError(t, os.WriteFile(cfg, []byte("linters-settings:\n  complexity:\n    packages:\n      - path: github.com/fikin/go-complexity-analysis/testdata/src/...\n        cyclo-over: 5\n      - path: github.com/fikin/go-complexity-analysis/testdata/src/a\n        cyclo-over: 1\n"), 0o600))
+	// the first matching entry applies
+	cmd := exec.Command(bin, "-c", cfg, "-out-format", "txt", "./../../testdata/src/a")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "func f2 seems to be complex (cyclomatic complexity=8)")
+	assert.Equal(t, 1, strings.Count(string(out), "seems to be"), string(out))
+	// the cmdline takes precedence
+	cmd = exec.Command(bin, "-c", cfg, "-cycloover", "10", "-out-format", "txt", "./../../testdata/src/a")
+	out, _ = cmd.Output()
+	assert.Equal(t, 0, cmd.ProcessState.ExitCode(), string(out))
+
+	assert.NoError(t, os.WriteFile(cfg, []byte("linters-settings:\n  complexity:\n    packages:\n      - cyclo-over: 5\n"), 0o600))
+	cmd = exec.Command(bin, "-c", cfg, "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Contains(t, stderr.String(), "packages: an entry has no path")
+
+	// the directory within the module, given on the cmdline
+	cmd = exec.Command(bin, "-pkgthreshold", "testdata/src/** cycloover=5", "-cycloover", "10", "-out-format", "txt", "./../../testdata/src/a")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "func f2 seems to be complex (cyclomatic complexity=8)")
+	for _, val := range []string{"testdata/src/**", "a cyclo=5", "a cycloover=-1"} {
+		cmd = exec.Command(bin, "-pkgthreshold", val, "./../../testdata/src/a")
+		stderr = &bytes.Buffer{}
+		cmd.Stderr = stderr
+		assert.Error(t, cmd.Run())
+		assert.Contains(t, stderr.String(), "invalid value", val)
+	}
+}
+
+func TestListSuppressed(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-list-suppressed", "-cycloover", "3", "./../../testdata/src/suppress")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.NoError(t, cmd.Run())
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	for i, suffix := range []string{
+		"/suppress.go:6: func dispatch is suppressed (one case per opcode), it violates no rule: stale",
+		"/suppress.go:18: func decode is suppressed (generated from the opcode table), it violates no rule: stale",
+		"/versions.go:6: func lookup ignores cyclo,maint (one branch per protocol version), it violates cyclo: stale for maint",
+		"/versions.go:23: func clamp ignores maint (no reason given), it violates no rule: stale",
+	} {
+		assert.True(t, strings.HasSuffix(lines[i], suffix), lines[i])
+	}
+	assert.Len(t, lines, 4)
+
+	stderr.Reset()
+	cmd = exec.Command(bin, "-list-suppressed", "-cycloover", "1", "./../../testdata/src/suppress")
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run(), "encode is not suppressed")
+	assert.Contains(t, stderr.String(), "/suppress.go:6: func dispatch is suppressed (one case per opcode), it violates cyclo\n")
+}
+
+func TestBaseline(t *testing.T) {
+	bin := buildCmd(t)
+	baselineFile := filepath.Join(t.TempDir(), "complexity-baseline.json")
+	cmd := exec.Command(bin, "-baseline", baselineFile, "-write-baseline", "-cycloover", "5", "./../../testdata/src/a")
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+	assert.Contains(t, string(out), "func f2 seems to be complex (cyclomatic complexity=8)")
+	res, err := readBaseline(baselineFile)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Functions)
+
+	// the violations present in the baseline do not fail
+	cmd = exec.Command(bin, "-baseline", baselineFile, "-cycloover", "5", "./../../testdata/src/a")
+	out, err = cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	cmd = exec.Command(bin, "-baseline", baselineFile, "-cycloover", "3", "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	assert.Error(t, cmd.Run())
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Regexp(t, `a\.go:\d+: regressed .*\.f\d: newly over cyclo`, stderr.String())
+	assert.NotContains(t, stderr.String(), ".f2: newly over")
+
+	for _, args := range [][]string{
+		{"-baseline", filepath.Join(t.TempDir(), "missing.json")},
+		{"-write-baseline"},
+	} {
+		cmd = exec.Command(bin, append(args, "./../../testdata/src/a")...)
+		assert.Error(t, cmd.Run(), args)
+	}
+}
+
+func TestNestOver(t *testing.T) {
+	bin := buildCmd(t)
+	assert.NoError(t, exec.Command(bin, "./../../testdata/src/nesting").Run())
+
+	cmd := exec.Command(bin, "-nestover", "3", "./../../testdata/src/nesting")
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "nesting.go:22: func deep seems to be deeply nested (nesting depth=4)")
+
+	out, _ = exec.Command(bin, "-cycloover", "0", "-out-format", "csv", "-columns", "name,nesting", "./../../testdata/src/nesting").Output()
+	assert.Equal(t, "name,nesting\nflat,0\nchain,1\ndeep,4\nclosure,1\n", string(out))
+}
+
+func TestReportModeExitCode(t *testing.T) {
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-report", "all", "./../../testdata/src/a")
+	out, _ := cmd.Output()
+	assert.Equal(t, 0, cmd.ProcessState.ExitCode(), "the metrics of clean functions are no violations")
+	assert.Contains(t, string(out), "Cyclomatic complexity: 1,")
+
+	cmd = exec.Command(bin, "-report", "none", "-localsover", "1", "./../../testdata/src/locals")
+	out, _ = cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode(), "unreported violations still fail")
+	assert.Empty(t, string(out))
+}
+
+func TestDiffRelativeNames(t *testing.T) {
+	bin := buildCmd(t)
+	// f2 spans lines 16-35 of a/a.go, named like by git diff --relative in its directory
+	diff := filepath.Join(t.TempDir(), "pr.diff")
+	assert.NoError(t, os.WriteFile(diff, []byte("--- a/a.go\n+++ b/a.go\n@@ -20,1 +20,1 @@\n-x\n+y\n--- a/gone.go\n+++ b/gone.go\n@@ -1,1 +1,1 @@\n-x\n+y\n"), 0o600))
+	cmd := exec.Command(bin, "-diff", diff, "-cycloover", "5", "./../../testdata/src/a")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, _ := cmd.Output()
+	assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	assert.Contains(t, string(out), "func f2")
+	assert.Contains(t, stderr.String(), "-diff file gone.go matches no analyzed file")
+	assert.NotContains(t, stderr.String(), "-diff file a.go")
+}
+
+func TestExplain(t *testing.T) {
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-path-mode", "module", "-cycloover", "5", "./../../testdata/src/a").Output()
+	assert.NotContains(t, string(out), "\t")
+	out, _ = exec.Command(bin, "-path-mode", "module", "-cycloover", "5", "-explain", "-explain-max", "2", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), "a.go:16: func f2 seems to be complex (cyclomatic complexity=8), grade C\n"+
+		"\ttestdata/src/a/a.go:17:2: for\n"+
+		"\ttestdata/src/a/a.go:18:3: if\n"+
+		"\ttestdata/src/a/a.go:16:1: and 5 more decision points\n\n")
+	out, _ = exec.Command(bin, "-cycloover", "5", "-explain", "file", "../../testdata/src/a/a.go").Output()
+	assert.Equal(t, 7, strings.Count(string(out), "\t../../testdata/src/a/a.go:"), string(out))
+}
+
+func TestViolationTotals(t *testing.T) {
+	tot := violationTotals{ByRule: map[string]int{}}
+	tot.add(complexity.FuncStatsType{FunctionName: "both", IsTooComplex: true, IsNotMaintenable: true})
+	tot.add(complexity.FuncStatsType{FunctionName: "cyclo", IsTooComplex: true})
+	tot.add(complexity.FuncStatsType{FunctionName: "none"})
+	assert.Equal(t, violationTotals{Functions: 2, Violations: 3, ByRule: map[string]int{"cyclo": 2, "maint": 1}}, tot)
+
+	buf := &bytes.Buffer{}
+	doPrintSummary(buf, tot)
+	assert.Equal(t, "3 violations in 2 functions: cyclo=2, maint=1\n", buf.String())
+
+	tot.SLOC = 1500
+	buf.Reset()
+	doPrintSummary(buf, tot)
+	assert.Equal(t, "3 violations in 2 functions: cyclo=2, maint=1; 2.00 violations per KLOC in 1500 source lines\n", buf.String())
+
+	// a function violating both thresholds is still printed once
+	bin := buildCmd(t)
+	cmd := exec.Command(bin, "-summary", "-cycloover", "5", "-maintunder", "100", "./../../testdata/src/a")
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	assert.Error(t, cmd.Run())
+	assert.Equal(t, 6, strings.Count(stdout.String(), " seems to "), stdout.String())
+	assert.Contains(t, stderr.String(), "7 violations in 6 functions: cyclo=1, maint=6")
+}
+
+func TestRunStats(t *testing.T) {
+	s := newRunStats()
+	s.mark("load")
+	s.mark("analyze")
+	s.functions = 10
+	assert.Len(t, s.phases, 2)
+	assert.Greater(t, s.peakHeap, uint64(0))
+
+	buf := &bytes.Buffer{}
+	doPrintStats(buf, s)
+	assert.Regexp(t, `^wall time \S+ \(load=\S+, analyze=\S+\), peak heap \d+\.\d MiB, 10 functions analyzed, \d+\.\d functions/s\n$`, buf.String())
+
+	bin := buildCmd(t)
+	out, _ := exec.Command(bin, "-stats", "./../../testdata/src/a").CombinedOutput()
+	assert.Regexp(t, `wall time \S+ \(load=\S+, analyze=\S+, report=\S+\), peak heap .* MiB, 6 functions analyzed`, string(out))
+
+	buf.Reset()
+	assert.NoError(t, doPrintStatsMetrics(buf, s))
+	assert.Contains(t, buf.String(), "# TYPE analyzer_wall_seconds gauge\n")
+	assert.Regexp(t, `\nanalyzer_phase_seconds\{phase="load"\} [0-9.e-]+\nanalyzer_phase_seconds\{phase="analyze"\} `, buf.String())
+	assert.Contains(t, buf.String(), "\nanalyzer_functions_analyzed 10\n")
+
+	out, _ = exec.Command(bin, "-stats", "-out-format", "metrics", "./../../testdata/src/a").Output()
+	assert.Contains(t, string(out), "code_cyclomatic_complexity{")
+	assert.Contains(t, string(out), "analyzer_phase_seconds{phase=\"report\"} ")
+	assert.Contains(t, string(out), "\nanalyzer_functions_analyzed 6\n")
+
+	out, _ = exec.Command(bin, "-stats", "-out-format", "json", "./../../testdata/src/a").Output()
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	stats := jsonStats{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &stats))
+	assert.Equal(t, "stats", stats.Kind)
+	assert.Equal(t, 6, stats.Functions)
+	assert.Len(t, stats.Phases, 3)
+	assert.Greater(t, stats.PeakHeapBytes, uint64(0))
+}
+
+func TestParseErrors(t *testing.T) {
+	bin := buildCmd(t)
+	for _, args := range [][]string{
+		{"./testdata/parseerr"},
+		{fileCmd, "testdata/parseerr/broken.go", "testdata/parseerr/ok.go", "testdata/parseerr/other.go"},
+	} {
+		cmd := exec.Command(bin, append([]string{"-summary", "-cycloover", "1"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err, string(out))
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+		assert.Contains(t, string(out), "broken.go:4: file does not parse, its functions are not analyzed: ")
+		assert.Contains(t, string(out), "func valid seems to be complex")
+		assert.NotContains(t, string(out), "func lost")
+		assert.Contains(t, string(out), "1 violations in 1 functions: cyclo=1; ")
+		assert.Contains(t, string(out), "; 1 files failed to parse")
+
+		// parse errors alone do not fail the run, unless asked to
+		cmd = exec.Command(bin, args...)
+		out, err = cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+		assert.Contains(t, string(out), "broken.go:4: file does not parse")
+
+		cmd = exec.Command(bin, append([]string{"-failonparseerror"}, args...)...)
+		assert.Error(t, cmd.Run())
+		assert.Equal(t, 1, cmd.ProcessState.ExitCode())
+	}
+
+	cmd := exec.Command(bin, "-out-format", "checkstyle", "./testdata/parseerr")
+	out, err := cmd.Output()
+	assert.NoError(t, err, string(out))
+	assert.Contains(t, string(out), `line="4"`)
+	assert.Contains(t, string(out), `source="parse-error"`)
+}
+
+// failingWriter fails all writes
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestStrict(t *testing.T) {
+	bin := buildCmd(t)
+	assert.NoError(t, exec.Command(bin, "-strict", "./../../testdata/src/a").Run())
+
+	for _, tc := range []struct {
+		args    []string
+		missing string
+	}{
+		{[]string{"./testdata/parseerr"}, "parseerr/broken.go: the file does not parse"},
+		{[]string{fileCmd, "testdata/parseerr/ok.go"}, "fan-in, recursive, api reach and hotspots of all functions: no type information in file mode"},
+		{[]string{"-timebudget", "1ns", "./../../testdata/src/..."}, "the analysis was stopped"},
+	} {
+		// lenient by default
+		cmd := exec.Command(bin, tc.args...)
+		out, _ := cmd.CombinedOutput()
+		assert.NotEqual(t, exitStrict, cmd.ProcessState.ExitCode(), string(out))
+		assert.NotContains(t, string(out), "strict:")
+
+		cmd = exec.Command(bin, append([]string{"-strict"}, tc.args...)...)
+		out, _ = cmd.CombinedOutput()
+		assert.Equal(t, exitStrict, cmd.ProcessState.ExitCode(), string(out))
+		assert.Contains(t, string(out), "complexity: strict: not computed: ")
+		assert.Contains(t, string(out), tc.missing)
+	}
+}
+
+func TestUnicodeOutputs(t *testing.T) {
+	defer func(old []column) { selectedColumns = old }(selectedColumns)
+	cols, err := parseColumns("filename,name,source")
+	assert.NoError(t, err)
+	selectedColumns = cols
+	stats := []complexity.FuncStatsType{{
+		Filename:     "mathé/σ, \"x\".go",
+		FunctionName: "ΣΔα",
+		GenSource:    `gen -names=a,b -q="x"`,
+		IsTooComplex: true,
+	}}
+
+	buf := &bytes.Buffer{}
+	defer func() { csvHeaderPrinted = false }()
+	assert.NoError(t, doPrintFuncStats(buf, stats))
+	assert.Equal(t, "filename,name,source\n\"mathé/σ, \"\"x\"\".go\",ΣΔα,\"gen -names=a,b -q=\"\"x\"\"\"\n", buf.String())
+	rows, err := csv.NewReader(buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"filename", "name", "source"}, {"mathé/σ, \"x\".go", "ΣΔα", `gen -names=a,b -q="x"`}}, rows)
+
+	// the header is printed once per run
+	assert.NoError(t, doPrintFuncStats(buf, stats))
+	assert.Equal(t, "\"mathé/σ, \"\"x\"\".go\",ΣΔα,\"gen -names=a,b -q=\"\"x\"\"\"\n", buf.String())
+	assert.Error(t, doPrintFuncStats(failingWriter{}, stats))
+
+	buf.Reset()
+	doPrintGob(buf, newGobResults(stats, false))
+	res, err := readGob(buf)
+	assert.NoError(t, err)
+	js, err := json.Marshal(res)
+	assert.NoError(t, err)
+	assert.Contains(t, string(js), `"FunctionName":"ΣΔα"`)
+
+	doc := checkstyleTag{Version: "5.0", Files: []checkstyleFileTag{{
+		FileName: "mathé/<σ>&.go",
+		Errors:   []checkstyleErrorTag{{Line: 1, Msg: complexity.ToDiagnosticMsg(stats[0])}},
+	}}}
+	out, err := xml.Marshal(doc)
+	assert.NoError(t, err)
+	var parsed struct {
+		Files []struct {
+			Name   string `xml:"name,attr"`
+			Errors []struct {
+				Msg string `xml:"message,attr"`
+			} `xml:"error"`
+		} `xml:"file"`
+	}
+	assert.NoError(t, xml.Unmarshal(out, &parsed), string(out))
+	assert.Equal(t, "mathé/<σ>&.go", parsed.Files[0].Name)
+	assert.Equal(t, "func ΣΔα seems to be complex (cyclomatic complexity=0)", parsed.Files[0].Errors[0].Msg)
 }
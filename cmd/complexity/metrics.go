@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// metricsFormat is the -out-format printing the Prometheus text exposition format, like for a Pushgateway
+const metricsFormat = "metrics"
+
+// flag option only in standalone cmdline mode
+// to export only the functions with the Cyclomatic complexity >= N, limiting the number of series
+var metricsMinCyclo int
+
+// gathered functions and package aggregates, printed when the output format is metrics
+var (
+	metricsFuncs = []complexity.FuncStatsType{}
+	metricsPkgs  = []packageMetrics{}
+)
+
+// packageMetrics are the package level aggregates exported as gauges
+type packageMetrics struct {
+	Path       string
+	Functions  int
+	SLOC       int
+	Violations int
+	MaxCyclo   int
+	Maint      int
+}
+
+func newPackageMetrics(pkgPath string, res *complexity.Result) packageMetrics {
+	m := packageMetrics{Path: pkgPath, Functions: len(res.Functions), SLOC: res.SLOC, Violations: res.Violations, Maint: res.MaintainabilityIndex}
+	for _, f := range res.Functions {
+		if f.CyclomaticComplexity > m.MaxCyclo {
+			m.MaxCyclo = f.CyclomaticComplexity
+		}
+	}
+	return m
+}
+
+// metricFamily is a gauge with its samples, printed after its HELP and TYPE lines
+type metricFamily struct {
+	name, help string
+	samples    []metricSample
+}
+
+type metricSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// escapeLabelValue escapes backslash, double-quote and line feed, as the exposition format requires
+var escapeLabelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
+
+func (f metricFamily) write(w io.Writer) {
+	if len(f.samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", f.name)
+	for _, s := range f.samples {
+		labels := make([]string, len(s.labels))
+		for i, l := range s.labels {
+			labels[i] = fmt.Sprintf("%s=\"%s\"", l[0], escapeLabelValue(l[1]))
+		}
+		value := strconv.FormatFloat(s.value, 'f', -1, 64)
+		if len(labels) == 0 {
+			fmt.Fprintf(w, "%s %s\n", f.name, value)
+			continue
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", f.name, strings.Join(labels, ","), value)
+	}
+}
+
+// doPrintMetrics prints the functions with the Cyclomatic complexity >= minCyclo and the packages
+// as a single exposition document, each metric family once with all its samples.
+// Repeated function names of a package, like init functions, are numbered to keep the series unique.
+func doPrintMetrics(w io.Writer, funcs []complexity.FuncStatsType, pkgs []packageMetrics, minCyclo int) error {
+	cyclo := metricFamily{name: "code_cyclomatic_complexity", help: "Cyclomatic complexity of the function."}
+	maint := metricFamily{name: "code_maintainability_index", help: "Maintainability index of the function."}
+	loc := metricFamily{name: "code_function_loc", help: "Lines of code of the function."}
+	seen := map[[2]string]int{}
+	for _, s := range funcs {
+		if s.CyclomaticComplexity < minCyclo {
+			continue
+		}
+		name := s.FunctionName
+		seen[[2]string{s.Package, name}]++
+		if n := seen[[2]string{s.Package, name}]; n > 1 {
+			name = fmt.Sprintf("%s#%d", name, n)
+		}
+		labels := [][2]string{{"package", s.Package}, {"function", name}}
+		cyclo.samples = append(cyclo.samples, metricSample{labels, float64(s.CyclomaticComplexity)})
+		maint.samples = append(maint.samples, metricSample{labels, float64(s.MaintenabilityIndex)})
+		loc.samples = append(loc.samples, metricSample{labels, float64(s.LOC)})
+	}
+	pkgFuncs := metricFamily{name: "code_package_functions", help: "Number of functions of the package."}
+	pkgSLOC := metricFamily{name: "code_package_sloc", help: "Source lines of code of the package."}
+	pkgViolations := metricFamily{name: "code_package_violations", help: "Number of violations of the functions of the package."}
+	pkgMaxCyclo := metricFamily{name: "code_package_max_cyclomatic_complexity", help: "Highest Cyclomatic complexity of the functions of the package."}
+	pkgMaint := metricFamily{name: "code_package_maintainability_index", help: "Maintainability index of the package taken as a whole."}
+	for _, p := range pkgs {
+		labels := [][2]string{{"package", p.Path}}
+		pkgFuncs.samples = append(pkgFuncs.samples, metricSample{labels, float64(p.Functions)})
+		pkgSLOC.samples = append(pkgSLOC.samples, metricSample{labels, float64(p.SLOC)})
+		pkgViolations.samples = append(pkgViolations.samples, metricSample{labels, float64(p.Violations)})
+		pkgMaxCyclo.samples = append(pkgMaxCyclo.samples, metricSample{labels, float64(p.MaxCyclo)})
+		pkgMaint.samples = append(pkgMaint.samples, metricSample{labels, float64(p.Maint)})
+	}
+	bw := bufio.NewWriter(w)
+	for _, f := range []metricFamily{cyclo, maint, loc, pkgFuncs, pkgSLOC, pkgViolations, pkgMaxCyclo, pkgMaint} {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// doPrintStatsMetrics prints the run statistics as analyzer_* gauges, following the metrics document
+func doPrintStatsMetrics(w io.Writer, s *runStats) error {
+	phases := metricFamily{name: "analyzer_phase_seconds", help: "Wall time of the run phase."}
+	for _, p := range s.phases {
+		phases.samples = append(phases.samples, metricSample{[][2]string{{"phase", p.name}}, p.duration.Seconds()})
+	}
+	bw := bufio.NewWriter(w)
+	for _, f := range []metricFamily{
+		{name: "analyzer_wall_seconds", help: "Wall time of the run.", samples: []metricSample{{nil, s.wall().Seconds()}}},
+		phases,
+		{name: "analyzer_peak_heap_bytes", help: "Peak heap, sampled at the end of each phase.", samples: []metricSample{{nil, float64(s.peakHeap)}}},
+		{name: "analyzer_functions_analyzed", help: "Number of functions analyzed.", samples: []metricSample{{nil, float64(s.functions)}}},
+		{name: "analyzer_functions_per_second", help: "Functions analyzed per second of wall time.", samples: []metricSample{{nil, s.perSecond()}}},
+	} {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// parseErrorRule is the rule id (diagnostic category) of the findings for files failing to parse
+const parseErrorRule = "parse-error"
+
+// flag option only in standalone cmdline mode
+// to fail the run on files failing to parse, which are otherwise reported without affecting the exit code
+var failOnParseError bool
+
+// parseErrorFinding is the finding of a file failing to parse, at its first error
+func parseErrorFinding(fset *token.FileSet, pos token.Pos, msg string) analysis.Diagnostic {
+	p := fset.Position(pos)
+	return analysis.Diagnostic{
+		Pos:      pos,
+		Category: parseErrorRule,
+		Message:  fmt.Sprintf("%s:%d: file does not parse, its functions are not analyzed: %s\n", p.Filename, p.Line, msg),
+	}
+}
+
+// takeParseErrors reports the files of the package failing to parse, one finding per file,
+// and leaves them out of the package syntax, so only valid files are analyzed
+func takeParseErrors(pkg *packages.Package) []analysis.Diagnostic {
+	diags := []analysis.Diagnostic{}
+	failed := map[string]bool{}
+	for _, e := range pkg.Errors {
+		if e.Kind != packages.ParseError {
+			continue
+		}
+		filename, line, col := splitErrorPos(e.Pos)
+		if failed[filename] {
+			continue
+		}
+		failed[filename] = true
+		diags = append(diags, parseErrorFinding(pkg.Fset, filePos(pkg.Fset, filename, line, col), e.Msg))
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	files := []*ast.File{}
+	for _, f := range pkg.Syntax {
+		if !failed[pkg.Fset.File(f.Pos()).Name()] {
+			files = append(files, f)
+		}
+	}
+	pkg.Syntax = files
+	return diags
+}
+
+// splitErrorPos splits packages.Error position "file:line:col" into its parts
+func splitErrorPos(pos string) (filename string, line, col int) {
+	parts := strings.Split(pos, ":")
+	if len(parts) < 3 {
+		return pos, 0, 0
+	}
+	line, _ = strconv.Atoi(parts[len(parts)-2])
+	col, _ = strconv.Atoi(parts[len(parts)-1])
+	return strings.Join(parts[:len(parts)-2], ":"), line, col
+}
+
+// filePos finds the position in the file set, token.NoPos if the file is not in it
+func filePos(fset *token.FileSet, filename string, line, col int) (pos token.Pos) {
+	fset.Iterate(func(f *token.File) bool {
+		if f.Name() != filename {
+			return true
+		}
+		if line > 0 && line <= f.LineCount() {
+			pos = f.LineStart(line) + token.Pos(max(col-1, 0))
+		} else {
+			pos = token.Pos(f.Base())
+		}
+		return false
+	})
+	return
+}
+
+// countsAsViolation tells if the finding affects the exit code, the informational ones do not
+func countsAsViolation(d analysis.Diagnostic) bool {
+	switch d.Category {
+	case parseErrorRule:
+		return failOnParseError
+	case complexity.DeferLoopCategory:
+		return false
+	}
+	return true
+}
+
+// routeParseErrors counts the files failing to parse in the totals and, as only txt output prints
+// the diagnostics, adds them to the checkstyle output or logs them for the other formats
+func routeParseErrors(arr []foundDiagnosticsStruct) {
+	for _, f := range arr {
+		for _, d := range f.diagnostics {
+			if d.Category != parseErrorRule {
+				continue
+			}
+			totals.FilesFailed++
+			switch outputFormat {
+			case "checkstyle":
+				p := f.pkg.Fset.Position(d.Pos)
+				msg := strings.TrimSpace(strings.TrimPrefix(d.Message, fmt.Sprintf("%s:%d: ", p.Filename, p.Line)))
+				i, ok := checkstyles.filesAsMap[p.Filename]
+				if !ok {
+					i = checkstyleFileTag{FileName: printedPath(p.Filename, currDir), Errors: []checkstyleErrorTag{}}
+				}
+				i.Errors = append(i.Errors, checkstyleErrorTag{Line: p.Line, Col: p.Column, Msg: msg, Severity: "error", Source: parseErrorRule})
+				checkstyles.filesAsMap[p.Filename] = i
+			case sarifFormat:
+				p := f.pkg.Fset.Position(d.Pos)
+				msg := strings.TrimSpace(strings.TrimPrefix(d.Message, fmt.Sprintf("%s:%d: ", p.Filename, p.Line)))
+				sarifResults = append(sarifResults, sarifParseError(p.Filename, p.Line, p.Column, msg))
+			case "csv", "gob", jsonFormat:
+				log.Print(strings.TrimSpace(printedMessage(diagnosticFilename(f.pkg, d), d.Message, "")))
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// path modes of the printed file names
+const (
+	pathModeAbs    = "abs"
+	pathModeRel    = "rel"
+	pathModeModule = "module"
+)
+
+// flag option only in standalone cmdline mode
+// one of : abs, rel, module, or empty for the default of each output format
+var pathMode string
+
+// pathRoot is the directory the file names are printed relative to with -path-mode, "" for absolute names
+var pathRoot string
+
+// configurePaths resolves the root of the printed file names of the -path-mode
+func configurePaths() error {
+	switch pathMode {
+	case "", pathModeAbs:
+		pathRoot = ""
+	case pathModeRel:
+		pathRoot = currDir
+	case pathModeModule:
+		pathRoot = findModuleRoot(currDir)
+		if pathRoot == "" {
+			pathRoot = currDir
+		}
+	default:
+		return fmt.Errorf("unknown path mode %q, valid are: %s, %s, %s", pathMode, pathModeAbs, pathModeRel, pathModeModule)
+	}
+	return nil
+}
+
+// findModuleRoot returns the nearest directory with a go.mod file from dir upwards, "" if there is none
+func findModuleRoot(dir string) string {
+	for {
+		if st, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !st.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// printedPath returns the file name relative to the -path-mode root, absolute if it is outside of it.
+// Without -path-mode, the names are relative to the defaultRoot of the output format, absolute if "".
+func printedPath(filename, defaultRoot string) string {
+	root := defaultRoot
+	if pathMode != "" {
+		root = pathRoot
+	}
+	if root == "" {
+		return filename
+	}
+	return getRelativeFileName(filename, root)
+}
+
+// printedMessage rewrites the file name the message starts with, like printedPath
+func printedMessage(filename, msg, defaultRoot string) string {
+	if filename != "" && strings.HasPrefix(msg, filename+":") {
+		return printedPath(filename, defaultRoot) + msg[len(filename):]
+	}
+	return msg
+}
+
+// printedPaths returns the functions with their file names as printed in gob output,
+// unchanged without -path-mode
+func printedPaths(arr []complexity.FuncStatsType) []complexity.FuncStatsType {
+	if pathMode == "" {
+		return arr
+	}
+	res := make([]complexity.FuncStatsType, len(arr))
+	for i, s := range arr {
+		s.Filename = printedPath(s.Filename, "")
+		res[i] = s
+	}
+	return res
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// pkgSummaryFormat is the -out-format printing a line per package and an overall line, instead of the functions,
+// unlike -summary, which tallies the violations per rule to stderr
+const pkgSummaryFormat = "pkgsummary"
+
+// csv and streaming flags contradicting the summary output
+var summaryConflicts = []string{"columns", "csv-no-header", "csvtotals", "csvfiles", "csvtypes", "csvmethods-by-type", "totals-mode", "stream"}
+
+// packageSummary is the functions of a package, or of a file in file mode
+type packageSummary struct {
+	// Kind is pkg, or file in file mode
+	Kind  string
+	Name  string
+	Funcs []complexity.FuncStatsType
+}
+
+// gathered package summaries, printed when the output format is summary
+var pkgSummaries = []packageSummary{}
+
+// functions of the package being analyzed, moved to its summary once it is done.
+// In file mode, there are no packages, so they are left for printing per file.
+var pendingSummaryFuncs = []complexity.FuncStatsType{}
+
+// csvFlags are the flags shaping the csv output, by the output formats they apply to
+var csvFlags = map[string][]string{
+	"columns":                   {"csv", jsonFormat},
+	"csv-no-header":             {"csv"},
+	"csvtotals":                 {"csv"},
+	"csvfiles":                  {"csv", "txt"},
+	"csvtypes":                  {"csv"},
+	"csvmethods-by-type":        {"csv"},
+	"totals-mode":               {"csv"},
+	"include-unexported-in-csv": {"csv"},
+	"sort":                      {"csv"},
+}
+
+// checkOutputFlags rejects output flags contradicting the output format, instead of silently ignoring them.
+// The decode, compare and schema subcommands have outputs of their own, which the flags are checked against there.
+func checkOutputFlags(args []string) error {
+	if len(args) > 0 && (args[0] == decodeCmd || args[0] == compareCmd || args[0] == schemaCmd) {
+		return nil
+	}
+	explicit := explicitFlags()
+	if outputFormat == pkgSummaryFormat {
+		for _, name := range summaryConflicts {
+			if explicit[name] {
+				return fmt.Errorf("-%s has no effect with -out-format %s, which prints no functions", name, pkgSummaryFormat)
+			}
+		}
+	}
+	names := []string{}
+	for name := range csvFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if explicit[name] && !slices.Contains(csvFlags[name], outputFormat) {
+			return fmt.Errorf("-%s has no effect with -out-format %s, it applies to %s output only", name, outputFormat, strings.Join(csvFlags[name], " and "))
+		}
+	}
+	return nil
+}
+
+// summaryCounts are the counted functions of a summary line
+type summaryCounts struct {
+	Functions  int
+	TooComplex int
+	NotMaint   int
+	// Other are the functions violating other rules only
+	Other int
+	Worst *complexity.FuncStatsType
+}
+
+// add counts the function, unless it is outside of the -diff or unexported with -exported-only
+func (c *summaryCounts) add(s complexity.FuncStatsType) {
+	if s.Unchanged || s.Unexported {
+		return
+	}
+	c.Functions++
+	rules := map[string]bool{}
+	for _, r := range complexity.Violations(s) {
+		rules[r] = true
+	}
+	if rules["cyclo"] {
+		c.TooComplex++
+	}
+	if rules["maint"] {
+		c.NotMaint++
+	}
+	if len(rules) > 0 && !rules["cyclo"] && !rules["maint"] {
+		c.Other++
+	}
+	if c.Worst == nil || s.CyclomaticComplexity > c.Worst.CyclomaticComplexity {
+		worst := s
+		c.Worst = &worst
+	}
+}
+
+func (c summaryCounts) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d/%d functions over cyclo threshold, %d under maintainability", c.TooComplex, c.Functions, c.NotMaint)
+	if c.Other > 0 {
+		fmt.Fprintf(b, ", %d over other thresholds", c.Other)
+	}
+	if c.Worst != nil {
+		fmt.Fprintf(b, ", worst: %s cyclo=%d", c.Worst.FunctionName, c.Worst.CyclomaticComplexity)
+	}
+	return b.String()
+}
+
+// summariesOf returns the package summaries, followed by one per file of the functions left outside of packages
+func summariesOf(pkgs []packageSummary, pending []complexity.FuncStatsType) []packageSummary {
+	files := map[string][]complexity.FuncStatsType{}
+	for _, s := range pending {
+		files[s.Filename] = append(files[s.Filename], s)
+	}
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := append([]packageSummary{}, pkgs...)
+	for _, name := range names {
+		res = append(res, packageSummary{Kind: "file", Name: printedPath(name, currDir), Funcs: files[name]})
+	}
+	return res
+}
+
+// doPrintPackageSummaries prints a line per package, including the clean ones, and the total line of the whole run
+func doPrintPackageSummaries(w io.Writer, pkgs []packageSummary) {
+	all := summaryCounts{}
+	for _, p := range pkgs {
+		c := summaryCounts{}
+		for _, s := range p.Funcs {
+			c.add(s)
+			all.add(s)
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", p.Kind, p.Name, c)
+	}
+	fmt.Fprintf(w, "total: %s\n", all)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// packageOverride is an entry of the packages list of the configuration file, or of -pkgthreshold,
+// overriding the thresholds of the packages matching its path pattern
+type packageOverride struct {
+	Path       string `yaml:"path" json:"path"`
+	CycloOver  *int   `yaml:"cyclo-over,omitempty" json:"cyclo-over,omitempty"`
+	MaintUnder *int   `yaml:"maint-under,omitempty" json:"maint-under,omitempty"`
+	// cmdline entries apply even when the threshold flag is given explicitly
+	cmdline bool
+}
+
+// flag option only in standalone cmdline mode
+// to override the thresholds of the matching packages, before the entries of the configuration file
+var cmdlinePkgOverrides = []packageOverride{}
+
+// parsePackageOverride parses a -pkgthreshold value, like "internal/legacy/** cycloover=25,maintunder=10"
+func parsePackageOverride(val string) error {
+	fields := strings.Fields(val)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected a path pattern and thresholds, like 'internal/legacy/** cycloover=25', got %q", val)
+	}
+	o := packageOverride{Path: fields[0], cmdline: true}
+	for _, kv := range strings.Split(strings.Join(fields[1:], ","), ",") {
+		if kv == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(kv, "=")
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid value %q of %s, expected a non-negative integer", value, key)
+		}
+		switch key {
+		case "cycloover":
+			o.CycloOver = &v
+		case "maintunder":
+			o.MaintUnder = &v
+		default:
+			return fmt.Errorf("unknown threshold %q, valid are: cycloover, maintunder", key)
+		}
+	}
+	cmdlinePkgOverrides = append(cmdlinePkgOverrides, o)
+	return nil
+}
+
+// packagePatternRe converts the path pattern to a regular expression: ** and ... match any string,
+// * any string within a path element, and a trailing /... or /** also matches the path before it,
+// so net/... matches net like in go package patterns
+func packagePatternRe(pattern string) (*regexp.Regexp, error) {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.ReplaceAll(re, `\*\*`, `\.\.\.`)
+	re = strings.ReplaceAll(re, `\*`, `[^/]*`)
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+	return regexp.Compile("^" + re + "$")
+}
+
+// relativeToModule returns the package path within its module, like internal/legacy, "" for its root package
+func relativeToModule(pkgPath string) string {
+	module := complexity.ModuleOf(pkgPath)
+	if module == "" || pkgPath == module {
+		return ""
+	}
+	return strings.TrimPrefix(pkgPath, module+"/")
+}
+
+// configurePackageThresholds sets the thresholds of the packages by the first entry matching their import path,
+// or their directory within the module. The thresholds given on the cmdline take precedence over the entries
+// of the configuration file.
+func configurePackageThresholds(overrides []packageOverride, explicit map[string]bool) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	type entry struct {
+		re         *regexp.Regexp
+		thresholds map[string]int
+	}
+	entries := []entry{}
+	for _, o := range overrides {
+		if o.Path == "" {
+			return fmt.Errorf("packages: an entry has no path")
+		}
+		re, err := packagePatternRe(o.Path)
+		if err != nil {
+			return fmt.Errorf("packages: %s: %v", o.Path, err)
+		}
+		thresholds := map[string]int{}
+		for _, t := range []struct {
+			key, flag string
+			value     *int
+		}{
+			{"max-cyclo", "cycloover", o.CycloOver},
+			{"min-maint", "maintunder", o.MaintUnder},
+		} {
+			if t.value == nil || explicit[t.flag] && !o.cmdline {
+				continue
+			}
+			if *t.value < 0 {
+				return fmt.Errorf("packages: %s: negative %s threshold %d", o.Path, t.flag, *t.value)
+			}
+			thresholds[t.key] = *t.value
+		}
+		entries = append(entries, entry{re: re, thresholds: thresholds})
+	}
+	complexity.PackageThresholds = func(pkgPath string) map[string]int {
+		rel := relativeToModule(pkgPath)
+		for _, e := range entries {
+			if e.re.MatchString(pkgPath) || rel != "" && e.re.MatchString(rel) {
+				return e.thresholds
+			}
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// flag option only in standalone cmdline mode
+// to print the timing and memory statistics of the run at its end (to stderr)
+var printStats bool
+
+type phaseStat struct {
+	name     string
+	duration time.Duration
+}
+
+// runStats tracks the duration of the run phases and the peak heap, sampled at the end of each phase
+type runStats struct {
+	start     time.Time
+	last      time.Time
+	phases    []phaseStat
+	peakHeap  uint64
+	functions int
+	// partial runs were stopped before analyzing the skipped packages
+	partial bool
+	skipped []string
+}
+
+// gathered run statistics, printed when printStats is set
+var timings = newRunStats()
+
+func newRunStats() *runStats {
+	now := time.Now()
+	return &runStats{start: now, last: now}
+}
+
+// mark ends the current phase, naming it
+func (s *runStats) mark(name string) {
+	now := time.Now()
+	s.phases = append(s.phases, phaseStat{name: name, duration: now.Sub(s.last)})
+	s.last = now
+	m := runtime.MemStats{}
+	runtime.ReadMemStats(&m)
+	s.peakHeap = max(s.peakHeap, m.HeapAlloc)
+}
+
+// wall is the duration from the start to the end of the last phase
+func (s *runStats) wall() time.Duration {
+	return s.last.Sub(s.start)
+}
+
+// perSecond is the number of functions analyzed per second of wall time
+func (s *runStats) perSecond() float64 {
+	if wall := s.wall(); wall > 0 {
+		return float64(s.functions) / wall.Seconds()
+	}
+	return 0
+}
+
+// printRunStats prints the statistics to stderr, and into the machine-readable outputs:
+// as analyzer_* gauges following the metrics document and as the last json line
+func printRunStats(s *runStats) {
+	doPrintStats(os.Stderr, s)
+	switch outputFormat {
+	case metricsFormat:
+		if err := doPrintStatsMetrics(os.Stdout, s); err != nil && outputErr == nil {
+			log.Printf("writing metrics output: %v", err)
+			outputErr = err
+		}
+	case jsonFormat:
+		writeJSONLine(jsonOut, newJSONStats(s))
+	}
+}
+
+func doPrintStats(w io.Writer, s *runStats) {
+	phases := []string{}
+	for _, p := range s.phases {
+		phases = append(phases, fmt.Sprintf("%s=%s", p.name, p.duration.Round(time.Millisecond)))
+	}
+	fmt.Fprintf(w, "wall time %s (%s), peak heap %0.1f MiB, %d functions analyzed, %0.1f functions/s\n",
+		s.wall().Round(time.Millisecond), strings.Join(phases, ", "), float64(s.peakHeap)/(1<<20), s.functions, s.perSecond())
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// sarifFormat is the -out-format printing a SARIF 2.1.0 log, for code scanning integrations
+const sarifFormat = "sarif"
+
+// sarifRule is a rule of the violations, as SARIF rule id under the complexity/ prefix
+type sarifRule struct {
+	rule, id, description string
+}
+
+// sarifRules are all rules, in the order of complexity.Violations, the categories of the other findings
+// and the parse errors
+var sarifRules = []sarifRule{
+	{"cyclo", "complexity/cyclomatic", "Cyclomatic complexity over -cycloover"},
+	{"maint", "complexity/maintainability", "Maintainability index under -maintunder"},
+	{"cognitive", "complexity/cognitive", "Cognitive complexity over -cognitiveover"},
+	{"params", "complexity/params", "Parameters over -paramsover"},
+	{"results", "complexity/results", "Results over -resultsover"},
+	{"returns", "complexity/returns", "Return statements over -returnsover"},
+	{"statements", "complexity/statements", "Statements over -stmtsover"},
+	{"loc", "complexity/loc", "Source lines of code over -locover"},
+	{"effort", "complexity/effort", "Halstead effort over -effortover"},
+	{"abc", "complexity/abc", "ABC size over -abcover"},
+	{"fanout", "complexity/fanout", "Called functions over -fanoutover"},
+	{"locals", "complexity/locals", "Local variables over -localsover"},
+	{"concurrency", "complexity/concurrency", "Concurrency constructs over -concover"},
+	{"defers", "complexity/defers", "Defer statements over -defersover"},
+	{"nesting", "complexity/nesting", "Nesting depth over -nestover"},
+	{"recursion", "complexity/recursion", "Recursive function, with -flag-recursion"},
+	{"score", "complexity/score", "Risk score over -scoreover"},
+	{complexity.TypeStatsCategory, "complexity/" + complexity.TypeStatsCategory, "Type over -methodsover, -fieldsover or -typecycloover, with -typestats"},
+	{complexity.ImportsCategory, "complexity/" + complexity.ImportsCategory, "Package external imports over -extimportsover"},
+	{complexity.PkgMaintCategory, "complexity/" + complexity.PkgMaintCategory, "Package maintainability index under -pkgmaintunder"},
+	{complexity.DensityCategory, "complexity/" + complexity.DensityCategory, "Package violations per KLOC over -violationsperkloc"},
+	{complexity.HotspotCategory, "complexity/" + complexity.HotspotCategory, "Critical hotspot, with -hotspots"},
+	{complexity.DeferLoopCategory, "complexity/" + complexity.DeferLoopCategory, "Defer statement in a loop, with -warn-defer-in-loop"},
+	{complexity.DirectiveCategory, "complexity/" + complexity.DirectiveCategory, "Invalid directive, which is ignored"},
+	{parseErrorRule, "complexity/" + parseErrorRule, "File failing to parse, its functions are not analyzed"},
+}
+
+// gathered results, printed when the output format is sarif
+var sarifResults = []sarifResult{}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri"`
+	Rules          []sarifRuleDescriptor `json:"rules"`
+}
+
+type sarifRuleDescriptor struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool `json:"executionSuccessful"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+}
+
+func sarifRuleIndex(rule string) int {
+	for i, r := range sarifRules {
+		if r.rule == rule {
+			return i
+		}
+	}
+	return -1
+}
+
+// sarifLocationOf locates the file relative to the current directory, like checkstyle output,
+// absolute paths outside of it being file URIs
+func sarifLocationOf(filename string, region sarifRegion) []sarifLocation {
+	uri := filepath.ToSlash(printedPath(filename, currDir))
+	if filepath.IsAbs(filename) && uri == filepath.ToSlash(filename) {
+		uri = "file://" + uri
+	}
+	return []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}, Region: region}}}
+}
+
+// sarifResultsOf returns a result per rule violated by the function, an error when far from its threshold
+// and a warning otherwise, see severeRule
+func sarifResultsOf(s complexity.FuncStatsType) []sarifResult {
+	res := []sarifResult{}
+	for _, rule := range complexity.Violations(s) {
+		level := "warning"
+		if severeRule(s, rule) {
+			level = "error"
+		}
+		i := sarifRuleIndex(rule)
+		res = append(res, sarifResult{
+			RuleID:    sarifRules[i].id,
+			RuleIndex: i,
+			Level:     level,
+			Message:   sarifMessage{Text: sarifMessageOf(s, rule)},
+			Locations: sarifLocationOf(s.Filename, sarifRegion{StartLine: s.Line, EndLine: s.EndLine}),
+		})
+	}
+	return res
+}
+
+func sarifMessageOf(s complexity.FuncStatsType, rule string) string {
+	value, threshold := ruleValue(s, rule)
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	switch rule {
+	case "recursion":
+		return fmt.Sprintf("func %s is recursive", s.FunctionName)
+	case "maint":
+		return fmt.Sprintf("func %s is under the %s threshold (%s < %s)", s.FunctionName, rule, format(value), format(threshold))
+	}
+	return fmt.Sprintf("func %s is over the %s threshold (%s > %s)", s.FunctionName, rule, format(value), format(threshold))
+}
+
+// sarifParseError is the result of a file failing to parse, an error only with -failonparseerror
+func sarifParseError(filename string, line, col int, msg string) sarifResult {
+	level := "warning"
+	if failOnParseError {
+		level = "error"
+	}
+	i := sarifRuleIndex(parseErrorRule)
+	return sarifResult{RuleID: sarifRules[i].id, RuleIndex: i, Level: level, Message: sarifMessage{Text: msg},
+		Locations: sarifLocationOf(filename, sarifRegion{StartLine: max(line, 1), StartColumn: col})}
+}
+
+// sarifFindingsOf returns a result per package, type and directive finding, like printed in txt output:
+// a warning when it fails the run and a note otherwise. The function findings are results of sarifResultsOf
+// and the parse errors of sarifParseError.
+func sarifFindingsOf(arr []foundDiagnosticsStruct) []sarifResult {
+	res := []sarifResult{}
+	for _, f := range arr {
+		for _, d := range f.diagnostics {
+			if i := sarifRuleIndex(d.Category); d.Category != "" && d.Category != parseErrorRule && i >= 0 {
+				res = append(res, sarifFinding(f.pkg, d, i))
+			}
+		}
+	}
+	return res
+}
+
+func sarifFinding(pkg *packages.Package, d analysis.Diagnostic, i int) sarifResult {
+	level := "note"
+	if countsAsViolation(d) {
+		level = "warning"
+	}
+	p := pkg.Fset.Position(d.Pos)
+	msg := strings.TrimSpace(strings.TrimPrefix(d.Message, fmt.Sprintf("%s:%d: ", p.Filename, p.Line)))
+	return sarifResult{RuleID: sarifRules[i].id, RuleIndex: i, Level: level, Message: sarifMessage{Text: msg},
+		Locations: sarifLocationOf(p.Filename, sarifRegion{StartLine: max(p.Line, 1), StartColumn: p.Column})}
+}
+
+func doPrintSarif(w io.Writer, results []sarifResult, partial bool) error {
+	rules := make([]sarifRuleDescriptor, len(sarifRules))
+	for i, r := range sarifRules {
+		rules[i] = sarifRuleDescriptor{ID: r.id, ShortDescription: sarifMessage{Text: r.description}}
+	}
+	buf, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:        sarifTool{Driver: sarifDriver{Name: complexity.Analyzer.Name, InformationURI: "https://github.com/fikin/go-complexity-analysis", Rules: rules}},
+			Invocations: []sarifInvocation{{ExecutionSuccessful: !partial}},
+			Results:     results,
+		}},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
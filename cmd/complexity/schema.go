@@ -0,0 +1,47 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// schemaCmd is the subcommand printing the JSON Schema of a json output
+const schemaCmd = "schema"
+
+// schemas are the JSON Schemas of the json outputs, by their name before .schema.json:
+// json of -out-format json and baseline of the -baseline file
+//
+//go:embed schemas/*.schema.json
+var schemas embed.FS
+
+// schemaNames returns the names of the embedded schemas, sorted
+func schemaNames() []string {
+	entries, _ := schemas.ReadDir("schemas")
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".schema.json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runSchema prints the schema of given name on stdout
+func runSchema(args []string) (exitcode int) {
+	if len(args) != 1 {
+		log.Printf("usage: %s %s <name>, where name is one of: %s", complexity.Analyzer.Name, schemaCmd, strings.Join(schemaNames(), ", "))
+		return 1
+	}
+	buf, err := schemas.ReadFile(path.Join("schemas", args[0]+".schema.json"))
+	if err != nil {
+		log.Printf("unknown schema %q, valid are: %s", args[0], strings.Join(schemaNames(), ", "))
+		return 1
+	}
+	fmt.Print(string(buf))
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"log"
+
+	"github.com/fikin/go-complexity-analysis"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// fileCmd is the subcommand analyzing lone files without loading their packages
+const fileCmd = "file"
+
+// withoutTypes is set in file mode, where the metrics needing type information are absent
+var withoutTypes bool
+
+// runFiles analyzes given .go files parse-only, tolerating missing imports.
+// Only syntax errors are fatal, types-dependent metrics are absent.
+func runFiles(filenames []string) (exitcode int) {
+	if len(filenames) == 0 {
+		log.Printf("usage: %s [-flag] %s <file.go>...", complexity.Analyzer.Name, fileCmd)
+		return 1
+	}
+	withoutTypes = true
+	warnTypesDependent()
+	fset := token.NewFileSet()
+	found := []foundDiagnosticsStruct{}
+	for _, filename := range filenames {
+		if complexity.SkipTests && complexity.IsTestFile(filename) || complexity.IsExcludedFile(filename) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			if f == nil {
+				log.Print(err)
+				return 1 // not readable
+			}
+			found = append(found, parseErrorFile(fset, f, err))
+			continue
+		}
+		if d := analyzeFile(fset, f); len(d.diagnostics) > 0 || d.funcViolations > 0 {
+			found = append(found, d)
+		}
+	}
+
+	printDiagnostics(found)
+
+	if hasViolations(found) || outputErr != nil {
+		exitcode = 1
+	}
+	return strictExitCode(exitcode, notComputed(found, nil, nil))
+}
+
+// warnTypesDependent warns that the options requesting metrics which need type information have no effect
+func warnTypesDependent() {
+	if apiReachTop > 0 || complexity.HotspotsTop > 0 || complexity.FlagRecursion {
+		log.Printf("types-dependent metrics are not available in %s mode: fan-in, recursive (-flag-recursion), api reach (-apireach) and hotspots (-hotspots)", fileCmd)
+	}
+}
+
+// parseErrorFile reports the file failing to parse, at its first error
+func parseErrorFile(fset *token.FileSet, f *ast.File, err error) foundDiagnosticsStruct {
+	pos, msg := f.Pos(), err.Error()
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		pos = filePos(fset, list[0].Pos.Filename, list[0].Pos.Line, list[0].Pos.Column)
+		msg = list[0].Msg
+	}
+	name := ""
+	if f.Name != nil {
+		name = f.Name.Name
+	}
+	return foundDiagnosticsStruct{
+		pkg:         &packages.Package{Name: name, Fset: fset},
+		diagnostics: []analysis.Diagnostic{parseErrorFinding(fset, pos, msg)},
+	}
+}
+
+// analyzeFile analyzes the functions of a parsed file without type information
+func analyzeFile(fset *token.FileSet, f *ast.File) foundDiagnosticsStruct {
+	d := foundDiagnosticsStruct{pkg: &packages.Package{Name: f.Name.Name, Fset: fset}}
+	complexity.MarkDiffFile(fset.File(f.Pos()).Name())
+	if complexity.IsGeneratedFile(f) && !complexity.IncludeGenerated {
+		return d
+	}
+	warnFnc := func(pos token.Pos, msg string) {
+		p := fset.Position(pos)
+		d.diagnostics = append(d.diagnostics, analysis.Diagnostic{Pos: pos, Message: fmt.Sprintf("%s:%d: %s", p.Filename, p.Line, msg), Category: complexity.DirectiveCategory})
+	}
+	funcs, decls := complexity.FileFuncStats(fset, nil, f.Name.Name, f.Name.Name, f, warnFnc)
+	for i := range funcs {
+		stats := funcs[i].FuncStatsType
+		complexity.ApplyIgnoredRules(&stats)
+		complexity.FuncStatsCallback(stats)
+		d.funcViolations += len(complexity.Violations(stats))
+		if msg := complexity.ToDiagnosticMsg(stats); msg != "" && !stats.Suppressed && !stats.Unchanged && !stats.Unexported {
+			diag := analysis.Diagnostic{
+				Pos:     funcs[i].Pos,
+				Message: fmt.Sprintf("%s:%d: %s\n", stats.Filename, stats.Line, msg),
+			}
+			if complexity.Explain && stats.IsTooComplex {
+				diag.Related = complexity.RelatedBranches(decls[i], nil)
+			}
+			d.diagnostics = append(d.diagnostics, diag)
+		}
+	}
+	return d
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print the findings of each package as soon as it is analyzed, also in csv
+var forceStream bool
+
+// flag option only in standalone cmdline mode
+// number of analyzed packages between progress lines while the output is buffered
+var progressEvery = 50
+
+// streaming tells if the findings are printed per package, instead of at the end of the run.
+// txt findings need no state of other packages, so they are streamed by default.
+// checkstyle, gob, metrics and sarif are documents of all results, and pkgsummary ends with the line of all of them, so they are always buffered.
+func streaming() bool {
+	switch outputFormat {
+	case "checkstyle", "gob", metricsFormat, sarifFormat, pkgSummaryFormat:
+		return false
+	case "csv":
+		return forceStream
+	default:
+		return true
+	}
+}
+
+// configureStreaming falls back to txt output, with a warning, when -stream is given along a buffered format,
+// and to the csv rows as analyzed when along -sort score, which ranks the rows of all packages
+func configureStreaming() {
+	if !forceStream {
+		return
+	}
+	switch outputFormat {
+	case "checkstyle", "gob", metricsFormat, sarifFormat:
+		log.Printf("-stream disables: -out-format %s, which needs all results; printing txt instead", outputFormat)
+		outputFormat = "txt"
+	case "csv":
+		if sortBy == sortScore {
+			log.Printf("-stream disables: -sort %s, which needs all results; printing the rows as analyzed instead", sortScore)
+			sortBy = sortNone
+		}
+	}
+}
+
+// packageAnalyzed prints the findings of a package when streaming,
+// otherwise it reports the progress every progressEvery packages
+func packageAnalyzed(found []foundDiagnosticsStruct, done, total int) {
+	if streaming() {
+		printFindings(found)
+		if outputFormat == "csv" {
+			funcStats = funcStats[:0]
+			pkgTotals = pkgTotals[:0]
+			typeStats = typeStats[:0]
+		}
+		fileFuncs = map[string][]complexity.FuncStatsType{}
+		fileHalstead = map[string]complexity.HalsteadAggregate{}
+		return
+	}
+	if progressEvery > 0 && done%progressEvery == 0 && done < total {
+		log.Printf("analyzed %d of %d packages", done, total)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// exitStrict is the exit code of -strict when some metrics could not be computed
+const exitStrict = 5
+
+// flag option only in standalone cmdline mode
+// to fail the run when any metric could not be computed, which is otherwise only logged or reported
+var strict bool
+
+// notComputed lists what the run could not compute and why: the functions of the files failing to parse,
+// the packages skipped by a stopped run and, without type information in file mode, the types-dependent metrics
+func notComputed(found []foundDiagnosticsStruct, skipped []string, stopErr error) []string {
+	missing := []string{}
+	if withoutTypes {
+		missing = append(missing, fmt.Sprintf("fan-in, recursive, api reach and hotspots of all functions: no type information in %s mode", fileCmd))
+	}
+	for _, f := range found {
+		for _, d := range f.diagnostics {
+			if d.Category == parseErrorRule {
+				missing = append(missing, fmt.Sprintf("functions of %s: the file does not parse", printedPath(diagnosticFilename(f.pkg, d), "")))
+			}
+		}
+	}
+	for _, p := range skipped {
+		missing = append(missing, fmt.Sprintf("package %s: the analysis was stopped: %v", p, stopReason(stopErr)))
+	}
+	return missing
+}
+
+// strictExitCode logs what was not computed and returns exitStrict with -strict,
+// otherwise, or when all metrics were computed, the exit code of the run
+func strictExitCode(exitcode int, missing []string) int {
+	if !strict || len(missing) == 0 {
+		return exitcode
+	}
+	for _, m := range missing {
+		log.Printf("strict: not computed: %s", m)
+	}
+	return exitStrict
+}
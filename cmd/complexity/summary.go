@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print the violation totals at the end of the run (to stderr)
+var printSummary bool
+
+// violationTotals counts violations per rule, while a function violating several rules counts once
+type violationTotals struct {
+	Functions   int
+	Violations  int
+	ByRule      map[string]int
+	FilesFailed int
+	SLOC        int
+	// SkippedPackages are the packages not analyzed as the run was stopped
+	SkippedPackages []string
+}
+
+// gathered totals, printed in the summary when printSummary is set
+var totals = violationTotals{ByRule: map[string]int{}}
+
+func (t *violationTotals) add(stats complexity.FuncStatsType) {
+	rules := complexity.Violations(stats)
+	if len(rules) == 0 {
+		return
+	}
+	t.Functions++
+	t.Violations += len(rules)
+	for _, r := range rules {
+		t.ByRule[r]++
+	}
+}
+
+func doPrintSummary(w io.Writer, t violationTotals) {
+	rules := []string{}
+	for r, cnt := range t.ByRule {
+		rules = append(rules, fmt.Sprintf("%s=%d", r, cnt))
+	}
+	sort.Strings(rules)
+	fmt.Fprintf(w, "%d violations in %d functions", t.Violations, t.Functions)
+	if len(rules) > 0 {
+		fmt.Fprintf(w, ": %s", strings.Join(rules, ", "))
+	}
+	if t.SLOC > 0 {
+		fmt.Fprintf(w, "; %0.2f violations per KLOC in %d source lines", complexity.ViolationsPerKLOCOf(t.Violations, t.SLOC), t.SLOC)
+	}
+	if t.FilesFailed > 0 {
+		fmt.Fprintf(w, "; %d files failed to parse", t.FilesFailed)
+	}
+	if len(t.SkippedPackages) > 0 {
+		fmt.Fprintf(w, "; %d packages skipped: %s", len(t.SkippedPackages), strings.Join(t.SkippedPackages, " "))
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to list the suppressed functions and those ignoring rules, with what they would violate, so stale suppressions show up
+var printSuppressed bool
+
+// gathered suppressed functions, printed at the end when printSuppressed
+var suppressedFuncs = []complexity.FuncStatsType{}
+
+// collectSuppressed keeps the function if it is suppressed or ignores some rules
+func collectSuppressed(stats complexity.FuncStatsType) {
+	if stats.Suppressed || len(stats.IgnoredRules) > 0 {
+		suppressedFuncs = append(suppressedFuncs, stats)
+	}
+}
+
+// suppressedViolations are the rules the suppressed function would violate
+func suppressedViolations(s complexity.FuncStatsType) []string {
+	if !s.Suppressed {
+		return s.IgnoredViolations
+	}
+	s.Suppressed = false
+	return complexity.Violations(s)
+}
+
+func doPrintSuppressed(w io.Writer, arr []complexity.FuncStatsType) {
+	for _, s := range arr {
+		what := "is suppressed"
+		if !s.Suppressed {
+			what = "ignores " + strings.Join(s.IgnoredRules, ",")
+		}
+		reason := s.SuppressReason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		violated := suppressedViolations(s)
+		stale := []string{}
+		for _, r := range s.IgnoredRules {
+			if !slices.Contains(violated, r) {
+				stale = append(stale, r)
+			}
+		}
+		switch {
+		case len(violated) == 0:
+			fmt.Fprintf(w, "%s:%d: func %s %s (%s), it violates no rule: stale\n", printedPath(s.Filename, ""), s.Line, s.FunctionName, what, reason)
+		case len(stale) > 0:
+			fmt.Fprintf(w, "%s:%d: func %s %s (%s), it violates %s: stale for %s\n", printedPath(s.Filename, ""), s.Line, s.FunctionName, what, reason, strings.Join(violated, ","), strings.Join(stale, ","))
+		default:
+			fmt.Fprintf(w, "%s:%d: func %s %s (%s), it violates %s\n", printedPath(s.Filename, ""), s.Line, s.FunctionName, what, reason, strings.Join(violated, ","))
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package nested
+
+func Branchy(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func Skipped(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,5 @@
+package base
+
+func Base() int {
+	return 1
+}
@@ -0,0 +1,7 @@
+package mid
+
+import "github.com/fikin/go-complexity-analysis/cmd/complexity/testdata/coupling/base"
+
+func Mid() int {
+	return base.Base() + 1
+}
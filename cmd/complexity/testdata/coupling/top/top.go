@@ -0,0 +1,10 @@
+package top
+
+import (
+	"github.com/fikin/go-complexity-analysis/cmd/complexity/testdata/coupling/base"
+	"github.com/fikin/go-complexity-analysis/cmd/complexity/testdata/coupling/mid"
+)
+
+func Top() int {
+	return base.Base() + mid.Mid()
+}
@@ -0,0 +1,7 @@
+package parseerr
+
+func broken() int {
+	return valid(2
+}
+
+func lost() {}
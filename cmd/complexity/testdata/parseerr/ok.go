@@ -0,0 +1,8 @@
+package parseerr
+
+func valid(a int) int {
+	if a > 0 {
+		return a
+	}
+	return -a
+}
@@ -0,0 +1,5 @@
+package parseerr
+
+func other() int {
+	return valid(1)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to list the functions both over a threshold and with -todomarkers in their comments
+var printTodoReport bool
+
+// gathered functions with violations and markers, printed at the end when printTodoReport
+var todoFuncs = []complexity.FuncStatsType{}
+
+// collectTodo keeps the function if it is both over a threshold and has markers
+func collectTodo(stats complexity.FuncStatsType) {
+	if stats.TodoMarkers > 0 && len(complexity.Violations(stats)) > 0 {
+		todoFuncs = append(todoFuncs, stats)
+	}
+}
+
+func doPrintTodoReport(w io.Writer, arr []complexity.FuncStatsType) {
+	for _, s := range arr {
+		fmt.Fprintf(w, "%s:%d: func %s is over %s and has %d markers\n",
+			printedPath(s.Filename, ""), s.Line, s.FunctionName, strings.Join(complexity.Violations(s), ","), s.TodoMarkers)
+		for _, e := range s.TodoExcerpts {
+			fmt.Fprintf(w, "\t%s\n", e)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print a totals row per package after the function rows of csv output
+var csvTotals bool
+
+// flag option only in standalone cmdline mode
+// to accumulate all functions of a package into its totals, not only the reported ones
+var allFuncs bool
+
+// totals modes, sum is deprecated and will be replaced by stats as the default
+const (
+	totalsModeSum   = "sum"
+	totalsModeStats = "stats"
+)
+
+// flag option only in standalone cmdline mode
+// one of : sum, stats
+var totalsMode = totalsModeSum
+
+// gathered per package totals, printed when csvTotals is set
+var pkgTotals = []packageTotals{}
+
+// totalsMetric is a metric summarized in the totals rows
+type totalsMetric struct {
+	name  string
+	value func(s complexity.FuncStatsType) float64
+	// isFloat metrics are printed with decimals, also as sums
+	isFloat bool
+	// lowerIsWorse metrics, like the maintainability index, report their minimum instead of their maximum
+	lowerIsWorse bool
+}
+
+// totalsMetrics are the metrics of the totals rows, in order
+var totalsMetrics = []totalsMetric{
+	{name: "cyclo", value: func(s complexity.FuncStatsType) float64 { return float64(s.CyclomaticComplexity) }},
+	{name: "maint", value: func(s complexity.FuncStatsType) float64 { return float64(s.MaintenabilityIndex) }, lowerIsWorse: true},
+	{name: "difficulty", value: func(s complexity.FuncStatsType) float64 { return s.HalsteadDifficulty }, isFloat: true},
+	{name: "volume", value: func(s complexity.FuncStatsType) float64 { return s.HalsteadVolume }, isFloat: true},
+	{name: "timetocode", value: func(s complexity.FuncStatsType) float64 { return s.TimeToCode }, isFloat: true},
+	{name: "loc", value: func(s complexity.FuncStatsType) float64 { return float64(s.LOC) }},
+	{name: "sloc", value: func(s complexity.FuncStatsType) float64 { return float64(s.SLOC) }},
+	{name: "cognitive", value: func(s complexity.FuncStatsType) float64 { return float64(s.CognitiveComplexity) }},
+	{name: "statements", value: func(s complexity.FuncStatsType) float64 { return float64(s.Statements) }},
+	{name: "locals", value: func(s complexity.FuncStatsType) float64 { return float64(s.Locals) }},
+	{name: "score", value: func(s complexity.FuncStatsType) float64 { return s.Score }, isFloat: true},
+}
+
+func (m totalsMetric) format(v float64) string {
+	if m.isFloat {
+		return fmt.Sprintf("%0.3f", v)
+	}
+	return strconv.Itoa(int(v))
+}
+
+// packageTotals are the functions of a package summarized in its totals row
+type packageTotals struct {
+	Package   string
+	Functions []complexity.FuncStatsType
+	// MaintainabilityIndex is of the package as a whole, regardless of the selected functions
+	MaintainabilityIndex int
+	// Halstead is of the package as a whole, regardless of the selected functions
+	Halstead complexity.HalsteadAggregate
+	// Imports are the packages the package imports, by their class
+	Imports complexity.ImportCounts
+	// Coupling is with the other analyzed packages
+	Coupling packageCoupling
+}
+
+// newPackageTotals takes the reported functions of the package, or all of them when all is set
+func newPackageTotals(pkgPath string, res *complexity.Result, all bool) packageTotals {
+	return packageTotals{Package: pkgPath, Functions: selectFuncs(res.Functions, all), MaintainabilityIndex: res.MaintainabilityIndex, Halstead: res.Halstead, Imports: res.Imports, Coupling: couplings[pkgPath]}
+}
+
+// selectFuncs returns the reported functions, or all of them when all is set, but never the unexported ones of -exported-only
+func selectFuncs(funcs []complexity.FuncResult, all bool) []complexity.FuncStatsType {
+	arr := []complexity.FuncStatsType{}
+	for _, f := range funcs {
+		if f.Unexported || !all && (f.Suppressed || f.Unchanged || complexity.ToDiagnosticMsg(f.FuncStatsType) == "") {
+			continue
+		}
+		arr = append(arr, f.FuncStatsType)
+	}
+	return arr
+}
+
+// record formats the totals as csv fields, marked by a leading "totals" field.
+// In sum mode each metric is summed, in stats mode it is summarized by its average,
+// median and maximum, or minimum for the maintainability index.
+// It ends with the maintainability index of the package, the count of functions per grade, from A to F,
+// the Halstead volume, difficulty and effort of the package as a whole,
+// the counts of its standard library, intra-module and external imports,
+// and its afferent and efferent coupling and instability,
+// followed with -halstead-raw by the sums of the distinct and total operators and operands of the functions.
+// The distinct counts are summed per function, not counted over the package as a whole.
+func (t packageTotals) record(mode string) []string {
+	rec := []string{"totals", t.Package, strconv.Itoa(len(t.Functions))}
+	for _, m := range totalsMetrics {
+		values := make([]float64, len(t.Functions))
+		for i, f := range t.Functions {
+			values[i] = m.value(f)
+		}
+		if mode == totalsModeStats {
+			rec = append(rec, fmt.Sprintf("%0.3f", average(values)), fmt.Sprintf("%0.3f", median(values)), m.format(worst(values, m.lowerIsWorse)))
+		} else {
+			rec = append(rec, m.format(sum(values)))
+		}
+	}
+	rec = append(rec, strconv.Itoa(t.MaintainabilityIndex))
+	grades := map[string]int{}
+	for _, f := range t.Functions {
+		grades[f.Grade]++
+	}
+	for _, g := range complexity.GradeLabels() {
+		rec = append(rec, strconv.Itoa(grades[g]))
+	}
+	rec = append(rec, fmt.Sprintf("%0.3f", t.Halstead.Volume), fmt.Sprintf("%0.3f", t.Halstead.Difficulty), fmt.Sprintf("%0.3f", t.Halstead.Effort))
+	rec = append(rec, strconv.Itoa(t.Imports.Stdlib), strconv.Itoa(t.Imports.Intra), strconv.Itoa(t.Imports.External))
+	rec = append(rec, strconv.Itoa(t.Coupling.Afferent), strconv.Itoa(t.Coupling.Efferent), fmt.Sprintf("%0.3f", t.Coupling.Instability))
+	if complexity.HalsteadRaw {
+		var distOpt, distOpd, sumOpt, sumOpd int
+		for _, f := range t.Functions {
+			distOpt += f.HalsteadDistinctOperators
+			distOpd += f.HalsteadDistinctOperands
+			sumOpt += f.HalsteadTotalOperators
+			sumOpd += f.HalsteadTotalOperands
+		}
+		for _, v := range []int{distOpt, distOpd, sumOpt, sumOpd} {
+			rec = append(rec, strconv.Itoa(v))
+		}
+	}
+	return rec
+}
+
+func sum(values []float64) float64 {
+	s := 0.0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return sum(values) / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// worst returns the maximum of the values, or the minimum if lowerIsWorse
+func worst(values []float64, lowerIsWorse bool) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	w := values[0]
+	for _, v := range values[1:] {
+		if lowerIsWorse && v < w || !lowerIsWorse && v > w {
+			w = v
+		}
+	}
+	return w
+}
+
+// parseTotalsMode is the -totals-mode flag parser
+func parseTotalsMode(val string) error {
+	if val != totalsModeSum && val != totalsModeStats {
+		return fmt.Errorf("unknown totals mode %q, valid are: %s, %s", val, totalsModeSum, totalsModeStats)
+	}
+	totalsMode = val
+	return nil
+}
+
+func doPrintTotals(w io.Writer, arr []packageTotals) error {
+	cw := csv.NewWriter(w)
+	for _, t := range arr {
+		if err := cw.Write(t.record(totalsMode)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
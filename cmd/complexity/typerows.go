@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/fikin/go-complexity-analysis"
+)
+
+// flag option only in standalone cmdline mode
+// to print a row per named type after the function rows of csv output, it implies -typestats
+var csvTypes bool
+
+// flag option only in standalone cmdline mode
+// to print a row per named type with the aggregated metrics of its methods, after the type rows of csv output,
+// it implies -typestats
+var csvMethodsByType bool
+
+// gathered types, printed when csvTypes or csvMethodsByType is set
+var typeStats = []complexity.TypeStatsType{}
+
+// typeRecord formats the type row as csv fields, marked by a leading "type" field
+func typeRecord(t complexity.TypeStatsType) []string {
+	return []string{"type", printedPath(t.Filename, currDir), strconv.Itoa(t.Line), t.TypeName, t.Kind,
+		strconv.Itoa(t.Methods), strconv.Itoa(t.Fields), strconv.Itoa(t.InterfaceMethods)}
+}
+
+// methodsRecord formats the methods of the type row as csv fields, marked by a leading "methods" field
+func methodsRecord(t complexity.TypeStatsType) []string {
+	return []string{"methods", printedPath(t.Filename, currDir), strconv.Itoa(t.Line), t.TypeName,
+		strconv.Itoa(t.Methods), strconv.Itoa(t.MethodsCyclo), strconv.Itoa(t.MaxMethodCyclo),
+		strconv.FormatFloat(t.MethodsMaint, 'f', 3, 64), strconv.Itoa(t.MethodsLOC)}
+}
+
+// doPrintTypeStats prints the type rows with csvTypes, then the methods rows of the types with methods with csvMethodsByType
+func doPrintTypeStats(w io.Writer, arr []complexity.TypeStatsType) error {
+	cw := csv.NewWriter(w)
+	for _, t := range arr {
+		if !csvTypes {
+			break
+		}
+		if err := cw.Write(typeRecord(t)); err != nil {
+			return err
+		}
+	}
+	for _, t := range arr {
+		if !csvMethodsByType || t.Methods == 0 {
+			continue
+		}
+		if err := cw.Write(methodsRecord(t)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
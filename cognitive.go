@@ -0,0 +1,139 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// CognitiveOver is the Cognitive complexity threshold, 0 disables the check
+var CognitiveOver int
+
+func init() {
+	Analyzer.Flags.IntVar(&CognitiveOver, "cognitiveover", 0, "print functions with the Cognitive complexity > N (0 disables the check)")
+	Analyzer.Flags.IntVar(&CognitiveOver, "cogover", 0, "same as -cognitiveover")
+}
+
+// CognitiveComplexity returns the Cognitive complexity of the function
+func CognitiveComplexity(fd *ast.FuncDecl) int {
+	return calcCognitiveComp(fd)
+}
+
+// calcCognitiveComp calculates the Cognitive complexity as defined by SonarSource:
+// control flow structures increment it, nested ones additionally by their nesting level,
+// each sequence of like boolean operators increments it once,
+// as do labeled break/continue and goto.
+func calcCognitiveComp(fd *ast.FuncDecl) int {
+	if fd.Body == nil {
+		return 0
+	}
+	c := &cognitiveCounter{}
+	c.walk(fd.Body, 0)
+	return c.comp
+}
+
+type cognitiveCounter struct {
+	comp int
+}
+
+func (c *cognitiveCounter) walk(n ast.Node, nesting int) {
+	if n == nil {
+		return
+	}
+	ast.Inspect(n, func(nn ast.Node) bool {
+		switch nn := nn.(type) {
+		case *ast.IfStmt:
+			c.walkIf(nn, nesting, false)
+			return false
+		case *ast.ForStmt:
+			c.comp += 1 + nesting
+			c.walk(nn.Init, nesting)
+			c.walk(nn.Cond, nesting)
+			c.walk(nn.Post, nesting)
+			c.walk(nn.Body, nesting+1)
+			return false
+		case *ast.RangeStmt:
+			c.comp += 1 + nesting
+			c.walk(nn.X, nesting)
+			c.walk(nn.Body, nesting+1)
+			return false
+		case *ast.SwitchStmt:
+			c.comp += 1 + nesting
+			c.walk(nn.Init, nesting)
+			c.walk(nn.Tag, nesting)
+			c.walk(nn.Body, nesting+1)
+			return false
+		case *ast.TypeSwitchStmt:
+			c.comp += 1 + nesting
+			c.walk(nn.Init, nesting)
+			c.walk(nn.Assign, nesting)
+			c.walk(nn.Body, nesting+1)
+			return false
+		case *ast.SelectStmt:
+			c.comp += 1 + nesting
+			c.walk(nn.Body, nesting+1)
+			return false
+		case *ast.FuncLit: // closures increase nesting only
+			if !skipFuncLits() {
+				c.walk(nn.Body, nesting+1)
+			}
+			return false
+		case *ast.BranchStmt:
+			if nn.Tok == token.GOTO || nn.Label != nil {
+				c.comp++
+			}
+		case *ast.BinaryExpr:
+			if nn.Op == token.LAND || nn.Op == token.LOR {
+				c.walkBoolSequence(nn, nesting)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// walkIf counts if, else-if and else, where else-if and else are not penalized for nesting
+func (c *cognitiveCounter) walkIf(n *ast.IfStmt, nesting int, isElseIf bool) {
+	c.comp++
+	if !isElseIf {
+		c.comp += nesting
+	}
+	c.walk(n.Init, nesting)
+	c.walk(n.Cond, nesting)
+	c.walk(n.Body, nesting+1)
+	switch e := n.Else.(type) {
+	case *ast.IfStmt:
+		c.walkIf(e, nesting, true)
+	case *ast.BlockStmt:
+		c.comp++
+		c.walk(e, nesting+1)
+	}
+}
+
+// walkBoolSequence counts each sequence of like boolean operators once,
+// i.e. "a && b && c" is 1 while "a && b || c" is 2
+func (c *cognitiveCounter) walkBoolSequence(n *ast.BinaryExpr, nesting int) {
+	ops := []token.Token{}
+	var flatten func(e ast.Expr)
+	flatten = func(e ast.Expr) {
+		switch e := e.(type) {
+		case *ast.ParenExpr:
+			flatten(e.X)
+		case *ast.BinaryExpr:
+			if e.Op == token.LAND || e.Op == token.LOR {
+				flatten(e.X)
+				ops = append(ops, e.Op)
+				flatten(e.Y)
+				return
+			}
+			c.walk(e, nesting)
+		default:
+			c.walk(e, nesting)
+		}
+	}
+	flatten(n)
+	for i, op := range ops {
+		if i == 0 || ops[i-1] != op {
+			c.comp++
+		}
+	}
+}
@@ -1,19 +1,53 @@
 package complexity
 
 import (
-	"flag"
 	"fmt"
 	"math"
+	"reflect"
+	"strings"
+	"sync"
 
 	"go/ast"
 	"go/token"
+	"go/types"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
 )
 
-const docComp = "complexity is cyclomatic complexity and maintanability index analyzer"
+const docComp = `complexity is cyclomatic complexity and maintanability index analyzer
+
+It calculates following metrics for each function:
+  cyclomatic complexity   number of independent paths (if, for, range, select, switch, final-else, chan read/write, ||, &&, go),
+                          without || and && with -cyclo-mode=classic
+  cognitive complexity    how hard the control flow is to understand, penalizing nesting
+  maintainability index   normalized 0-100, derived from halstead volume, cyclomatic complexity and source lines of code
+  halstead difficulty     how hard the function is to write or understand, from operators and operands
+  halstead volume         size of the function's implementation, from operators and operands
+  halstead effort         mental effort to write the function, difficulty * volume
+  time to code            estimated hours to write the function, effort / 18 seconds
+  halstead bugs           estimated number of delivered bugs, volume / 3000
+  abc size                sqrt(A²+B²+C²) of assignments, branches (calls) and conditions
+  fan-out                 number of distinct functions and methods called
+  locals                  number of local variables declared, without the parameters
+  concurrency score       go statements, channel operations, selects with their cases and sync calls
+  defers                  number of defer statements, of those in loops and of the most pending along a path
+  nesting                 deepest nesting of if, for, switch and select statements
+  risk score              0-1, weighted combination of cyclomatic complexity, maintainability index, loc and halstead volume
+  loc                     lines of code of the function
+  sloc                    source lines of code of the function, without blank and comment-only lines
+  statements              number of statements of the function, a formatting-independent size
+  var decl loc            lines of code of (only) variable and constant declarations
+
+Functions with cyclomatic complexity above -cycloover, maintainability index below -maintunder,
+or (when enabled) cognitive complexity above -cognitiveover, more parameters than -paramsover,
+more results than -resultsover, more return statements than -returnsover,
+more statements than -stmtsover, more source lines of code than -locover, Halstead effort above -effortover,
+ABC size above -abcover, fan-out above -fanoutover,
+more local variables than -localsover, concurrency score above -concover,
+more defer statements than -defersover, nesting deeper than -nestover, risk score above -scoreover
+or, with -flag-recursion, recursion are reported.`
 
 // Analyzer is ...
 var Analyzer = &analysis.Analyzer{
@@ -23,6 +57,7 @@ var Analyzer = &analysis.Analyzer{
 	Requires: []*analysis.Analyzer{
 		inspect.Analyzer,
 	},
+	ResultType: reflect.TypeOf(new(Result)),
 }
 
 // FuncStatsType is statistics of a single function
@@ -31,29 +66,215 @@ type FuncStatsType struct {
 	Line                 int
 	FunctionName         string
 	LOC                  int
+	SLOC                 int
 	ConstantsLOC         int
+	Receivers            int
+	Params               int
+	Results              int
+	Returns              int
+	Statements           int
+	FanIn                int
 	CyclomaticComplexity int
+	CognitiveComplexity  int
 	MaintenabilityIndex  int
-	HalsbreadDifficulty  float64
-	HalsbreadVolume      float64
-	TimeToCode           float64
-	IsTooComplex         bool
-	IsNotMaintenable     bool
+	ABCAssignments       int
+	ABCBranches          int
+	ABCConditions        int
+	ABCSize              float64
+	HalsteadDifficulty   float64
+	HalsteadVolume       float64
+	// Deprecated: misspelled, use HalsteadDifficulty. It is kept equal to it for one more version.
+	HalsbreadDifficulty float64 `json:"-"`
+	// Deprecated: misspelled, use HalsteadVolume. It is kept equal to it for one more version.
+	HalsbreadVolume     float64 `json:"-"`
+	HalsteadEffort      float64
+	HalsteadBugs        float64
+	TimeToCode          float64
+	IsTooComplex        bool
+	IsNotMaintenable    bool
+	IsTooCognitive      bool
+	IsTooManyParams     bool
+	IsTooManyResults    bool
+	IsTooManyReturns    bool
+	IsTooManyStatements bool
+	IsTooMuchEffort     bool
+	IsTooBigABC         bool
+	Generated           bool
+	// GenSource is the generator command of the nearest //go:generate directive preceding the function, with -gensource
+	GenSource string
+	// TodoMarkers is the number of -todomarkers, like TODO, in the comments of the function body
+	TodoMarkers int
+	// TodoExcerpts are the comment lines with markers, from their first marker on
+	TodoExcerpts []string
+	// Suppressed functions are annotated with //nolint:complexity or //complexity:ignore,
+	// they are not reported and have no violations. SuppressReason is the reason given along,
+	// also of the IgnoredRules.
+	Suppressed     bool
+	SuppressReason string
+	// Unchanged functions do not intersect the lines changed by the -diff,
+	// they are not reported and have no violations
+	Unchanged bool
+	// CycloOver and MaintUnder are the thresholds in effect for the function,
+	// the global ones unless overridden by directives like //complexity:max-cyclo=40
+	CycloOver  int
+	MaintUnder int
+	// Grade is the letter grade of the -grades, from A, the best, to F
+	Grade string
+	// CommentLines are the lines of the function and its doc comment holding comments, with -mi-with-comments
+	CommentLines int
+	// ClassicMaintIndex is the Maintainability index without the comment bonus, with -mi-with-comments
+	ClassicMaintIndex int
+	// Halstead counts, with -halstead-raw: n1, n2, N1, N2, the vocabulary n1+n2 and the length N1+N2
+	HalsteadDistinctOperators int `json:",omitempty"`
+	HalsteadDistinctOperands  int `json:",omitempty"`
+	HalsteadTotalOperators    int `json:",omitempty"`
+	HalsteadTotalOperands     int `json:",omitempty"`
+	HalsteadVocabulary        int `json:",omitempty"`
+	HalsteadLength            int `json:",omitempty"`
+	// FanOut is the number of distinct functions and methods the function calls
+	FanOut          int
+	IsTooMuchFanOut bool
+	// Locals is the number of local variables the function declares, without its parameters
+	Locals          int
+	IsTooManyLocals bool
+	// concurrency constructs, see ConcurrencyCounts, and their ConcurrencyScore sum
+	GoStmts          int
+	ChanOps          int
+	Selects          int
+	SelectCases      int
+	SyncCalls        int
+	ConcurrencyScore int
+	IsTooConcurrent  bool
+	// Recursive functions call themselves, CallsItself, or are mutually recursive with the RecursiveWith functions
+	// of the package, calling each other directly. It is detected with type information only.
+	Recursive          bool
+	CallsItself        bool
+	RecursiveWith      []string `json:",omitempty"`
+	IsFlaggedRecursive bool
+	// Package is the import path of the package of the function, its package name in file mode
+	Package string
+	// DeclarationOnly functions have no body, like those implemented in assembly,
+	// so their Cyclomatic complexity is 1 and their Halstead metrics are 0
+	DeclarationOnly bool
+	// Score is the risk score combining the metrics, see RiskScore
+	Score      float64
+	IsTooRisky bool
+	// EndLine is the line of the closing brace, Span the lines from Line to EndLine like LOC
+	EndLine int
+	Span    int
+	// NameLine and NameColumn are the position of the function name, unlike Line which is of the func keyword
+	NameLine   int
+	NameColumn int
+	// defer statements, see DeferCounts
+	Defers          int
+	DefersInLoop    int
+	MaxLiveDefers   int
+	IsTooManyDefers bool
+	// Unexported functions are outside of the API with -exported-only,
+	// they are not reported and have no violations
+	Unexported bool
+	// IsTooLong functions have more source lines of code than -locover
+	IsTooLong bool
+	// IgnoredRules are the rules ignored by //complexity:ignore <rules> directives, the function is still
+	// reported for the others. IgnoredViolations are those of them the function would otherwise violate.
+	IgnoredRules      []string
+	IgnoredViolations []string
+	// MaxNesting is the deepest nesting of the control statements, see MaxNesting
+	MaxNesting  int
+	IsTooNested bool
+}
+
+// FuncResult is statistics of a single function along with its declaration position
+type FuncResult struct {
+	Pos token.Pos
+	FuncStatsType
+}
+
+// Result is the Analyzer result, consumable by downstream analyzers.
+// It contains all functions of the package, not only those crossing the thresholds,
+// and does not change what is printed or reported.
+type Result struct {
+	Functions []FuncResult
+	// HalsteadNormalization records the operand normalization used for Halstead metrics
+	HalsteadNormalization string
+	// APIReach is the complexity reachable from each exported function, highest first
+	APIReach []APIReachType
+	// SLOC is the source lines of code of the analyzed files of the package
+	SLOC int
+	// Violations is the number of violations of all functions, counted once per rule
+	Violations int
+	// MaintainabilityIndex is the Maintainability index of all functions of the package taken as a whole
+	MaintainabilityIndex int
+	// Types are the package level named types, with -typestats
+	Types []TypeResult
+	// Halstead are the Halstead metrics of the package taken as a whole, see HalsteadAggregate
+	Halstead HalsteadAggregate
+	// FileHalstead are the Halstead metrics of each analyzed file taken as a whole, by file name
+	FileHalstead map[string]HalsteadAggregate
+	// Imports are the packages the package imports, by their class, see ClassifyImport
+	Imports ImportCounts
 }
 
 // FuncStatsCallback is called on each processed function statictics
 // Main is to define its own callback logic instead.
 var FuncStatsCallback = func(s FuncStatsType) {}
 
+// PackageResultCallback is called with the result of each processed package, after its FuncStatsCallback calls.
+// The callbacks are never called concurrently, also when packages are analyzed concurrently.
+// Main is to define its own callback logic instead.
+var PackageResultCallback = func(pkgPath string, res *Result) {}
+
 var (
 	CycloOver   int
 	MaintUnder  int
-	SkipFileFnc = func(filename string) bool { return false }
+	ReturnsOver int
+	StmtsOver   int
+	// LOCOver is the function length threshold on the source lines of code, 0 disables the check
+	LOCOver    int
+	EffortOver float64
+	// MIUseStatements makes the Maintainability index use the statements count instead of lines of code
+	MIUseStatements bool
+	SkipFileFnc     = func(filename string) bool { return false }
+	// SkipTests skips the functions of _test.go files
+	SkipTests bool
+)
+
+// Halstead operand normalization options.
+// The defaults (no selector flattening, merged literals, case-sensitive identifiers)
+// reproduce the original behavior.
+var (
+	HalstFlattenSelectors bool
+	HalstMergeLiterals    = true
+	HalstFoldCase         bool
+	// HalsteadRaw keeps the operator and operand counts the Halstead metrics derive from
+	HalsteadRaw bool
+	// ShowLines adds the line range of the function to the diagnostic messages
+	ShowLines bool
 )
 
+// flags are registered on Analyzer.Flags so the analyzer composes with
+// other analyzers in multichecker and unitchecker drivers
 func init() {
-	flag.IntVar(&CycloOver, "cycloover", 10, "print functions with the Cyclomatic complexity > N")
-	flag.IntVar(&MaintUnder, "maintunder", 20, "print functions with the Maintainability index < N")
+	Analyzer.Flags.IntVar(&CycloOver, "cycloover", 10, "print functions with the Cyclomatic complexity > N")
+	Analyzer.Flags.IntVar(&MaintUnder, "maintunder", 20, "print functions with the Maintainability index < N")
+	Analyzer.Flags.IntVar(&ReturnsOver, "returnsover", 0, "print functions with more than N return statements (0 disables the check)")
+	Analyzer.Flags.IntVar(&StmtsOver, "stmtsover", 0, "print functions with more than N statements (0 disables the check)")
+	Analyzer.Flags.IntVar(&StmtsOver, "stmtover", 0, "same as -stmtsover")
+	Analyzer.Flags.IntVar(&LOCOver, "locover", 0, "print functions with more than N source lines of code, without blank and comment-only lines (0 disables the check)")
+	Analyzer.Flags.Float64Var(&EffortOver, "effortover", 0, "print functions with the Halstead effort > N (0 disables the check)")
+	Analyzer.Flags.Float64Var(&EffortOver, "halsteffortover", 0, "same as -effortover")
+	Analyzer.Flags.BoolVar(&SkipTests, "skiptests", false, "skip the functions of _test.go files")
+	Analyzer.Flags.BoolVar(&MIUseStatements, "mi-use-statements", false, "use the statements count instead of lines of code in the Maintainability index")
+	Analyzer.Flags.BoolVar(&HalstFlattenSelectors, "halstflatten", false, "count selectors like s.x and pkg.X as a single Halstead operand")
+	Analyzer.Flags.BoolVar(&HalstMergeLiterals, "halstmergelits", true, "count literals with identical content as the same Halstead operand")
+	Analyzer.Flags.BoolVar(&HalstFoldCase, "halstfoldcase", false, "treat identifiers case-insensitively in Halstead metrics")
+	Analyzer.Flags.BoolVar(&ShowLines, "show-lines", false, "add the line range of the function to the messages, like 'lines 190–243'")
+	Analyzer.Flags.BoolVar(&HalsteadRaw, "halstead-raw", false, "output the distinct and total Halstead operator and operand counts, the vocabulary and the length")
+}
+
+// HalsteadNormalization describes the Halstead operand normalization in effect
+func HalsteadNormalization() string {
+	return fmt.Sprintf("flatten-selectors=%t,merge-literals=%t,fold-case=%t,literals=%s", HalstFlattenSelectors, HalstMergeLiterals, HalstFoldCase, HalstLiterals)
 }
 
 func runComp(pass *analysis.Pass) (facts interface{}, err error) {
@@ -61,20 +282,135 @@ func runComp(pass *analysis.Pass) (facts interface{}, err error) {
 	if !ok {
 		return nil, fmt.Errorf("internal error, wrong inspector.Inspector type")
 	}
+	res := &Result{HalsteadNormalization: HalsteadNormalization()}
+	decls := []*ast.FuncDecl{}
+	files := []*ast.File{}
 	inspector.Preorder([]ast.Node{(*ast.File)(nil)}, func(n ast.Node) {
-		if SkipFileFnc(pass.Fset.File(n.Pos()).Name()) {
+		filename := pass.Fset.File(n.Pos()).Name()
+		MarkDiffFile(filename) // a skipped file is not a mismatched diff
+		if SkipFileFnc(filename) || SkipTests && IsTestFile(filename) || IsExcludedFile(filename) {
+			return
+		}
+		generatedFile := IsGeneratedFile(n.(*ast.File))
+		if generatedFile && !IncludeGenerated {
 			return
 		}
-		astVisitFunctions(n, func(nn *ast.FuncDecl) {
-			stats := calcFuncStats(pass, nn)
-			reportFnc := func(msg string, args ...interface{}) {
-				pass.Reportf(nn.Pos(), msg, args...)
+		files = append(files, n.(*ast.File))
+		warnFnc := func(pos token.Pos, msg string) {
+			p := pass.Fset.Position(pos)
+			pass.Report(analysis.Diagnostic{Pos: pos, Message: fmt.Sprintf("%s:%d: %s", p.Filename, p.Line, msg), Category: DirectiveCategory})
+		}
+		funcs, fileDecls := FileFuncStats(pass.Fset, pass.TypesInfo, pass.Pkg.Path(), pass.Pkg.Name(), n.(*ast.File), warnFnc)
+		res.Functions = append(res.Functions, funcs...)
+		decls = append(decls, fileDecls...)
+	})
+	g := buildCallGraph(pass.TypesInfo, pass.Pkg, decls)
+	for i, fanIn := range g.fanIn() {
+		res.Functions[i].FanIn = fanIn
+	}
+	applyRecursion(g, res.Functions)
+	for i := range res.Functions {
+		ApplyIgnoredRules(&res.Functions[i].FuncStatsType)
+	}
+	for i, f := range res.Functions {
+		reportFnc := func(msg string, args ...interface{}) {
+			d := analysis.Diagnostic{Pos: f.Pos, Message: fmt.Sprintf(msg, args...)}
+			if Explain && f.IsTooComplex {
+				d.Related = RelatedBranches(decls[i], pass.TypesInfo)
 			}
-			reportFuncStats(reportFnc, stats)
-			FuncStatsCallback(stats)
-		})
+			pass.Report(d)
+		}
+		reportFuncStats(reportFnc, f.FuncStatsType)
+	}
+	res.APIReach = calcAPIReach(g, decls, res.Functions)
+	reportHotspots(pass, decls, res.Functions)
+	reportDefersInLoops(pass, decls, res.Functions)
+	res.SLOC, res.Violations = countPackageSLOC(pass, files), countViolations(res.Functions)
+	reportDensity(pass, files, res)
+	res.MaintainabilityIndex = PackageMaintainabilityIndex(res.Functions)
+	res.Halstead, res.FileHalstead = halsteadAggregates(pass.Fset, pass.TypesInfo, files, decls, res.Functions)
+	reportPackageMaint(pass, files, res)
+	res.Imports = CountImports(pass.Pkg, ModuleOf(pass.Pkg.Path()))
+	reportImports(pass, files, res)
+	if TypeStats {
+		res.Types = CalcTypeStats(pass.Fset, files)
+		aggregateMethods(res.Types, decls, res.Functions)
+		reportTypeStats(pass, res.Types)
+	}
+	deliverCallbacks(pass.Pkg.Path(), res)
+	return res, nil
+}
+
+// FileFuncStats calculates the statistics of the functions of the file, and with -funclit of its function literals,
+// along with their declarations in the same order. It applies the generated code markers, the -todomarkers,
+// the suppression, threshold and -diff options, but not those needing all functions of the package, like the fan-in.
+// info is nil without type information, like in file mode, pkgPath and pkgName are then both the package name.
+// Invalid directives are reported via warnFnc.
+func FileFuncStats(fset *token.FileSet, info *types.Info, pkgPath, pkgName string, f *ast.File, warnFnc func(pos token.Pos, msg string)) (funcs []FuncResult, decls []*ast.FuncDecl) {
+	generatedFile := IsGeneratedFile(f)
+	genRegions := findGenRegions(f, warnFnc)
+	genCmds := findGenCommands(f)
+	todoRe := todoMarkersRegexp()
+	pkgThresholds := PackageThresholds(pkgPath)
+	addFunc := func(nn *ast.FuncDecl) {
+		if IsExcludedFunc(pkgPath, nn) {
+			return
+		}
+		inGenRegion := isInGenRegion(genRegions, nn)
+		if inGenRegion && SkipGenRegions {
+			return
+		}
+		stats := funcStats(fset, info, nn)
+		stats.Package = pkgPath
+		stats.Generated = generatedFile || inGenRegion
+		if GenSource {
+			stats.GenSource = genSourceOf(genCmds, nn)
+		}
+		stats.TodoMarkers, stats.TodoExcerpts = findTodoMarkers(todoRe, f, nn)
+		stats.Suppressed, stats.SuppressReason = SuppressionOf(fset, f, nn)
+		ignoredRules, ignoreReason := IgnoredRulesOf(fset, f, nn, warnFnc)
+		if !stats.Suppressed {
+			stats.IgnoredRules, stats.SuppressReason = ignoredRules, ignoreReason
+		}
+		if !stats.Suppressed && SkipEntryPoints && IsEntryPoint(pkgName, nn) {
+			stats.Suppressed, stats.SuppressReason = true, EntryPointReason
+		}
+		stats.Unchanged = IsUnchanged(fset, nn)
+		stats.Unexported = ExportedOnly && !IsExportedFunc(nn)
+		ApplyCommentWeight(&stats, fset, f, nn)
+		ApplyPackageThresholds(&stats, pkgThresholds)
+		ApplyThresholdDirectives(&stats, nn, warnFnc)
+		funcs = append(funcs, FuncResult{Pos: nn.Pos(), FuncStatsType: stats})
+		decls = append(decls, nn)
+	}
+	astVisitFunctions(f, func(nn *ast.FuncDecl) {
+		addFunc(nn)
+		if FuncLitUnits {
+			visitFuncLits(nn, DisplayName(nn), addFunc)
+		}
 	})
-	return
+	if FuncLitUnits {
+		visitFuncLits(f, "glob", addFunc)
+	}
+	return funcs, decls
+}
+
+// callbacksMu serializes the callbacks, as drivers like multichecker analyze packages concurrently
+var callbacksMu sync.Mutex
+
+// deliverCallbacks calls the callbacks of the package as one block, not interleaved with those of other packages:
+// FuncStatsCallback per function, TypeStatsCallback per type and then PackageResultCallback.
+// The blocks of the packages come in the order the driver completes them.
+func deliverCallbacks(pkgPath string, res *Result) {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+	for _, f := range res.Functions {
+		FuncStatsCallback(f.FuncStatsType)
+	}
+	for _, t := range res.Types {
+		TypeStatsCallback(t.TypeStatsType)
+	}
+	PackageResultCallback(pkgPath, res)
 }
 
 type branchVisitor func(n ast.Node) (w ast.Visitor)
@@ -84,27 +420,116 @@ func (v branchVisitor) Visit(n ast.Node) (w ast.Visitor) {
 	return v(n)
 }
 
-func calcFuncStats(pass *analysis.Pass, n *ast.FuncDecl) FuncStatsType {
+// FuncStats calculates the statistics of a single function.
+// It can be used directly on parsed code, without the analysis driver.
+// Without type information the Halstead metrics classify identifiers
+// by their syntactic resolution only, see HalsteadMetrics.
+func FuncStats(fset *token.FileSet, n *ast.FuncDecl) FuncStatsType {
+	return funcStats(fset, nil, n)
+}
+
+func funcStats(fset *token.FileSet, info *types.Info, n *ast.FuncDecl) FuncStatsType {
 	nPos := n.Pos()
-	pos := pass.Fset.File(nPos).Position(nPos)
+	pos := fset.File(nPos).Position(nPos)
+	end := fset.Position(n.End())
+	name := fset.Position(n.Name.Pos())
 
 	stats := FuncStatsType{
-		Filename:             pos.Filename,
-		Line:                 pos.Line,
-		FunctionName:         n.Name.Name,
-		LOC:                  countLOC(pass.Fset, n),
-		ConstantsLOC:         countVarsLOC(pass.Fset, n),
-		CyclomaticComplexity: calcCycloComp(n),
-	}
-	stats.HalsbreadDifficulty, stats.HalsbreadVolume = calcHalstComp(n)
-	stats.MaintenabilityIndex = calcMaintIndex(stats.HalsbreadVolume, stats.CyclomaticComplexity, stats.LOC)
+		Filename:            pos.Filename,
+		Line:                pos.Line,
+		FunctionName:        DisplayName(n),
+		LOC:                 countLOC(fset, n),
+		SLOC:                countSLOC(fset, n),
+		ConstantsLOC:        countVarsLOC(fset, n),
+		CognitiveComplexity: CognitiveComplexity(n),
+		Receivers:           countFields(n.Recv),
+		Params:              countFields(n.Type.Params),
+		Results:             countFields(n.Type.Results),
+		Returns:             countReturns(n),
+		Statements:          countStmts(n),
+		DeclarationOnly:     n.Body == nil,
+		EndLine:             end.Line,
+		Span:                end.Line - pos.Line + 1,
+		NameLine:            name.Line,
+		NameColumn:          name.Column,
+	}
+	w := walkFunc(n, info)
+	stats.CyclomaticComplexity = w.cycloComp()
+	stats.HalsteadDifficulty, stats.HalsteadVolume = w.halstComp()
+	if HalsteadRaw {
+		stats.HalsteadDistinctOperators, stats.HalsteadDistinctOperands, stats.HalsteadTotalOperators, stats.HalsteadTotalOperands = w.halstCounts()
+		stats.HalsteadVocabulary = stats.HalsteadDistinctOperators + stats.HalsteadDistinctOperands
+		stats.HalsteadLength = stats.HalsteadTotalOperators + stats.HalsteadTotalOperands
+	}
+	stats.HalsbreadDifficulty, stats.HalsbreadVolume = stats.HalsteadDifficulty, stats.HalsteadVolume
+	size := stats.SLOC
+	if MIUseStatements {
+		size = stats.Statements
+	}
+	stats.MaintenabilityIndex = MaintainabilityIndex(stats.HalsteadVolume, stats.CyclomaticComplexity, size)
+	stats.Grade = GradeOf(stats.CyclomaticComplexity, stats.MaintenabilityIndex)
+	stats.CycloOver, stats.MaintUnder = CycloOver, MaintUnder
 	stats.IsTooComplex = stats.CyclomaticComplexity > CycloOver
 	stats.IsNotMaintenable = stats.MaintenabilityIndex < MaintUnder
-	stats.TimeToCode = stats.HalsbreadDifficulty * stats.HalsbreadVolume / (18 * 3600)
+	stats.IsTooCognitive = CognitiveOver > 0 && stats.CognitiveComplexity > CognitiveOver
+	stats.IsTooManyParams = ParamsOver > 0 && stats.Params > ParamsOver
+	stats.IsTooManyResults = ResultsOver > 0 && stats.Results > ResultsOver
+	stats.IsTooManyReturns = ReturnsOver > 0 && stats.Returns > ReturnsOver
+	stats.IsTooManyStatements = StmtsOver > 0 && stats.Statements > StmtsOver
+	stats.IsTooLong = LOCOver > 0 && stats.SLOC > LOCOver
+	stats.HalsteadEffort = stats.HalsteadDifficulty * stats.HalsteadVolume
+	stats.HalsteadBugs = stats.HalsteadVolume / 3000
+	stats.TimeToCode = stats.HalsteadEffort / (18 * 3600)
+	stats.IsTooMuchEffort = EffortOver > 0 && stats.HalsteadEffort > EffortOver
+	stats.ABCAssignments, stats.ABCBranches, stats.ABCConditions, stats.ABCSize = ABCMetrics(n)
+	stats.IsTooBigABC = ABCOver > 0 && stats.ABCSize > ABCOver
+	stats.FanOut = FanOut(info, n)
+	stats.IsTooMuchFanOut = FanOutOver > 0 && stats.FanOut > FanOutOver
+	stats.Locals = Locals(info, n)
+	stats.IsTooManyLocals = LocalsOver > 0 && stats.Locals > LocalsOver
+	conc := Concurrency(info, n)
+	stats.GoStmts, stats.ChanOps, stats.Selects, stats.SelectCases, stats.SyncCalls = conc.GoStmts, conc.ChanOps, conc.Selects, conc.SelectCases, conc.SyncCalls
+	stats.ConcurrencyScore = conc.Score()
+	stats.IsTooConcurrent = ConcOver > 0 && stats.ConcurrencyScore > ConcOver
+	defers := Defers(n)
+	stats.Defers, stats.DefersInLoop, stats.MaxLiveDefers = defers.Defers, defers.InLoop, defers.MaxLive
+	stats.IsTooManyDefers = DefersOver > 0 && stats.Defers > DefersOver
+	stats.MaxNesting = MaxNesting(n)
+	stats.IsTooNested = NestOver > 0 && stats.MaxNesting > NestOver
+	applyScore(&stats)
 
 	return stats
 }
 
+// CyclomaticComplexity returns the Cyclomatic complexity of the function
+func CyclomaticComplexity(fd *ast.FuncDecl) int {
+	return walkFunc(fd, nil).cycloComp()
+}
+
+// HalsteadMetrics returns the Halstead difficulty and volume of the function.
+// Identifiers resolved within the file are operands, all others operators.
+// Use HalsteadMetricsWithTypes for the exact classification of type-checked code.
+func HalsteadMetrics(fd *ast.FuncDecl) (difficulty float64, volume float64) {
+	return walkFunc(fd, nil).halstComp()
+}
+
+// HalsteadMetricsWithTypes returns the Halstead difficulty and volume of the function,
+// classifying identifiers by the objects they denote in info
+func HalsteadMetricsWithTypes(fd *ast.FuncDecl, info *types.Info) (difficulty float64, volume float64) {
+	return walkFunc(fd, info).halstComp()
+}
+
+// MaintainabilityIndex returns the Maintainability index, normalized to 0-100 unless -mi-scale=raw,
+// for given Halstead volume, Cyclomatic complexity and lines of code
+func MaintainabilityIndex(volume float64, cyclo, loc int) int {
+	return calcMaintIndex(volume, cyclo, loc)
+}
+
+// IsTestFile tells if the file is a test file, of the package or of its external _test package
+func IsTestFile(filename string) bool {
+	return strings.HasSuffix(filename, "_test.go")
+}
+
 func astVisitFunctions(n ast.Node, cb func(*ast.FuncDecl)) {
 	var v ast.Visitor
 	v = branchVisitor(func(nn ast.Node) ast.Visitor {
@@ -117,21 +542,66 @@ func astVisitFunctions(n ast.Node, cb func(*ast.FuncDecl)) {
 	ast.Walk(v, n)
 }
 
-func calcHalstComp(fd *ast.FuncDecl) (difficulty float64, volume float64) {
-	operators, operands := map[string]int{}, map[string]int{}
+// funcWalker accumulates the metrics of a single traversal of a function:
+// the occurrences of each Halstead operator and operand, and the branches of the Cyclomatic complexity
+type funcWalker struct {
+	opt  map[string]int
+	opd  map[string]int
+	info *types.Info
+	// branches are the decision points outside of function literals
+	branches int
+	// points are the positions of the branches
+	points []BranchPoint
+	// uncounted is the nesting of subtrees whose branches are not counted:
+	// function literals, which have their own control flow, and values walked again for each name
+	uncounted int
+}
+
+// walkFunc traverses the function once, see funcWalker
+func walkFunc(fd *ast.FuncDecl, info *types.Info) *funcWalker {
+	w := &funcWalker{opt: map[string]int{}, opd: map[string]int{}, info: info}
+	// declarations without body, like of assembly functions, have no code to measure
+	if fd.Body != nil {
+		w.walkDecl(fd)
+	}
+	return w
+}
 
-	walkDecl(fd, operators, operands)
+// branch counts n decision points at pos, unless within an uncounted subtree
+func (w *funcWalker) branch(pos token.Pos, label string, n int) {
+	if w.uncounted == 0 {
+		w.branches += n
+		w.points = append(w.points, BranchPoint{Pos: pos, Label: label, Weight: n})
+	}
+}
 
-	distOpt := len(operators) // distinct operators
-	distOpd := len(operands)  // distinct operands
-	var sumOpt, sumOpd int
-	for _, val := range operators {
+// halsteadCounts counts the occurrences of each operator and operand of the function
+func halsteadCounts(fd *ast.FuncDecl, info *types.Info) (operators, operands map[string]int) {
+	w := walkFunc(fd, info)
+	return w.opt, w.opd
+}
+
+// cycloComp is the Cyclomatic complexity: the branches
+// of if, final else, for, range, select, switch, channel read or write, && and ||, plus 2 per go statement
+func (w *funcWalker) cycloComp() int {
+	return 1 + w.branches
+}
+
+// halstCounts returns the distinct and total operators and operands, n1, n2, N1 and N2
+func (w *funcWalker) halstCounts() (distOpt, distOpd, sumOpt, sumOpd int) {
+	distOpt = len(w.opt) // distinct operators
+	distOpd = len(w.opd) // distinct operands
+	for _, val := range w.opt {
 		sumOpt += val
 	}
-
-	for _, val := range operands {
+	for _, val := range w.opd {
 		sumOpd += val
 	}
+	return
+}
+
+func (w *funcWalker) halstComp() (difficulty float64, volume float64) {
+	distOpt, distOpd, sumOpt, sumOpd := w.halstCounts()
 
 	nVocab := distOpt + distOpd
 	length := sumOpt + sumOpd
@@ -145,298 +615,476 @@ func calcHalstComp(fd *ast.FuncDecl) (difficulty float64, volume float64) {
 	return
 }
 
-func walkDecl(n ast.Node, opt map[string]int, opd map[string]int) {
+func (w *funcWalker) walkDecl(n ast.Node) {
 	switch n := n.(type) {
 	case *ast.GenDecl:
-		appendValidSymb(n.Lparen.IsValid(), n.Rparen.IsValid(), opt, "()")
+		appendValidSymb(n.Lparen.IsValid(), n.Rparen.IsValid(), w.opt, "()")
 
 		if n.Tok.IsOperator() {
-			opt[n.Tok.String()]++
+			w.opt[n.Tok.String()]++
 		} else {
-			opd[n.Tok.String()]++
+			w.opd[n.Tok.String()]++
 		}
 		for _, s := range n.Specs {
-			walkSpec(s, opt, opd)
+			w.walkSpec(s)
 		}
 	case *ast.FuncDecl:
 		if n.Recv == nil {
-			opt["func"]++
-			opt[n.Name.Name]++
-			opt["()"]++
+			w.opt["func"]++
+			w.opt[n.Name.Name]++
+			w.opt["()"]++
 		} else {
-			opt["func"]++
-			opt[n.Name.Name]++
-			opt["()"] += 2
+			w.opt["func"]++
+			w.opt[n.Name.Name]++
+			w.opt["()"] += 2
+		}
+		w.walkTypeParams(n.Type.TypeParams)
+		if n.Body != nil {
+			w.walkStmt(n.Body)
 		}
-		walkStmt(n.Body, opt, opd)
+	default:
+		recordUnhandledNode(n)
 	}
 }
 
-func walkStmt(n ast.Node, opt map[string]int, opd map[string]int) {
+func (w *funcWalker) walkStmt(n ast.Node) {
 	switch n := n.(type) {
 	case *ast.DeclStmt:
-		walkDecl(n.Decl, opt, opd)
+		w.walkDecl(n.Decl)
 	case *ast.ExprStmt:
-		walkExpr(n.X, opt, opd)
+		w.walkExpr(n.X)
 	case *ast.SendStmt:
-		walkExpr(n.Chan, opt, opd)
+		w.branch(n.Arrow, "<-", 1) // writing to channels
+		w.walkExpr(n.Chan)
 		if n.Arrow.IsValid() {
-			opt["<-"]++
+			w.opt["<-"]++
 		}
-		walkExpr(n.Value, opt, opd)
+		w.walkExpr(n.Value)
 	case *ast.IncDecStmt:
-		walkExpr(n.X, opt, opd)
+		w.walkExpr(n.X)
 		if n.Tok.IsOperator() {
-			opt[n.Tok.String()]++
+			w.opt[n.Tok.String()]++
 		}
 	case *ast.AssignStmt:
 		if n.Tok.IsOperator() {
-			opt[n.Tok.String()]++
+			w.opt[n.Tok.String()]++
 		}
 		for _, exp := range n.Lhs {
-			walkExpr(exp, opt, opd)
+			w.walkExpr(exp)
 		}
 		for _, exp := range n.Rhs {
-			walkExpr(exp, opt, opd)
+			w.walkExpr(exp)
 		}
 	case *ast.GoStmt:
+		w.branch(n.Go, "go", 2) // subroutines are double complexity
 		if n.Go.IsValid() {
-			opt["go"]++
+			w.opt["go"]++
 		}
-		walkExpr(n.Call, opt, opd)
+		w.walkExpr(n.Call)
 	case *ast.DeferStmt:
 		if n.Defer.IsValid() {
-			opt["defer"]++
+			w.opt["defer"]++
 		}
-		walkExpr(n.Call, opt, opd)
+		w.walkExpr(n.Call)
 	case *ast.ReturnStmt:
 		if n.Return.IsValid() {
-			opt["return"]++
+			w.opt["return"]++
 		}
 		for _, e := range n.Results {
-			walkExpr(e, opt, opd)
+			w.walkExpr(e)
 		}
 	case *ast.BranchStmt:
-		if n.Tok.IsOperator() {
-			opt[n.Tok.String()]++
+		if n.Tok.IsOperator() || n.Tok == token.GOTO || n.Label != nil { // goto and labeled break/continue jump
+			w.opt[n.Tok.String()]++
 		} else {
-			opd[n.Tok.String()]++
+			w.opd[n.Tok.String()]++
 		}
 		if n.Label != nil {
-			walkExpr(n.Label, opt, opd)
+			w.opd[identKey(n.Label.Name)]++
+		}
+	case *ast.LabeledStmt:
+		w.opd[identKey(n.Label.Name)]++
+		if n.Colon.IsValid() {
+			w.opt[":"]++
+		}
+		w.walkStmt(n.Stmt)
+	case *ast.EmptyStmt:
+		if !n.Implicit {
+			w.opt[";"]++
 		}
 	case *ast.BlockStmt:
-		appendValidSymb(n.Lbrace.IsValid(), n.Rbrace.IsValid(), opt, "{}")
+		appendValidSymb(n.Lbrace.IsValid(), n.Rbrace.IsValid(), w.opt, "{}")
 		for _, s := range n.List {
-			walkStmt(s, opt, opd)
+			w.walkStmt(s)
 		}
 	case *ast.IfStmt:
+		w.branch(n.If, "if", 1)
+		if _, ok := n.Else.(*ast.BlockStmt); ok { // include final else
+			w.branch(n.Else.Pos(), "else", 1)
+		}
 		if n.If.IsValid() {
-			opt["if"]++
+			w.opt["if"]++
 		}
 		if n.Init != nil {
-			walkStmt(n.Init, opt, opd)
+			w.walkStmt(n.Init)
 		}
-		walkExpr(n.Cond, opt, opd)
-		walkStmt(n.Body, opt, opd)
+		w.walkExpr(n.Cond)
+		w.walkStmt(n.Body)
 		if n.Else != nil {
-			opt["else"]++
-			walkStmt(n.Else, opt, opd)
+			w.opt["else"]++
+			w.walkStmt(n.Else)
 		}
 	case *ast.SwitchStmt:
+		w.branch(n.Switch, "switch", 1)
 		if n.Switch.IsValid() {
-			opt["switch"]++
+			w.opt["switch"]++
 		}
 		if n.Init != nil {
-			walkStmt(n.Init, opt, opd)
+			w.walkStmt(n.Init)
 		}
 		if n.Tag != nil {
-			walkExpr(n.Tag, opt, opd)
+			w.walkExpr(n.Tag)
+		}
+		w.walkStmt(n.Body)
+	case *ast.TypeSwitchStmt:
+		if n.Switch.IsValid() {
+			w.opt["switch"]++
+		}
+		if n.Init != nil {
+			w.walkStmt(n.Init)
 		}
-		walkStmt(n.Body, opt, opd)
+		w.walkStmt(n.Assign)
+		w.walkStmt(n.Body)
 	case *ast.SelectStmt:
+		w.branch(n.Select, "select", 1)
 		if n.Select.IsValid() {
-			opt["select"]++
+			w.opt["select"]++
 		}
-		walkStmt(n.Body, opt, opd)
+		w.walkStmt(n.Body)
 	case *ast.ForStmt:
+		w.branch(n.For, "for", 1)
 		if n.For.IsValid() {
-			opt["for"]++
+			w.opt["for"]++
 		}
 		if n.Init != nil {
-			walkStmt(n.Init, opt, opd)
+			w.walkStmt(n.Init)
 		}
 		if n.Cond != nil {
-			walkExpr(n.Cond, opt, opd)
+			w.walkExpr(n.Cond)
 		}
 		if n.Post != nil {
-			walkStmt(n.Post, opt, opd)
+			w.walkStmt(n.Post)
 		}
-		walkStmt(n.Body, opt, opd)
+		w.walkStmt(n.Body)
 	case *ast.RangeStmt:
+		w.branch(n.For, "range", 1)
 		if n.For.IsValid() {
-			opt["for"]++
+			w.opt["for"]++
 		}
 		if n.Key != nil {
-			walkExpr(n.Key, opt, opd)
+			w.walkExpr(n.Key)
 			if n.Tok.IsOperator() {
-				opt[n.Tok.String()]++
+				w.opt[n.Tok.String()]++
 			} else {
-				opd[n.Tok.String()]++
+				w.opd[n.Tok.String()]++
 			}
 		}
 		if n.Value != nil {
-			walkExpr(n.Value, opt, opd)
+			w.walkExpr(n.Value)
 		}
-		opt["range"]++
-		walkExpr(n.X, opt, opd)
-		walkStmt(n.Body, opt, opd)
+		w.opt["range"]++
+		w.walkExpr(n.X)
+		w.walkStmt(n.Body)
 	case *ast.CaseClause:
 		if n.List == nil {
-			opt["default"]++
+			w.opt["default"]++
 		} else {
 			for _, c := range n.List {
-				walkExpr(c, opt, opd)
+				w.walkExpr(c)
 			}
 		}
 		if n.Colon.IsValid() {
-			opt[":"]++
+			w.opt[":"]++
 		}
 		if n.Body != nil {
 			for _, b := range n.Body {
-				walkStmt(b, opt, opd)
+				w.walkStmt(b)
 			}
 		}
+	case *ast.CommClause:
+		if n.Comm == nil {
+			w.opt["default"]++
+		} else {
+			w.opt["case"]++
+			w.walkStmt(n.Comm)
+		}
+		if n.Colon.IsValid() {
+			w.opt[":"]++
+		}
+		for _, b := range n.Body {
+			w.walkStmt(b)
+		}
+	default:
+		recordUnhandledNode(n)
 	}
 }
 
-func walkSpec(spec ast.Spec, opt map[string]int, opd map[string]int) {
+func (w *funcWalker) walkSpec(spec ast.Spec) {
 	switch spec := spec.(type) {
 	case *ast.ValueSpec:
-		for _, n := range spec.Names {
-			walkExpr(n, opt, opd)
+		for i, n := range spec.Names {
+			w.walkExpr(n)
 			if spec.Type != nil {
-				walkExpr(spec.Type, opt, opd)
+				w.walkExpr(spec.Type)
 			}
 			if spec.Values != nil {
+				if i > 0 { // the branches of the values are counted once
+					w.uncounted++
+				}
 				for _, v := range spec.Values {
-					walkExpr(v, opt, opd)
+					w.walkExpr(v)
+				}
+				if i > 0 {
+					w.uncounted--
 				}
 			}
 		}
+	case *ast.TypeSpec: // local type declarations
+		w.walkExpr(spec.Name)
+		w.walkTypeParams(spec.TypeParams)
+		if spec.Assign.IsValid() {
+			w.opt["="]++
+		}
+		w.walkExpr(spec.Type)
+	default:
+		recordUnhandledNode(spec)
 	}
 }
 
-func walkExpr(exp ast.Expr, opt map[string]int, opd map[string]int) {
+// isIdentChain tells if the selector is made of identifiers only, like a.b.c
+func isIdentChain(exp *ast.SelectorExpr) bool {
+	switch x := exp.X.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isIdentChain(x)
+	}
+	return false
+}
+
+// identKey normalizes identifier name as Halstead map key
+func identKey(name string) string {
+	if HalstFoldCase {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+func (w *funcWalker) walkExpr(exp ast.Expr) {
 	switch exp := exp.(type) {
 	case *ast.ParenExpr:
-		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), opt, "()")
-		walkExpr(exp.X, opt, opd)
+		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), w.opt, "()")
+		w.walkExpr(exp.X)
 	case *ast.SelectorExpr:
-		walkExpr(exp.X, opt, opd)
-		walkExpr(exp.Sel, opt, opd)
+		if HalstFlattenSelectors && isIdentChain(exp) {
+			w.opd[identKey(types.ExprString(exp))]++
+			return
+		}
+		w.walkExpr(exp.X)
+		w.walkExpr(exp.Sel)
 	case *ast.IndexExpr:
-		walkExpr(exp.X, opt, opd)
-		appendValidSymb(exp.Lbrack.IsValid(), exp.Rbrack.IsValid(), opt, "{}")
-		walkExpr(exp.Index, opt, opd)
+		w.walkExpr(exp.X)
+		appendValidSymb(exp.Lbrack.IsValid(), exp.Rbrack.IsValid(), w.opt, "{}")
+		w.walkExpr(exp.Index)
+	case *ast.IndexListExpr: // generic instantiation with several type arguments
+		w.walkExpr(exp.X)
+		appendValidSymb(exp.Lbrack.IsValid(), exp.Rbrack.IsValid(), w.opt, "[]")
+		for _, e := range exp.Indices {
+			w.walkExpr(e)
+		}
 	case *ast.SliceExpr:
-		walkExpr(exp.X, opt, opd)
-		appendValidSymb(exp.Lbrack.IsValid(), exp.Rbrack.IsValid(), opt, "[]")
+		w.walkExpr(exp.X)
+		appendValidSymb(exp.Lbrack.IsValid(), exp.Rbrack.IsValid(), w.opt, "[]")
 		if exp.Low != nil {
-			walkExpr(exp.Low, opt, opd)
+			w.walkExpr(exp.Low)
 		}
 		if exp.High != nil {
-			walkExpr(exp.High, opt, opd)
+			w.walkExpr(exp.High)
 		}
 		if exp.Max != nil {
-			walkExpr(exp.Max, opt, opd)
+			w.walkExpr(exp.Max)
 		}
 	case *ast.TypeAssertExpr:
-		walkExpr(exp.X, opt, opd)
-		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), opt, "()")
+		w.walkExpr(exp.X)
+		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), w.opt, "()")
 		if exp.Type != nil {
-			walkExpr(exp.Type, opt, opd)
+			w.walkExpr(exp.Type)
+		} else { // x.(type) of a type switch
+			w.opt["type"]++
 		}
 	case *ast.CallExpr:
-		walkExpr(exp.Fun, opt, opd)
-		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), opt, "()")
+		w.walkExpr(exp.Fun)
+		appendValidSymb(exp.Lparen.IsValid(), exp.Rparen.IsValid(), w.opt, "()")
 		if exp.Ellipsis != 0 {
-			opt["..."]++
+			w.opt["..."]++
 		}
 		for _, a := range exp.Args {
-			walkExpr(a, opt, opd)
+			w.walkExpr(a)
 		}
 	case *ast.StarExpr:
 		if exp.Star.IsValid() {
-			opt["*"]++
+			w.opt["*"]++
 		}
-		walkExpr(exp.X, opt, opd)
+		w.walkExpr(exp.X)
 	case *ast.UnaryExpr:
+		if exp.Op == token.ARROW { // channel reading
+			w.branch(exp.OpPos, "<-", 1)
+		}
 		if exp.Op.IsOperator() {
-			opt[exp.Op.String()]++
+			w.opt[exp.Op.String()]++
 		} else {
-			opd[exp.Op.String()]++
+			w.opd[exp.Op.String()]++
 		}
-		walkExpr(exp.X, opt, opd)
+		w.walkExpr(exp.X)
 	case *ast.BinaryExpr:
-		walkExpr(exp.X, opt, opd)
-		opt[exp.Op.String()]++
-		walkExpr(exp.Y, opt, opd)
+		if (exp.Op == token.LAND || exp.Op == token.LOR) && CycloMode == CycloModeStrict {
+			w.branch(exp.OpPos, exp.Op.String(), 1)
+		}
+		w.walkExpr(exp.X)
+		w.opt[exp.Op.String()]++
+		w.walkExpr(exp.Y)
 	case *ast.KeyValueExpr:
-		walkExpr(exp.Key, opt, opd)
+		w.walkExpr(exp.Key)
 		if exp.Colon.IsValid() {
-			opt[":"]++
+			w.opt[":"]++
 		}
-		walkExpr(exp.Value, opt, opd)
+		w.walkExpr(exp.Value)
 	case *ast.BasicLit:
 		if exp.Kind.IsLiteral() {
-			opd[exp.Value]++
+			w.opd[literalOperand(exp)]++
 		} else {
-			opt[exp.Value]++
+			w.opt[exp.Value]++
 		}
 	case *ast.FuncLit:
-		walkExpr(exp.Type, opt, opd)
-		walkStmt(exp.Body, opt, opd)
+		if skipFuncLits() {
+			w.opt["func"]++
+			break
+		}
+		w.uncounted++ // closures have their own control flow
+		w.walkExpr(exp.Type)
+		w.walkStmt(exp.Body)
+		w.uncounted--
 	case *ast.CompositeLit:
-		appendValidSymb(exp.Lbrace.IsValid(), exp.Rbrace.IsValid(), opt, "{}")
+		appendValidSymb(exp.Lbrace.IsValid(), exp.Rbrace.IsValid(), w.opt, "{}")
 		if exp.Type != nil {
-			walkExpr(exp.Type, opt, opd)
+			w.walkExpr(exp.Type)
 		}
 		for _, e := range exp.Elts {
-			walkExpr(e, opt, opd)
+			w.walkExpr(e)
 		}
 	case *ast.Ident:
-		if exp.Obj == nil {
-			opt[exp.Name]++
+		if !isOperand(exp, w.info) {
+			w.opt[identKey(exp.Name)]++
 		} else {
-			opd[exp.Name]++
+			w.opd[identKey(exp.Name)]++
 		}
 	case *ast.Ellipsis:
 		if exp.Ellipsis.IsValid() {
-			opt["..."]++
+			w.opt["..."]++
 		}
 		if exp.Elt != nil {
-			walkExpr(exp.Elt, opt, opd)
+			w.walkExpr(exp.Elt)
 		}
 	case *ast.FuncType:
 		if exp.Func.IsValid() {
-			opt["func"]++
+			w.opt["func"]++
 		}
-		appendValidSymb(true, true, opt, "()")
+		w.walkTypeParams(exp.TypeParams)
+		appendValidSymb(true, true, w.opt, "()")
 		if exp.Params.List != nil {
 			for _, f := range exp.Params.List {
-				walkExpr(f.Type, opt, opd)
+				w.walkExpr(f.Type)
+			}
+		}
+	case *ast.InterfaceType: // inline constraints of type parameters
+		if exp.Interface.IsValid() {
+			w.opt["interface"]++
+		}
+		appendValidSymb(exp.Methods.Opening.IsValid(), exp.Methods.Closing.IsValid(), w.opt, "{}")
+		for _, f := range exp.Methods.List {
+			for _, n := range f.Names {
+				w.walkExpr(n)
+			}
+			w.walkExpr(f.Type)
+		}
+	case *ast.ArrayType:
+		appendValidSymb(exp.Lbrack.IsValid(), true, w.opt, "[]")
+		if exp.Len != nil {
+			w.walkExpr(exp.Len)
+		}
+		w.walkExpr(exp.Elt)
+	case *ast.MapType:
+		if exp.Map.IsValid() {
+			w.opt["map"]++
+		}
+		w.opt["[]"]++
+		w.walkExpr(exp.Key)
+		w.walkExpr(exp.Value)
+	case *ast.StructType:
+		if exp.Struct.IsValid() {
+			w.opt["struct"]++
+		}
+		appendValidSymb(exp.Fields.Opening.IsValid(), exp.Fields.Closing.IsValid(), w.opt, "{}")
+		for _, f := range exp.Fields.List {
+			for _, n := range f.Names {
+				w.opd[identKey(n.Name)]++
 			}
+			w.walkExpr(f.Type)
 		}
 	case *ast.ChanType:
 		if exp.Begin.IsValid() {
-			opt["chan"]++
+			w.opt["chan"]++
 		}
 		if exp.Arrow.IsValid() {
-			opt["<-"]++
+			w.opt["<-"]++
 		}
-		walkExpr(exp.Value, opt, opd)
+		w.walkExpr(exp.Value)
+	default:
+		recordUnhandledNode(exp)
+	}
+}
+
+// walkTypeParams counts the brackets of type parameters as operator, their names as operands
+// and walks their constraints, where ~ of an element like ~int is an operator
+func (w *funcWalker) walkTypeParams(fl *ast.FieldList) {
+	if fl == nil {
+		return
+	}
+	appendValidSymb(fl.Opening.IsValid(), fl.Closing.IsValid(), w.opt, "[]")
+	for _, f := range fl.List {
+		for _, n := range f.Names {
+			w.walkExpr(n)
+		}
+		w.walkExpr(f.Type)
+	}
+}
+
+// isOperand tells if the identifier denotes data: variables, fields, constants, nil and labels.
+// Functions, types, packages and builtins are operators.
+// Identifiers without type information fall back to their syntactic resolution,
+// where anything declared outside of the file, like an imported func, is an operator.
+func isOperand(id *ast.Ident, info *types.Info) bool {
+	var obj types.Object
+	if info != nil {
+		obj = info.ObjectOf(id)
+	}
+	switch obj.(type) {
+	case *types.Var, *types.Const, *types.Nil, *types.Label:
+		return true
+	case *types.Func, *types.TypeName, *types.PkgName, *types.Builtin:
+		return false
 	}
+	return id.Obj != nil
 }
 
 func appendValidSymb(lvalid bool, rvalid bool, opt map[string]int, symb string) {
@@ -445,12 +1093,15 @@ func appendValidSymb(lvalid bool, rvalid bool, opt map[string]int, symb string)
 	}
 }
 
-// calcMaintComp calculates the maintainability index
+// calcMaintIndex is the Maintainability index with the MICoefficients, on the MIScale
 // source: https://docs.microsoft.com/en-us/archive/blogs/codeanalysis/maintainability-index-range-and-meaning
 func calcMaintIndex(halstComp float64, cycloComp, loc int) int {
-	origVal := 171.0 - 5.2*logOf(halstComp) - 0.23*float64(cycloComp) - 16.2*logOf(float64(loc))
-	normVal := int(math.Max(0.0, origVal*100.0/171.0))
-	return normVal
+	return scaleMaintIndex(origMaintIndex(halstComp, cycloComp, loc))
+}
+
+func origMaintIndex(halstComp float64, cycloComp, loc int) float64 {
+	a, b, c, d := MICoefficients[0], MICoefficients[1], MICoefficients[2], MICoefficients[3]
+	return a - b*logOf(halstComp) - c*float64(cycloComp) - d*logOf(float64(loc))
 }
 
 func logOf(val float64) float64 {
@@ -471,39 +1122,6 @@ func log2Of(val float64) float64 {
 	}
 }
 
-// calcCycloComp calculates the Cyclomatic complexity
-func calcCycloComp(fd *ast.FuncDecl) int {
-	comp := 1
-	var v ast.Visitor
-	v = branchVisitor(func(n ast.Node) (w ast.Visitor) {
-		switch n := n.(type) {
-		case *ast.GoStmt: // subroutines are double complexity
-			comp += 2
-		case *ast.SendStmt: // writing to channels
-			comp++
-		case *ast.UnaryExpr:
-			if n.Op == token.ARROW { // channel reading
-				comp++
-			}
-		case *ast.IfStmt:
-			comp++
-			if _, ok := n.Else.(*ast.BlockStmt); ok { // include final else
-				comp++
-			}
-		case *ast.ForStmt, *ast.RangeStmt, *ast.SelectStmt, *ast.SwitchStmt:
-			comp++
-		case *ast.BinaryExpr:
-			if n.Op == token.LAND || n.Op == token.LOR {
-				comp++
-			}
-		}
-		return v
-	})
-	ast.Walk(v, fd)
-
-	return comp
-}
-
 func countVarsLOC(fs *token.FileSet, n *ast.FuncDecl) int {
 	loc := 0
 	var v ast.Visitor
@@ -522,6 +1140,77 @@ func countVarsLOC(fs *token.FileSet, n *ast.FuncDecl) int {
 	return loc
 }
 
+// countReturns counts return statements of a function, excluding those of nested function literals
+func countReturns(n *ast.FuncDecl) int {
+	cnt := 0
+	var v ast.Visitor
+	v = branchVisitor(func(nn ast.Node) ast.Visitor {
+		switch nn.(type) {
+		case *ast.FuncLit: // returns belong to the closure
+			return nil
+		case *ast.ReturnStmt:
+			cnt++
+		}
+		return v
+	})
+	ast.Walk(v, n)
+	return cnt
+}
+
+// countStmts counts the statements of a function, a formatting-independent size.
+// Blocks and labels only wrap other statements and are not counted,
+// neither are the statements of nested function literals.
+func countStmts(n *ast.FuncDecl) int {
+	cnt := 0
+	var v ast.Visitor
+	v = branchVisitor(func(nn ast.Node) ast.Visitor {
+		switch nnn := nn.(type) {
+		case *ast.FuncLit:
+			return nil
+		case *ast.BlockStmt, *ast.LabeledStmt:
+		case *ast.EmptyStmt:
+			if !nnn.Implicit {
+				cnt++
+			}
+		case ast.Stmt:
+			cnt++
+		}
+		return v
+	})
+	ast.Walk(v, n)
+	return cnt
+}
+
+// countSLOC counts source lines of a function, i.e. lines having any code token.
+// Blank lines and lines with only comments are not counted.
+func countSLOC(fs *token.FileSet, n ast.Node) int {
+	f := fs.File(n.Pos())
+	lines := map[int]bool{}
+	ast.Inspect(n, func(nn ast.Node) bool {
+		switch nn := nn.(type) {
+		case nil:
+			return false
+		case *ast.CommentGroup, *ast.Comment:
+			return false
+		case *ast.FuncLit:
+			lines[f.Line(nn.Pos())] = true
+			if skipFuncLits() { // only the line it starts on belongs to the enclosing function
+				return false
+			}
+			lines[f.Line(nn.End()-1)] = true
+		case *ast.BasicLit: // raw strings can span lines
+			for l := f.Line(nn.Pos()); l <= f.Line(nn.End()); l++ {
+				lines[l] = true
+			}
+		default:
+			lines[f.Line(nn.Pos())] = true
+			lines[f.Line(nn.End()-1)] = true
+		}
+		return true
+	})
+	return len(lines)
+}
+
 // counts lines of a function
 func countLOC(fs *token.FileSet, n ast.Node) int {
 	f := fs.File(n.Pos())
@@ -530,10 +1219,16 @@ func countLOC(fs *token.FileSet, n ast.Node) int {
 	return endLine - startLine + 1
 }
 
+// reportFuncStats reports the function as selected by -report
 func reportFuncStats(reportFnc func(msg string, args ...interface{}), stats FuncStatsType) {
-	if flag.Lookup("test.v") != nil {
-		// Only when `go test`
-		reportFnc("Cyclomatic complexity: %d, Halstead difficulty: %0.3f, volume: %0.3f", stats.CyclomaticComplexity, stats.HalsbreadDifficulty, stats.HalsbreadVolume)
+	switch Report {
+	case ReportNone:
+		return
+	case ReportAll:
+		reportFnc("Cyclomatic complexity: %d, Halstead difficulty: %0.3f, volume: %0.3f, Cognitive complexity: %d", stats.CyclomaticComplexity, stats.HalsteadDifficulty, stats.HalsteadVolume, stats.CognitiveComplexity)
+		return
+	}
+	if stats.Suppressed || stats.Unchanged || stats.Unexported {
 		return
 	}
 	msg := ToDiagnosticMsg(stats)
@@ -542,12 +1237,101 @@ func reportFuncStats(reportFnc func(msg string, args ...interface{}), stats Func
 	}
 }
 
+// Violations returns the names of the rules the function violates, in the precedence order of ToDiagnosticMsg:
+// cyclo, maint, cognitive, params, results, returns, statements, loc, effort, abc, fanout, locals, concurrency, defers, nesting,
+// recursion, score.
+// A function is reported once, by its first violation, while each of its violations counts toward its rule.
+// Suppressed, unchanged and unexported functions have none.
+func Violations(stats FuncStatsType) []string {
+	rules := []string{}
+	if stats.Suppressed || stats.Unchanged || stats.Unexported {
+		return rules
+	}
+	for _, r := range ruleFlags(&stats) {
+		if *r.violated {
+			rules = append(rules, r.name)
+		}
+	}
+	return rules
+}
+
+// ruleFlag is the violation flag of a rule
+type ruleFlag struct {
+	name     string
+	violated *bool
+}
+
+// ruleFlags returns the violation flags of the function by rule, in the order of the report
+func ruleFlags(stats *FuncStatsType) []ruleFlag {
+	return []ruleFlag{
+		{"cyclo", &stats.IsTooComplex},
+		{"maint", &stats.IsNotMaintenable},
+		{"cognitive", &stats.IsTooCognitive},
+		{"params", &stats.IsTooManyParams},
+		{"results", &stats.IsTooManyResults},
+		{"returns", &stats.IsTooManyReturns},
+		{"statements", &stats.IsTooManyStatements},
+		{"loc", &stats.IsTooLong},
+		{"effort", &stats.IsTooMuchEffort},
+		{"abc", &stats.IsTooBigABC},
+		{"fanout", &stats.IsTooMuchFanOut},
+		{"locals", &stats.IsTooManyLocals},
+		{"concurrency", &stats.IsTooConcurrent},
+		{"defers", &stats.IsTooManyDefers},
+		{"nesting", &stats.IsTooNested},
+		{"recursion", &stats.IsFlaggedRecursive},
+		{"score", &stats.IsTooRisky},
+	}
+}
+
 // ToDiagnosticMsg is used to form diagnostic message for not-good functions
 func ToDiagnosticMsg(stats FuncStatsType) (msg string) {
+	// the recursion is noted after the violation, unless it is the violation
+	recursionNote := stats.Recursive
 	if stats.IsTooComplex {
 		msg = fmt.Sprintf("func %s seems to be complex (cyclomatic complexity=%d)", stats.FunctionName, stats.CyclomaticComplexity)
 	} else if stats.IsNotMaintenable {
 		msg = fmt.Sprintf("func %s seems to have low maintainability (maintainability index=%d)", stats.FunctionName, stats.MaintenabilityIndex)
+	} else if stats.IsTooCognitive {
+		msg = fmt.Sprintf("func %s seems to be hard to understand (cognitive complexity=%d)", stats.FunctionName, stats.CognitiveComplexity)
+	} else if stats.IsTooManyParams {
+		msg = fmt.Sprintf("func %s seems to have too many parameters (parameters=%d)", stats.FunctionName, stats.Params)
+	} else if stats.IsTooManyResults {
+		msg = fmt.Sprintf("func %s seems to have too many results (results=%d)", stats.FunctionName, stats.Results)
+	} else if stats.IsTooManyReturns {
+		msg = fmt.Sprintf("func %s seems to have too many exit points (return statements=%d)", stats.FunctionName, stats.Returns)
+	} else if stats.IsTooManyStatements {
+		msg = fmt.Sprintf("func %s seems to be too long (statements=%d)", stats.FunctionName, stats.Statements)
+	} else if stats.IsTooLong {
+		msg = fmt.Sprintf("func %s seems to be too long (source lines of code=%d)", stats.FunctionName, stats.SLOC)
+	} else if stats.IsTooMuchEffort {
+		msg = fmt.Sprintf("func %s seems to take much effort (halstead effort=%0.3f)", stats.FunctionName, stats.HalsteadEffort)
+	} else if stats.IsTooBigABC {
+		msg = fmt.Sprintf("func %s seems to do too much (abc size=%0.1f)", stats.FunctionName, stats.ABCSize)
+	} else if stats.IsTooMuchFanOut {
+		msg = fmt.Sprintf("func %s seems to depend on too many functions (fan-out=%d)", stats.FunctionName, stats.FanOut)
+	} else if stats.IsTooManyLocals {
+		msg = fmt.Sprintf("func %s seems to juggle too many variables (local variables=%d)", stats.FunctionName, stats.Locals)
+	} else if stats.IsTooConcurrent {
+		msg = fmt.Sprintf("func %s seems to need a careful concurrency review (concurrency score=%d)", stats.FunctionName, stats.ConcurrencyScore)
+	} else if stats.IsTooManyDefers {
+		msg = fmt.Sprintf("func %s seems to juggle too many resources (defer statements=%d)", stats.FunctionName, stats.Defers)
+	} else if stats.IsTooNested {
+		msg = fmt.Sprintf("func %s seems to be deeply nested (nesting depth=%d)", stats.FunctionName, stats.MaxNesting)
+	} else if stats.IsFlaggedRecursive {
+		msg = fmt.Sprintf("func %s seems to need a termination review (%s)", stats.FunctionName, RecursionNote(stats))
+		recursionNote = false
+	} else if stats.IsTooRisky {
+		msg = fmt.Sprintf("func %s seems to be risky (risk score=%0.3f)", stats.FunctionName, stats.Score)
+	}
+	if msg != "" && stats.Grade != "" {
+		msg += ", grade " + stats.Grade
+	}
+	if msg != "" && ShowLines {
+		msg += fmt.Sprintf(", lines %d–%d", stats.Line, stats.EndLine)
+	}
+	if msg != "" && recursionNote {
+		msg += ", recursive: " + RecursionNote(stats)
 	}
 	return
 }
@@ -1,12 +1,1842 @@
 package complexity
 
 import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
+// TestMain reports every function with its metrics, which the testdata fixtures assert with want comments
+func TestMain(m *testing.M) {
+	Report = ReportAll
+	os.Exit(m.Run())
+}
+
 // TestAnalyzer is a test for Analyzer.
 func TestAnalyzer(t *testing.T) {
-	analysistest.Run(t, analysistest.TestData(), Analyzer, []string{"a", "halstead"}...)
+	analysistest.Run(t, analysistest.TestData(), Analyzer, []string{"a", "halstead", "cognitive", "typeswitch", "generics", "composite"}...)
+}
+
+// TestAnalyzerFlags checks options are settable via Analyzer.Flags.
+func TestAnalyzerFlags(t *testing.T) {
+	for _, name := range []string{"cycloover", "maintunder"} {
+		defer Analyzer.Flags.Set(name, Analyzer.Flags.Lookup(name).Value.String())
+	}
+
+	if err := Analyzer.Flags.Set("cycloover", "15"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Analyzer.Flags.Set("maintunder", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if CycloOver != 15 || MaintUnder != 30 {
+		t.Errorf("expected cycloover=15 maintunder=30, got %d %d", CycloOver, MaintUnder)
+	}
+}
+
+func parseFuncDecl(t *testing.T, src string) (*token.FileSet, *ast.FuncDecl) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "snippet.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			return fset, fd
+		}
+	}
+	t.Fatal("no function declaration in snippet")
+	return nil, nil
+}
+
+// TestFuncStats exercises the exported per-function API on parsed snippets.
+func TestFuncStats(t *testing.T) {
+	fset, fd := parseFuncDecl(t, `package p
+
+func f(a, b int) int {
+	if a > b && b > 0 {
+		return a
+	}
+	for i := 0; i < b; i++ {
+		a += i
+	}
+	return b
+}
+`)
+	stats := FuncStats(fset, fd)
+	assert.Equal(t, "snippet.go", stats.Filename)
+	assert.Equal(t, 3, stats.Line)
+	assert.Equal(t, "f", stats.FunctionName)
+	assert.Equal(t, 9, stats.LOC)
+	assert.Equal(t, 4, stats.CyclomaticComplexity)
+	assert.Equal(t, stats.CyclomaticComplexity, CyclomaticComplexity(fd))
+
+	difficulty, volume := HalsteadMetrics(fd)
+	assert.Equal(t, difficulty, stats.HalsteadDifficulty)
+	assert.Equal(t, volume, stats.HalsteadVolume)
+	assert.Equal(t, stats.HalsteadDifficulty, stats.HalsbreadDifficulty)
+	assert.Equal(t, stats.HalsteadVolume, stats.HalsbreadVolume)
+	assert.Greater(t, volume, 0.0)
+	assert.Equal(t, MaintainabilityIndex(volume, 4, 9), stats.MaintenabilityIndex)
+	assert.False(t, stats.IsTooComplex)
+}
+
+func TestMaintainabilityIndex(t *testing.T) {
+	assert.Equal(t, 100, MaintainabilityIndex(0, 0, 0))
+	assert.Equal(t, 0, MaintainabilityIndex(1e9, 100, 10000))
+	assert.Equal(t, 64, MaintainabilityIndex(100, 4, 9))
+}
+
+// TestAnalyzerResult checks all functions are part of the result, regardless of thresholds.
+func TestAnalyzerResult(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+	assert.Len(t, results, 1)
+	res, ok := results[0].Result.(*Result)
+	assert.True(t, ok)
+	assert.Len(t, res.Functions, 6)
+	assert.Equal(t, "f0", res.Functions[0].FunctionName)
+	assert.True(t, res.Functions[0].Pos.IsValid())
+}
+
+// quietT ignores analysistest expectations, for tests inspecting only the result
+type quietT struct{}
+
+func (quietT) Errorf(format string, args ...interface{}) {}
+
+func runResult(t *testing.T, pkg string) *Result {
+	t.Helper()
+	results := analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, pkg)
+	res, ok := results[0].Result.(*Result)
+	if !ok {
+		t.Fatalf("unexpected result type %T", results[0].Result)
+	}
+	return res
+}
+
+func funcNames(res *Result, cond func(FuncResult) bool) []string {
+	names := []string{}
+	for _, f := range res.Functions {
+		if cond(f) {
+			names = append(names, f.FunctionName)
+		}
+	}
+	return names
+}
+
+func TestGeneratedRegions(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "genregions")[0].Result.(*Result)
+	assert.Equal(t, []string{"gen1", "gen2"}, funcNames(res, func(f FuncResult) bool { return f.Generated }))
+
+	defer Analyzer.Flags.Set("skipgenregions", "false")
+	assert.NoError(t, Analyzer.Flags.Set("skipgenregions", "true"))
+	res = runResult(t, "genregions")
+	assert.Equal(t, []string{"hand", "hand2"}, funcNames(res, func(FuncResult) bool { return true }))
+}
+
+func TestGeneratedFiles(t *testing.T) {
+	// the marker counts only before the package clause
+	res := runResult(t, "generatedfile")
+	assert.Equal(t, []string{"Mix"}, funcNames(res, func(FuncResult) bool { return true }))
+	handSLOC := res.SLOC
+
+	defer Analyzer.Flags.Set("include-generated", "false")
+	assert.NoError(t, Analyzer.Flags.Set("include-generated", "true"))
+	res = runResult(t, "generatedfile")
+	assert.Equal(t, []string{"(Color).String"}, funcNames(res, func(f FuncResult) bool { return f.Generated }))
+	assert.Equal(t, []string{"Mix", "(Color).String"}, funcNames(res, func(FuncResult) bool { return true }))
+	assert.Greater(t, res.SLOC, handSLOC)
+}
+
+func TestSkipTests(t *testing.T) {
+	names := func() map[string]bool {
+		all := map[string]bool{}
+		for _, r := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "skiptests") {
+			for _, f := range r.Result.(*Result).Functions {
+				all[f.FunctionName] = true
+			}
+		}
+		return all
+	}
+	assert.Equal(t, map[string]bool{"check": true, "TestCheck": true, "TestExternal": true}, names())
+
+	defer Analyzer.Flags.Set("skiptests", "false")
+	assert.NoError(t, Analyzer.Flags.Set("skiptests", "true"))
+	assert.Equal(t, map[string]bool{"check": true}, names())
+}
+
+func TestSuppression(t *testing.T) {
+	res := runResult(t, "suppress")
+	assert.Equal(t, []string{"dispatch", "decode"}, funcNames(res, func(f FuncResult) bool { return f.Suppressed }))
+	assert.Equal(t, "one case per opcode", res.Functions[0].SuppressReason)
+	assert.Equal(t, "generated from the opcode table", res.Functions[1].SuppressReason)
+	assert.Empty(t, Violations(FuncStatsType{Suppressed: true, IsTooComplex: true}))
+
+	for text, want := range map[string]bool{
+		"//nolint:complexity":         true,
+		"//nolint:lll,complexity":     true,
+		"//complexity:ignore":         true,
+		"//complexity:ignore because": true,
+		"//nolint:complexityx":        false,
+		"// nolint:complexity":        false,
+		"//nolint":                    false,
+		"//complexity:ignored":        false,
+	} {
+		got, _, _, _ := parseSuppression(text)
+		assert.Equal(t, want, got, text)
+	}
+}
+
+func TestIgnoredRules(t *testing.T) {
+	for text, want := range map[string][]string{
+		"//complexity:ignore cyclo":               {"cyclo"},
+		"//complexity:ignore cyclo,maint reason":  {"cyclo", "maint"},
+		"//complexity:ignore because":             nil,
+		"//complexity:ignore loop kept for speed": nil,
+		"//nolint:complexity cyclo":               nil,
+	} {
+		ok, rules, _, err := parseSuppression(text)
+		assert.NoError(t, err, text)
+		assert.True(t, ok, text)
+		assert.Equal(t, want, rules, text)
+	}
+	// mistyped rules suppress nothing, rather than the whole function
+	for text, want := range map[string]string{
+		"//complexity:ignore cylco":                 `unknown rule "cylco" in //complexity:ignore directive, did you mean cyclo?`,
+		"//complexity:ignore Maint reason":          `did you mean maint?`,
+		"//complexity:ignore cyclo,maintainability": `unknown rule "maintainability" in //complexity:ignore directive, valid are: cyclo, maint,`,
+		"//complexity:ignore cyclo,maintenance":     `unknown rule "maintenance"`,
+	} {
+		ok, rules, _, err := parseSuppression(text)
+		assert.False(t, ok, text)
+		assert.Nil(t, rules, text)
+		if assert.Error(t, err, text) {
+			assert.Contains(t, err.Error(), want, text)
+		}
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", "package p\n\n//complexity:ignore cylco\nfunc f() {}\n", parser.ParseComments)
+	assert.NoError(t, err)
+	warnings := []string{}
+	rules, _ := IgnoredRulesOf(fset, f, f.Decls[0].(*ast.FuncDecl), func(pos token.Pos, msg string) {
+		warnings = append(warnings, fmt.Sprintf("%d: %s", fset.Position(pos).Line, msg))
+	})
+	assert.Empty(t, rules)
+	assert.Equal(t, []string{`3: unknown rule "cylco" in //complexity:ignore directive, did you mean cyclo? It is ignored`}, warnings)
+	suppressed, _ := SuppressionOf(fset, f, f.Decls[0].(*ast.FuncDecl))
+	assert.False(t, suppressed)
+
+	defer Analyzer.Flags.Set("cycloover", "10")
+	assert.NoError(t, Analyzer.Flags.Set("cycloover", "3"))
+	res := runResult(t, "suppress")
+	assert.Empty(t, funcNames(res, func(f FuncResult) bool { return len(Violations(f.FuncStatsType)) > 0 }))
+	lookup, clamp := res.Functions[3], res.Functions[4]
+	assert.Equal(t, "lookup", lookup.FunctionName)
+	assert.False(t, lookup.Suppressed)
+	assert.False(t, lookup.IsTooComplex)
+	assert.Equal(t, []string{"cyclo", "maint"}, lookup.IgnoredRules)
+	assert.Equal(t, []string{"cyclo"}, lookup.IgnoredViolations)
+	assert.Equal(t, "one branch per protocol version", lookup.SuppressReason)
+
+	// the other rules are still reported
+	defer Analyzer.Flags.Set("stmtsover", "0")
+	assert.NoError(t, Analyzer.Flags.Set("stmtsover", "4"))
+	res = runResult(t, "suppress")
+	assert.Equal(t, []string{"statements"}, Violations(res.Functions[3].FuncStatsType))
+	assert.Equal(t, []string{"maint"}, clamp.IgnoredRules)
+	assert.Empty(t, clamp.IgnoredViolations, "the ignore is stale")
+}
+
+func TestThresholdDirectives(t *testing.T) {
+	defer Analyzer.Flags.Set("cycloover", "10")
+	assert.NoError(t, Analyzer.Flags.Set("cycloover", "2"))
+	results := analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "thresholds")
+	res := results[0].Result.(*Result)
+
+	loop, strict, typos := res.Functions[0], res.Functions[1], res.Functions[2]
+	assert.Equal(t, 40, loop.CycloOver)
+	assert.False(t, loop.IsTooComplex)
+	assert.Equal(t, 1, strict.CycloOver)
+	assert.Equal(t, 100, strict.MaintUnder)
+	assert.Equal(t, []string{"cyclo", "maint"}, Violations(strict.FuncStatsType))
+	assert.Equal(t, 2, typos.CycloOver)
+	assert.Equal(t, 20, typos.MaintUnder)
+
+	msgs := ""
+	for _, d := range results[0].Diagnostics {
+		msgs += d.Message + "\n"
+	}
+	assert.Contains(t, msgs, "thresholds.go:31: unknown threshold directive \"max-cylco\", valid are: max-cyclo, min-maint\n")
+	assert.Contains(t, msgs, "thresholds.go:32: invalid value \"ten\" of threshold directive min-maint, expected a non-negative integer\n")
+}
+
+func TestExclude(t *testing.T) {
+	all := func(FuncResult) bool { return true }
+	res := runResult(t, "exclude")
+	assert.Equal(t, []string{"(*Server).Close", "(Server).Name", "(*List[T]).Len", "Close", "TestLike", "(*Server).DeepCopy"}, funcNames(res, all))
+
+	_, fd := parseFuncDecl(t, "package p\nfunc (l *List[K, V]) Len() int { return 0 }")
+	assert.Equal(t, "p.(*List[K, V]).Len", QualifiedName("p", fd))
+	assert.Equal(t, "(*List[K, V]).Len", DisplayName(fd))
+	_, fd = parseFuncDecl(t, "package p\nfunc Len() int { return 0 }")
+	assert.Equal(t, "Len", DisplayName(fd))
+
+	defer func() { ExcludeFuncs, ExcludeFiles = nil, nil }()
+	assert.NoError(t, Analyzer.Flags.Set("exclude-file", `zz_generated_.*\.go$`))
+	assert.NoError(t, Analyzer.Flags.Set("exclude-func", `^exclude\.Test,\(\*Server\)\.Close$`))
+	assert.NoError(t, Analyzer.Flags.Set("exclude-func", `^exclude\.\(\*List\[T\]\)\.`))
+	assert.Equal(t, `^exclude\.Test,\(\*Server\)\.Close$,^exclude\.\(\*List\[T\]\)\.`, Analyzer.Flags.Lookup("exclude-func").Value.String())
+	res = runResult(t, "exclude")
+	assert.Equal(t, []string{"(Server).Name", "Close"}, funcNames(res, all))
+
+	assert.EqualError(t, Analyzer.Flags.Set("exclude-func", "a(b"), "invalid regexp \"a(b\": error parsing regexp: missing closing ): `a(b`")
+}
+
+// TestHalsteadNormalization shows how each normalization option shifts the volume of the same function.
+func TestHalsteadNormalization(t *testing.T) {
+	volume := func(flag, val string) (float64, string) {
+		defer Analyzer.Flags.Set(flag, Analyzer.Flags.Lookup(flag).DefValue)
+		assert.NoError(t, Analyzer.Flags.Set(flag, val))
+		res := runResult(t, "halstnorm")
+		return res.Functions[0].HalsteadVolume, res.HalsteadNormalization
+	}
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "halstnorm")[0].Result.(*Result)
+	assert.Equal(t, "flatten-selectors=false,merge-literals=true,fold-case=false,literals=value", res.HalsteadNormalization)
+	assert.InDelta(t, 110.361, res.Functions[0].HalsteadVolume, 0.001)
+
+	// p.X, p.Y and strings.ToUpper become single operands
+	v, norm := volume("halstflatten", "true")
+	assert.Equal(t, "flatten-selectors=true,merge-literals=true,fold-case=false,literals=value", norm)
+	assert.InDelta(t, 89.858, v, 0.001)
+	// each "x" literal is an own operand
+	v, _ = volume("halstmergelits", "false")
+	assert.InDelta(t, 114.694, v, 0.001)
+	// Total and total are one operand
+	v, _ = volume("halstfoldcase", "true")
+	assert.InDelta(t, 108.000, v, 0.001)
+}
+
+func TestCognitiveOver(t *testing.T) {
+	defer Analyzer.Flags.Set("cognitiveover", "0")
+	fset, fd := parseFuncDecl(t, `package p
+
+func f(a, b bool) {
+	if a {
+		if b {
+			println()
+		}
+	}
+}
+`)
+	stats := FuncStats(fset, fd)
+	assert.Equal(t, 3, stats.CognitiveComplexity)
+	assert.False(t, stats.IsTooCognitive)
+
+	assert.NoError(t, Analyzer.Flags.Set("cognitiveover", "2"))
+	stats = FuncStats(fset, fd)
+	assert.True(t, stats.IsTooCognitive)
+	assert.Equal(t, "func f seems to be hard to understand (cognitive complexity=3), grade B", ToDiagnosticMsg(stats))
+
+	assert.NoError(t, Analyzer.Flags.Set("cogover", "3"))
+	assert.Equal(t, 3, CognitiveOver)
+	assert.False(t, FuncStats(fset, fd).IsTooCognitive)
+}
+
+func TestAPIReach(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "apireach")[0].Result.(*Result)
+	type reach struct {
+		name        string
+		comp, calls int
+	}
+	got := []reach{}
+	for _, r := range res.APIReach {
+		got = append(got, reach{r.FunctionName, r.ReachedComplexity, r.ExternalCalls})
+	}
+	assert.Equal(t, []reach{
+		{"(*Server).ServeHTTP", 8, 2},
+		{"Ping", 5, 1},
+		{"Alone", 1, 2},
+	}, got)
+}
+
+func TestParamsAndResults(t *testing.T) {
+	defer Analyzer.Flags.Set("paramsover", "0")
+	defer Analyzer.Flags.Set("resultsover", "0")
+	assert.NoError(t, Analyzer.Flags.Set("paramsover", "3"))
+	assert.NoError(t, Analyzer.Flags.Set("resultsover", "2"))
+
+	tests := []struct {
+		src                           string
+		recv, params, results         int
+		tooManyParams, tooManyResults bool
+	}{
+		{"func f() {}", 0, 0, 0, false, false},
+		{"func f(a, b, c int, d string) {}", 0, 4, 0, true, false},
+		{"func (s *S) f(a int, rest ...string) (int, error) {}", 1, 2, 2, false, false},
+		{"func f(format string, args ...interface{}) (int, string, error) {}", 0, 2, 3, false, true},
+		{"func f(a, b int) (x, y int, err error) {}", 0, 2, 3, false, true},
+	}
+	for _, tt := range tests {
+		fset, fd := parseFuncDecl(t, "package p\n"+tt.src)
+		stats := FuncStats(fset, fd)
+		assert.Equal(t, tt.recv, stats.Receivers, tt.src)
+		assert.Equal(t, tt.params, stats.Params, tt.src)
+		assert.Equal(t, tt.results, stats.Results, tt.src)
+		assert.Equal(t, tt.tooManyParams, stats.IsTooManyParams, tt.src)
+		assert.Equal(t, tt.tooManyResults, stats.IsTooManyResults, tt.src)
+	}
+}
+
+func TestReturnsOver(t *testing.T) {
+	defer Analyzer.Flags.Set("returnsover", "0")
+	assert.NoError(t, Analyzer.Flags.Set("returnsover", "2"))
+
+	fset, fd := parseFuncDecl(t, `package p
+
+func f(n int) (res int, err error) {
+	cb := func() int {
+		return 1
+	}
+	if n < 0 {
+		return
+	}
+	for i := 0; i < n; i++ {
+		if i == 3 {
+			return cb(), nil
+		}
+	}
+	return n, nil
+}
+`)
+	stats := FuncStats(fset, fd)
+	assert.Equal(t, 3, stats.Returns)
+	assert.True(t, stats.IsTooManyReturns)
+	assert.Equal(t, "func f seems to have too many exit points (return statements=3), grade C", ToDiagnosticMsg(stats))
+}
+
+func TestHotspots(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "hot.go", `package p
+func Exported() {}
+func helper() {}
+func Clean() {}
+type t struct{}
+func (t) Method() {}
+`, 0)
+	assert.NoError(t, err)
+	decls := []*ast.FuncDecl{}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			decls = append(decls, fd)
+		}
+	}
+	funcs := []FuncResult{
+		{FuncStatsType: FuncStatsType{FunctionName: "Exported", CyclomaticComplexity: 15, IsTooComplex: true, MaintenabilityIndex: 40, FanIn: 1}},
+		{FuncStatsType: FuncStatsType{FunctionName: "helper", CyclomaticComplexity: 20, IsTooComplex: true, MaintenabilityIndex: 10, IsNotMaintenable: true, FanIn: 2}},
+		{FuncStatsType: FuncStatsType{FunctionName: "Clean", CyclomaticComplexity: 3, MaintenabilityIndex: 80, FanIn: 2}},
+		{FuncStatsType: FuncStatsType{FunctionName: "Method", CyclomaticComplexity: 11, IsTooComplex: true, MaintenabilityIndex: 60}},
+	}
+
+	hotspots := calcHotspots(decls, funcs)
+	assert.Len(t, hotspots, 3, "functions under all thresholds must never be hotspots")
+	assert.Equal(t, 1, hotspots[0].idx)
+	assert.InDelta(t, 1+2.0+0.5, hotspots[0].score, 0.001)
+	assert.Equal(t, []string{"fan-in=2", "cyclomatic complexity=20 over 10", "maintainability index=10 under 20"}, hotspots[0].factors)
+	assert.Equal(t, 0, hotspots[1].idx)
+	assert.Equal(t, []string{"exported", "fan-in=1", "cyclomatic complexity=15 over 10"}, hotspots[1].factors)
+	assert.Equal(t, 3, hotspots[2].idx, "method of unexported type is not exported")
+
+	defer Analyzer.Flags.Set("hotspotweights", HotspotWeights.String())
+	assert.NoError(t, Analyzer.Flags.Set("hotspotweights", "exported=10"))
+	hotspots = calcHotspots(decls, funcs)
+	assert.Equal(t, 0, hotspots[0].idx)
+	assert.Error(t, Analyzer.Flags.Set("hotspotweights", "size=1"))
+	assert.Error(t, Analyzer.Flags.Set("hotspotweights", "cyclo=-1"))
+}
+
+// TestHalsteadCoverage checks the Halstead walker handles all AST node kinds of the fixture corpus.
+func TestHalsteadCoverage(t *testing.T) {
+	defer Analyzer.Flags.Set("debugcoverage", "false")
+	assert.NoError(t, Analyzer.Flags.Set("debugcoverage", "true"))
+	ResetUnhandledNodes()
+	analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "./...")
+	assert.Empty(t, UnhandledNodesReport(UnhandledNodes()))
+}
+
+func TestStatements(t *testing.T) {
+	defer Analyzer.Flags.Set("stmtsover", "0")
+	defer Analyzer.Flags.Set("mi-use-statements", "false")
+	fset, fd := parseFuncDecl(t, `package p
+
+func f(items []string) {
+	cb := func(s string) {
+		println(s)
+		println(s)
+	}
+loop:
+	for _, s := range items {
+		if s == "" {
+			continue loop
+		}
+		cb(
+			s,
+		)
+	}
+}
+`)
+	stats := FuncStats(fset, fd)
+	// cb :=, for range, if, continue, cb()
+	assert.Equal(t, 5, stats.Statements)
+	assert.False(t, stats.IsTooManyStatements)
+
+	assert.NoError(t, Analyzer.Flags.Set("stmtsover", "4"))
+	assert.NoError(t, Analyzer.Flags.Set("mi-use-statements", "true"))
+	byStmts := FuncStats(fset, fd)
+	assert.True(t, byStmts.IsTooManyStatements)
+	assert.Equal(t, MaintainabilityIndex(stats.HalsteadVolume, stats.CyclomaticComplexity, 5), byStmts.MaintenabilityIndex)
+	assert.Greater(t, byStmts.MaintenabilityIndex, stats.MaintenabilityIndex)
+
+	assert.NoError(t, Analyzer.Flags.Set("stmtover", "5"))
+	assert.Equal(t, 5, StmtsOver)
+	assert.False(t, FuncStats(fset, fd).IsTooManyStatements)
+}
+
+func TestSLOC(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "sloc")[0].Result.(*Result)
+	documented, terse, multiline := res.Functions[0], res.Functions[1], res.Functions[2]
+	assert.Equal(t, 29, documented.LOC)
+	assert.Equal(t, 10, documented.SLOC)
+	assert.Equal(t, 10, terse.LOC)
+	assert.Equal(t, terse.LOC, terse.SLOC)
+	assert.Equal(t, terse.MaintenabilityIndex, documented.MaintenabilityIndex)
+	assert.Greater(t, documented.MaintenabilityIndex, MaintainabilityIndex(documented.HalsteadVolume, documented.CyclomaticComplexity, documented.LOC))
+	assert.Equal(t, 7, multiline.SLOC, "blank line of the raw string is code")
+}
+
+func TestHalsteadEffort(t *testing.T) {
+	defer Analyzer.Flags.Set("effortover", "0")
+	fset, fd := parseFuncDecl(t, `package p
+
+func f() {
+	print("Hello, World")
+}
+`)
+	// operators: func, f, (), {}, print, () => n1=5, N1=6
+	// operands: "Hello, World" => n2=1, N2=1
+	// volume = 7 * log2(6) = 18.095, difficulty = 5/2 * 1/1 = 2.5
+	stats := FuncStats(fset, fd)
+	assert.InDelta(t, 18.095, stats.HalsteadVolume, 0.001)
+	assert.InDelta(t, 2.5, stats.HalsteadDifficulty, 0.001)
+	assert.InDelta(t, 45.237, stats.HalsteadEffort, 0.001)
+	assert.InDelta(t, 45.237/18/3600, stats.TimeToCode, 0.000001)
+	assert.InDelta(t, 0.006, stats.HalsteadBugs, 0.001)
+	assert.False(t, stats.IsTooMuchEffort)
+
+	assert.NoError(t, Analyzer.Flags.Set("effortover", "45"))
+	assert.True(t, FuncStats(fset, fd).IsTooMuchEffort)
+	assert.NoError(t, Analyzer.Flags.Set("halsteffortover", "46"))
+	assert.Equal(t, 46.0, EffortOver)
+	assert.False(t, FuncStats(fset, fd).IsTooMuchEffort)
+
+	fset, fd = parseFuncDecl(t, "package p\nfunc f() {}")
+	stats = FuncStats(fset, fd)
+	assert.Equal(t, 0.0, stats.HalsteadEffort)
+	assert.Equal(t, 0.0, stats.TimeToCode)
+	assert.InDelta(t, stats.HalsteadVolume/3000, stats.HalsteadBugs, 0.000001)
+}
+
+func TestABCMetrics(t *testing.T) {
+	_, fd := parseFuncDecl(t, `package p
+
+func f(xs []int) (n int) {
+	defer println("done")
+	a, b := 1, 2
+	n += a
+	b++
+	for i := 0; i < len(xs); i++ {
+		if !(xs[i] > 0 && xs[i] < 10) {
+			continue
+		}
+		switch xs[i] {
+		case 1:
+			n = g(n)
+		default:
+		}
+	}
+	go func() {
+		x := 1
+		x++
+		if x > 0 || x < 0 {
+			println(x)
+		}
+	}()
+	return
+}
+`)
+	a, b, c, size := ABCMetrics(fd)
+	// a, b := ...; n += a; b++; i := 0; i++; n = g(n)
+	assert.Equal(t, 7, a)
+	// defer, println, len, g, go, func(){}()
+	assert.Equal(t, 6, b)
+	// for cond, if, !, &&, case 1
+	assert.Equal(t, 5, c)
+	assert.Equal(t, 10.5, size)
+
+	_, fd = parseFuncDecl(t, "package p\nfunc f() {}")
+	a, b, c, size = ABCMetrics(fd)
+	assert.Equal(t, []float64{0, 0, 0, 0}, []float64{float64(a), float64(b), float64(c), size})
+}
+
+func TestFuncLitUnits(t *testing.T) {
+	byName := func(res *Result) map[string]FuncStatsType {
+		m := map[string]FuncStatsType{}
+		for _, f := range res.Functions {
+			m[f.FunctionName] = f.FuncStatsType
+		}
+		return m
+	}
+
+	res := runResult(t, "funclit")
+	assert.Equal(t, []string{"spawn", "(*testingT).Run", "tableDriven"}, funcNames(res, func(FuncResult) bool { return true }))
+	inclusive := byName(res)
+
+	defer Analyzer.Flags.Set("funclit", "false")
+	assert.NoError(t, Analyzer.Flags.Set("funclit", "true"))
+	res = runResult(t, "funclit")
+	assert.Equal(t, []string{"spawn", "spawn$1", "(*testingT).Run", "tableDriven", "tableDriven$1", "tableDriven$1$1", "glob$1"},
+		funcNames(res, func(FuncResult) bool { return true }))
+	units := byName(res)
+
+	// goroutine closure
+	assert.Equal(t, 2, units["spawn$1"].CyclomaticComplexity)
+	assert.Equal(t, 1, units["spawn$1"].Params)
+	assert.Equal(t, 1, units["spawn$1"].Returns)
+	assert.Equal(t, 18, units["spawn$1"].Line)
+	assert.Equal(t, 4, units["spawn"].CyclomaticComplexity) // for, go
+	assert.Equal(t, 4, inclusive["spawn"].CyclomaticComplexity)
+	assert.Equal(t, inclusive["spawn"].SLOC-7, units["spawn"].SLOC) // the literal's first and last lines stay
+	assert.Less(t, units["spawn"].HalsteadVolume, inclusive["spawn"].HalsteadVolume)
+
+	// table-driven test body, with a literal nested in it
+	assert.Equal(t, 3, units["tableDriven$1"].CyclomaticComplexity)
+	assert.Equal(t, 1, units["tableDriven$1$1"].CyclomaticComplexity)
+	assert.Equal(t, 2, units["tableDriven"].CyclomaticComplexity)
+	assert.Equal(t, 1, units["tableDriven"].CognitiveComplexity)     // for
+	assert.Equal(t, 2, units["tableDriven$1"].CognitiveComplexity)   // if, &&
+	assert.Equal(t, 5, inclusive["tableDriven"].CognitiveComplexity) // for, nested if, &&
+
+	// package level literal
+	assert.Equal(t, 8, units["glob$1"].Line)
+	assert.Equal(t, 3, units["glob$1"].SLOC)
+
+	// literals counted into the enclosing function too, on request
+	defer Analyzer.Flags.Set("funclitinparent", "false")
+	assert.NoError(t, Analyzer.Flags.Set("funclitinparent", "true"))
+	res = runResult(t, "funclit")
+	assert.Equal(t, inclusive["spawn"], byName(res)["spawn"])
+	assert.Equal(t, units["spawn$1"], byName(res)["spawn$1"])
+}
+
+func TestViolations(t *testing.T) {
+	stats := FuncStatsType{FunctionName: "f", CyclomaticComplexity: 12, MaintenabilityIndex: 5, IsTooComplex: true, IsNotMaintenable: true}
+	assert.Equal(t, []string{"cyclo", "maint"}, Violations(stats))
+	assert.Equal(t, "func f seems to be complex (cyclomatic complexity=12)", ToDiagnosticMsg(stats))
+	assert.Empty(t, Violations(FuncStatsType{}))
+}
+
+func TestCyclomaticComplexityExcludesClosures(t *testing.T) {
+	_, plain := parseFuncDecl(t, `package p
+
+func f(xs []int) {
+	for _, x := range xs {
+		println(x)
+	}
+}
+`)
+	_, withClosure := parseFuncDecl(t, `package p
+
+func f(xs []int) {
+	cb := func(x int) {
+		if x > 0 && x < 10 {
+			println(x)
+		} else {
+			<-make(chan int)
+		}
+	}
+	for _, x := range xs {
+		cb(x)
+	}
+}
+`)
+	assert.Equal(t, 2, CyclomaticComplexity(plain))
+	assert.Equal(t, CyclomaticComplexity(plain), CyclomaticComplexity(withClosure))
+}
+
+func TestCyclomaticComplexityDefaultClause(t *testing.T) {
+	src := `package p
+
+func f(x int, c chan int) {
+	switch x {
+	case 1:
+		println(1)
+	case 2:
+		println(2)
+	%s
+	}
+	select {
+	case <-c:
+	%s
+	}
+}
+`
+	_, withoutDefault := parseFuncDecl(t, fmt.Sprintf(src, "", ""))
+	_, withDefault := parseFuncDecl(t, fmt.Sprintf(src, "default:", "default:"))
+	// switch and select count once each, with or without the default clause; chan read +1
+	assert.Equal(t, 4, CyclomaticComplexity(withoutDefault))
+	assert.Equal(t, 4, CyclomaticComplexity(withDefault))
+}
+
+func TestViolationsPerKLOC(t *testing.T) {
+	assert.Equal(t, 2.0, ViolationsPerKLOCOf(3, 1500))
+	assert.Equal(t, 0.0, ViolationsPerKLOCOf(3, 0))
+
+	densityFindings := func() []string {
+		msgs := []string{}
+		for _, r := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "a") {
+			for _, d := range r.Diagnostics {
+				if d.Category == DensityCategory {
+					msgs = append(msgs, d.Message)
+				}
+			}
+		}
+		return msgs
+	}
+	defer Analyzer.Flags.Set("cycloover", "10")
+	defer Analyzer.Flags.Set("violationsperkloc", "0")
+	defer Analyzer.Flags.Set("densityminsloc", "500")
+	assert.NoError(t, Analyzer.Flags.Set("cycloover", "5"))
+
+	res := runResult(t, "a")
+	assert.Equal(t, 1, res.Violations)
+	assert.Equal(t, 53, res.SLOC)
+	assert.Empty(t, densityFindings())
+
+	// small packages are exempt
+	assert.NoError(t, Analyzer.Flags.Set("violationsperkloc", "15"))
+	assert.Empty(t, densityFindings())
+
+	assert.NoError(t, Analyzer.Flags.Set("densityminsloc", "50"))
+	msgs := densityFindings()
+	assert.Len(t, msgs, 1)
+	assert.Regexp(t, `a.go:1: package a has 18.87 violations per KLOC \(1 violations in 53 source lines\), over 15$`, msgs[0])
+
+	assert.NoError(t, Analyzer.Flags.Set("violationsperkloc", "20"))
+	assert.Empty(t, densityFindings())
+}
+
+func TestPackageMaintainabilityIndex(t *testing.T) {
+	assert.Equal(t, 100, PackageMaintainabilityIndex(nil))
+	funcs := []FuncResult{
+		{FuncStatsType: FuncStatsType{FunctionName: "f", HalsteadVolume: 100, CyclomaticComplexity: 3, SLOC: 10}},
+		{FuncStatsType: FuncStatsType{FunctionName: "g", HalsteadVolume: 300, CyclomaticComplexity: 5, SLOC: 30}},
+	}
+	assert.Equal(t, MaintainabilityIndex(400, 8, 40), PackageMaintainabilityIndex(funcs))
+
+	pkgMaintFindings := func() []string {
+		msgs := []string{}
+		for _, r := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "a") {
+			for _, d := range r.Diagnostics {
+				if d.Category == PkgMaintCategory {
+					msgs = append(msgs, d.Message)
+				}
+			}
+		}
+		return msgs
+	}
+	defer Analyzer.Flags.Set("pkgmaintunder", "0")
+	res := runResult(t, "a")
+	assert.Equal(t, 42, res.MaintainabilityIndex)
+	assert.Empty(t, pkgMaintFindings())
+
+	assert.NoError(t, Analyzer.Flags.Set("pkgmaintunder", "50"))
+	msgs := pkgMaintFindings()
+	assert.Len(t, msgs, 1)
+	assert.Regexp(t, `a.go:1: package a seems to have low maintainability \(maintainability index=42\), under 50$`, msgs[0])
+
+	// excluded functions do not contribute
+	defer func() { ExcludeFuncs = nil }()
+	assert.NoError(t, Analyzer.Flags.Set("exclude-func", "."))
+	assert.Equal(t, 100, runResult(t, "a").MaintainabilityIndex)
+}
+
+func TestHalsteadTypeSwitch(t *testing.T) {
+	res := runResult(t, "typeswitch")
+	describe := res.Functions[0]
+	assert.Equal(t, "describe", describe.FunctionName)
+	// the type switch header, clauses and bodies are all counted
+	assert.InDelta(t, 197.418, describe.HalsteadVolume, 0.001)
+	assert.Equal(t, 58, describe.MaintenabilityIndex)
+}
+
+func TestHalsteadGenerics(t *testing.T) {
+	_, plain := parseFuncDecl(t, "package p\nfunc f[T int](x T) T { return x }")
+	_, approx := parseFuncDecl(t, "package p\nfunc f[T ~int](x T) T { return x }")
+	_, noTypeParams := parseFuncDecl(t, "package p\nfunc f(x int) int { return x }")
+	_, plainVolume := HalsteadMetrics(plain)
+	_, approxVolume := HalsteadMetrics(approx)
+	_, noTypeParamsVolume := HalsteadMetrics(noTypeParams)
+	assert.Greater(t, plainVolume, noTypeParamsVolume) // [T int]
+	assert.Greater(t, approxVolume, plainVolume)       // ~
+
+	_, call := parseFuncDecl(t, "package p\nfunc f() { _ = Map[int, string](nil, nil) }")
+	ResetUnhandledNodes()
+	defer Analyzer.Flags.Set("debugcoverage", "false")
+	assert.NoError(t, Analyzer.Flags.Set("debugcoverage", "true"))
+	_, volume := HalsteadMetrics(call)
+	assert.Empty(t, UnhandledNodes())
+	assert.Greater(t, volume, 0.0)
+}
+
+func TestGenSource(t *testing.T) {
+	sources := func() map[string]string {
+		m := map[string]string{}
+		for _, f := range runResult(t, "gensource").Functions {
+			m[f.FunctionName] = f.GenSource
+		}
+		return m
+	}
+	assert.Equal(t, map[string]string{"handWritten": "", "mockGet": "", "mockPut": "", "kindString": ""}, sources())
+
+	defer Analyzer.Flags.Set("gensource", "false")
+	assert.NoError(t, Analyzer.Flags.Set("gensource", "true"))
+	assert.Equal(t, map[string]string{
+		"handWritten": "",
+		"mockGet":     "mockgen -source=store.go -destination=mocks.go -package=gensource",
+		"mockPut":     "mockgen -source=store.go -destination=mocks.go -package=gensource",
+		"kindString":  "stringer -type=Kind",
+	}, sources())
+}
+
+func TestHalsteadCompositeTypes(t *testing.T) {
+	_, fd := parseFuncDecl(t, `package p
+
+func f() {
+	m := map[string][]int{"a": {1}}
+	p := struct{ x, y int }{1, 2}
+	var a [2]bool
+	_, _, _ = m, p, a
+}
+`)
+	operators, operands := halsteadCounts(fd, nil)
+	assert.Equal(t, map[string]int{
+		"func": 1, "f": 1, "()": 1, "{}": 5, ":=": 2, "map": 1, "[]": 3, "int": 2, ":": 1,
+		"struct": 1, "bool": 1, "=": 1, "string": 1, "_": 3,
+	}, operators)
+	assert.Equal(t, map[string]int{
+		"m": 2, `"a"`: 1, "1": 2, "p": 2, "x": 1, "y": 1, "2": 2, "var": 1, "a": 2,
+	}, operands)
+}
+
+func TestHalsteadTypesInfo(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "snippet.go", `package p
+
+type point struct{ x int }
+
+const limit = 3
+
+func f(p point, s []int) bool {
+	var q *point = nil
+	_ = q
+	return len(s)+p.x > limit
+}
+`, 0)
+	assert.NoError(t, err)
+	info := &types.Info{Uses: map[*ast.Ident]types.Object{}, Defs: map[*ast.Ident]types.Object{}}
+	_, err = (&types.Config{}).Check("p", fset, []*ast.File{f}, info)
+	assert.NoError(t, err)
+	fd := f.Decls[2].(*ast.FuncDecl)
+
+	operators, operands := halsteadCounts(fd, info)
+	for _, k := range []string{"point", "len"} {
+		assert.Contains(t, operators, k)
+		assert.NotContains(t, operands, k)
+	}
+	for _, k := range []string{"p", "s", "q", "x", "nil", "limit"} {
+		assert.Contains(t, operands, k)
+		assert.NotContains(t, operators, k)
+	}
+
+	// syntactic fallback: the field and the package level declarations
+	// resolve within the file, nil does not
+	operators, operands = halsteadCounts(fd, nil)
+	assert.Contains(t, operators, "nil")
+	assert.Contains(t, operators, "x")
+	assert.Contains(t, operands, "point")
+}
+
+func TestTodoMarkers(t *testing.T) {
+	defer Analyzer.Flags.Set("todoignorecase", "false")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "snippet.go", `package p
+
+func f() string {
+	/* HACK around
+	   the xxx case */
+	s := "TODO: in a string"
+	return s // TODOS is no marker
+}
+`, parser.ParseComments)
+	assert.NoError(t, err)
+	fd := f.Decls[0].(*ast.FuncDecl)
+
+	count, excerpts := TodoMarkersOf(f, fd)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"HACK around"}, excerpts)
+
+	assert.NoError(t, Analyzer.Flags.Set("todoignorecase", "true"))
+	count, excerpts = TodoMarkersOf(f, fd)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"HACK around", "xxx case"}, excerpts)
+
+	res := runResult(t, "todo")
+	assert.Equal(t, 3, res.Functions[0].TodoMarkers)
+	assert.Equal(t, []string{"TODO: split up the branches, FIXME the order", "HACK until the comparator lands"}, res.Functions[0].TodoExcerpts)
+	assert.Equal(t, 1, res.Functions[1].TodoMarkers)
+}
+
+func TestHalsteadUnicode(t *testing.T) {
+	defer Analyzer.Flags.Set("halstfoldcase", "false")
+	_, fd := parseFuncDecl(t, `package p
+
+func ƒ(α, β float64) complex128 {
+	Σ, σ := α+β, α*β
+	r, s := '😀'+'α', "é"+"é" // precomposed and decomposed
+	_, _ = r, s
+	return complex(Σ, σ) + 2.5i + 0x1p-2
+}
+`)
+	_, operands := halsteadCounts(fd, nil)
+	for _, k := range []string{"α", "β", "Σ", "σ", "'😀'", "'α'", "\"\u00e9\"", "\"e\u0301\"", "2.5i", "0x1p-2"} {
+		assert.Contains(t, operands, k)
+	}
+	assert.Equal(t, 2, operands["α"])
+	assert.Equal(t, 2, operands["Σ"])
+	assert.Equal(t, 2, operands["σ"])
+
+	assert.NoError(t, Analyzer.Flags.Set("halstfoldcase", "true"))
+	_, operands = halsteadCounts(fd, nil)
+	assert.Equal(t, 4, operands["σ"])
+	assert.NotContains(t, operands, "Σ")
+}
+
+// separateWalkCycloComp is the Cyclomatic complexity as counted by its own ast.Walk,
+// before it was merged into the Halstead traversal
+func separateWalkCycloComp(fd *ast.FuncDecl) int {
+	comp := 1
+	var v ast.Visitor
+	v = branchVisitor(func(n ast.Node) (w ast.Visitor) {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			comp += 2
+		case *ast.SendStmt:
+			comp++
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				comp++
+			}
+		case *ast.IfStmt:
+			comp++
+			if _, ok := n.Else.(*ast.BlockStmt); ok {
+				comp++
+			}
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SelectStmt, *ast.SwitchStmt:
+			comp++
+		case *ast.FuncLit:
+			return nil
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				comp++
+			}
+		}
+		return v
+	})
+	ast.Walk(v, fd)
+	return comp
+}
+
+// allFuncDecls parses the functions of the fixtures and of the package itself
+func allFuncDecls(t testing.TB) []*ast.FuncDecl {
+	files, err := filepath.Glob("testdata/src/*/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, _ := filepath.Glob("*.go")
+	decls := []*ast.FuncDecl{}
+	for _, name := range append(files, own...) {
+		f, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil {
+			continue // fixtures failing to parse on purpose
+		}
+		astVisitFunctions(f, func(fd *ast.FuncDecl) { decls = append(decls, fd) })
+	}
+	return decls
+}
+
+func TestSingleWalkCyclomaticComplexity(t *testing.T) {
+	decls := allFuncDecls(t)
+	assert.Greater(t, len(decls), 100)
+	defer func() { FuncLitUnits = false }()
+	for _, units := range []bool{false, true} {
+		FuncLitUnits = units
+		for _, fd := range decls {
+			assert.Equal(t, separateWalkCycloComp(fd), CyclomaticComplexity(fd), fd.Name.Name)
+		}
+	}
+
+	// values of several names are walked for each of them, but their branches count once
+	_, fd := parseFuncDecl(t, "package p\nfunc f(a, b bool) {\n\tvar x, y = a && b, a || b\n\t_, _ = x, y\n}")
+	assert.Equal(t, 3, CyclomaticComplexity(fd))
+	// declarations without body, like assembly functions
+	_, fd = parseFuncDecl(t, "package p\nfunc f(a int) int")
+	assert.Equal(t, 1, CyclomaticComplexity(fd))
+}
+
+// syntheticFuncDecl is a large function mixing branches, loops, closures and expressions
+func syntheticFuncDecl(b *testing.B) *ast.FuncDecl {
+	src := &strings.Builder{}
+	src.WriteString("package p\nfunc big(xs []int, ch chan int, m map[string]int) (sum int) {\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(src, "\tif x := xs[%d]; x > %d && x%%2 == 0 || m[\"k%d\"] < 0 {\n\t\tsum += x * %d\n\t} else {\n\t\tsum--\n\t}\n", i%7, i, i, i)
+		fmt.Fprintf(src, "\tfor j, v := range xs {\n\t\tswitch {\n\t\tcase v == j:\n\t\t\tch <- v\n\t\tdefault:\n\t\t\tsum += <-ch\n\t\t}\n\t}\n")
+		fmt.Fprintf(src, "\tgo func(n int) { m[\"n\"] = n + len(xs) }(%d)\n", i)
+	}
+	src.WriteString("\treturn sum\n}\n")
+	f, err := parser.ParseFile(token.NewFileSet(), "big.go", src.String(), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return f.Decls[0].(*ast.FuncDecl)
+}
+
+func BenchmarkFuncMetrics(b *testing.B) {
+	fd := syntheticFuncDecl(b)
+	b.Run("separate walks", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			separateWalkCycloComp(fd)
+			walkFunc(fd, nil).halstComp()
+		}
+	})
+	b.Run("single walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			w := walkFunc(fd, nil)
+			w.cycloComp()
+			w.halstComp()
+		}
+	})
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/pkg/old.go b/pkg/new.go
+similarity index 90%
+rename from pkg/old.go
+rename to pkg/new.go
+index 1111111..2222222 100644
+--- a/pkg/old.go
++++ b/pkg/new.go
+@@ -3,3 +3,4 @@ func f() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ 	return
+@@ -20,2 +21,0 @@ func g() {
+--- removed line looking like a file header
+-	x++
+diff --git a/pkg/pure.go b/pkg/renamed.go
+similarity index 100%
+rename from pkg/pure.go
+rename to pkg/renamed.go
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package p
+-func f() {}
+diff --git a/added.go b/added.go
+new file mode 100644
+--- /dev/null
++++ b/added.go
+@@ -0,0 +1,2 @@
++package p
++func f() {}
+\ No newline at end of file
+--- tail.go	2024-01-01 00:00:00
++++ tail.go	2024-01-02 00:00:00
+@@ -7 +7 @@
+-x
++y
+`
+	changed, err := ParseUnifiedDiff(strings.NewReader(diff))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]LineRange{
+		"pkg/new.go": {{3, 5}, {21, 22}},
+		"added.go":   {{1, 2}},
+		"tail.go":    {{6, 7}},
+	}, changed)
+
+	_, err = ParseUnifiedDiff(strings.NewReader("@@ -1 +1 @@\n-x\n+y\n"))
+	assert.EqualError(t, err, "line 1: hunk without file header")
+	_, err = ParseUnifiedDiff(strings.NewReader("+++ b/a.go\n@@ -x +1 @@\n"))
+	assert.Error(t, err)
+}
+
+func TestDiffRestriction(t *testing.T) {
+	diff := filepath.Join(t.TempDir(), "pr.diff")
+	// f2 spans lines 16-35 and f4 45-59 of a/a.go
+	assert.NoError(t, os.WriteFile(diff, []byte("--- a/testdata/src/a/a.go\n+++ b/testdata/src/a/a.go\n@@ -20,1 +20,1 @@\n-x\n+y\n@@ -45,0 +46,1 @@\n+z\n"), 0o600))
+	defer func() { ChangedLines = nil }()
+	assert.NoError(t, Analyzer.Flags.Set("diff", diff))
+	res := runResult(t, "a")
+	assert.Equal(t, []string{"f2", "f4"}, funcNames(res, func(f FuncResult) bool { return !f.Unchanged }))
+	assert.Empty(t, Violations(FuncStatsType{IsTooComplex: true, Unchanged: true}))
+
+	assert.Error(t, Analyzer.Flags.Set("diff", filepath.Join(t.TempDir(), "missing.diff")))
+}
+
+func TestChangedRangesOf(t *testing.T) {
+	defer func() { ChangedLines, DiffRoot, rootDiffFiles = nil, "", nil }()
+	ChangedLines = map[string][]LineRange{
+		"main.go":       {{1, 1}},
+		"cmd/x/main.go": {{2, 2}},
+	}
+	for i := 0; i < 10; i++ { // not decided by the map order
+		assert.Equal(t, []LineRange{{2, 2}}, changedRangesOf("/other/cmd/x/main.go"))
+	}
+	assert.Equal(t, []LineRange{{1, 1}}, changedRangesOf("/other/cmd/y/main.go"))
+	assert.Empty(t, changedRangesOf("/other/cmd/x/xmain.go"))
+
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), nil, 0o600))
+	DiffRoot, rootDiffFiles = root, existingFiles(root, ChangedLines)
+	assert.Equal(t, []LineRange{{1, 1}}, changedRangesOf(filepath.Join(root, "main.go")))
+	assert.Equal(t, []LineRange{{2, 2}}, changedRangesOf(filepath.Join(root, "cmd", "x", "main.go")))
+	assert.Empty(t, changedRangesOf(filepath.Join(root, "cmd", "y", "main.go")))
+
+	// like git diff --relative in the directory of the package
+	ChangedLines = map[string][]LineRange{"a.go": {{3, 3}}, "b.go": {{4, 4}}, "README.md": {{1, 1}}}
+	DiffRoot, rootDiffFiles = root, existingFiles(root, ChangedLines)
+	matchedDiffFiles.names = map[string]bool{}
+	assert.Equal(t, []LineRange{{3, 3}}, changedRangesOf(filepath.Join(root, "pkg", "a.go")))
+	assert.Equal(t, []string{"b.go"}, UnmatchedDiffFiles())
+	MarkDiffFile(filepath.Join(root, "pkg", "b.go"))
+	assert.Empty(t, UnmatchedDiffFiles())
+}
+
+func TestBranchPoints(t *testing.T) {
+	for _, fd := range allFuncDecls(t) {
+		sum := 0
+		for _, p := range BranchPoints(fd, nil) {
+			sum += p.Weight
+		}
+		assert.Equal(t, CyclomaticComplexity(fd)-1, sum, fd.Name.Name)
+	}
+
+	fset, fd := parseFuncDecl(t, `package p
+func f(c chan int, a, b bool) {
+	for a && b {
+		if a || <-c > 0 {
+		} else {
+		}
+	}
+	go func() { if a {} }()
+}`)
+	labels := []string{}
+	for _, p := range BranchPoints(fd, nil) {
+		labels = append(labels, fmt.Sprintf("%d:%s", fset.Position(p.Pos).Line, p.Label))
+	}
+	assert.Equal(t, []string{"3:for", "3:&&", "4:if", "4:||", "4:<-", "5:else", "8:go"}, labels)
+
+	defer func() { ExplainMax = 50 }()
+	ExplainMax = 3
+	related := RelatedBranches(fd, nil)
+	assert.Len(t, related, 4)
+	assert.Equal(t, "and 4 more decision points", related[3].Message)
+	assert.Equal(t, fd.Pos(), related[3].Pos)
+	ExplainMax = 0
+	related = RelatedBranches(fd, nil)
+	assert.Len(t, related, 7)
+	assert.Equal(t, "go (+2)", related[6].Message)
+}
+
+func TestGrades(t *testing.T) {
+	// the bounds are inclusive on both metrics
+	assert.Equal(t, "A", GradeOf(5, 85))
+	assert.Equal(t, "B", GradeOf(6, 85))
+	assert.Equal(t, "B", GradeOf(5, 84))
+	assert.Equal(t, "B", GradeOf(10, 65))
+	assert.Equal(t, "C", GradeOf(10, 64))
+	assert.Equal(t, "D", GradeOf(30, 20))
+	assert.Equal(t, "E", GradeOf(50, 10))
+	assert.Equal(t, "F", GradeOf(51, 100))
+	assert.Equal(t, "F", GradeOf(1, 9))
+	assert.Equal(t, []string{"A", "B", "C", "D", "E", "F"}, GradeLabels())
+	assert.Equal(t, "A:5:85,B:10:65,C:20:40,D:30:20,E:50:10,F", Analyzer.Flags.Lookup("grades").Value.String())
+
+	defer func(grades []GradeBound, worst string) { Grades, WorstGrade = grades, worst }(Grades, WorstGrade)
+	assert.NoError(t, Analyzer.Flags.Set("grades", "good:10:50, poor"))
+	assert.Equal(t, []GradeBound{{"good", 10, 50}}, Grades)
+	assert.Equal(t, "good", GradeOf(10, 50))
+	assert.Equal(t, "poor", GradeOf(11, 50))
+	assert.NoError(t, Analyzer.Flags.Set("grades", "A:5:85,B:10:65"))
+	assert.Equal(t, []string{"A", "B", "F"}, GradeLabels())
+
+	assert.Error(t, Analyzer.Flags.Set("grades", "A:5"))
+	assert.Error(t, Analyzer.Flags.Set("grades", "A:x:85"))
+	assert.Error(t, Analyzer.Flags.Set("grades", "F"))
+
+	stats := FuncStatsType{FunctionName: "f", CyclomaticComplexity: 12, IsTooComplex: true, Grade: "C"}
+	assert.Equal(t, "func f seems to be complex (cyclomatic complexity=12), grade C", ToDiagnosticMsg(stats))
+}
+
+func TestMIWithComments(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "micomments")[0].Result.(*Result)
+	documented, terse := res.Functions[0], res.Functions[1]
+	assert.Equal(t, terse.MaintenabilityIndex, documented.MaintenabilityIndex, "comments are no code")
+	assert.Zero(t, documented.CommentLines, "counted only with -mi-with-comments")
+
+	defer func(maintUnder int) { MIWithComments, MaintUnder = false, maintUnder }(MaintUnder)
+	MIWithComments, MaintUnder = true, 75
+	res = runResult(t, "micomments")
+	documented, terse = res.Functions[0], res.Functions[1]
+	assert.Equal(t, 14, documented.CommentLines)
+	assert.Equal(t, 1, terse.CommentLines, "its want comment")
+	assert.Equal(t, terse.ClassicMaintIndex, documented.ClassicMaintIndex)
+	// the documented function passes the threshold its terse twin fails
+	assert.Less(t, documented.ClassicMaintIndex, MaintUnder)
+	assert.GreaterOrEqual(t, documented.MaintenabilityIndex, MaintUnder)
+	assert.False(t, documented.IsNotMaintenable)
+	assert.True(t, terse.IsNotMaintenable)
+
+	assert.Equal(t, MaintainabilityIndex(1000, 10, 20), MaintainabilityIndexWithComments(1000, 10, 20, 0))
+	assert.Less(t, MaintainabilityIndexWithComments(1000, 10, 20, 10), MaintainabilityIndexWithComments(1000, 10, 20, 50))
+	assert.Equal(t, 100, MaintainabilityIndexWithComments(0, 1, 1, 50), "capped")
+}
+
+func TestMIScale(t *testing.T) {
+	defer func() {
+		MIScale, MICoefficients, MaintUnder = MIScaleVS, [4]float64{171, 5.2, 0.23, 16.2}, 20
+	}()
+	vs := runResult(t, "a")
+	assert.NoError(t, Analyzer.Flags.Set("mi-scale", "raw"))
+	raw := runResult(t, "a")
+	for i, f := range raw.Functions {
+		orig := origMaintIndex(f.HalsteadVolume, f.CyclomaticComplexity, f.SLOC)
+		assert.Equal(t, int(orig), f.MaintenabilityIndex, f.FunctionName)
+		assert.Equal(t, int(orig*100/171), vs.Functions[i].MaintenabilityIndex, f.FunctionName)
+	}
+	assert.Equal(t, 171, MaxMaintIndex())
+	assert.Equal(t, 171, PackageMaintainabilityIndex(nil))
+
+	// -maintunder is on the selected scale
+	notMaint := func(f FuncResult) bool { return f.IsNotMaintenable }
+	MaintUnder = 110
+	assert.Equal(t, []string{"f2", "f4"}, funcNames(runResult(t, "a"), notMaint))
+	assert.NoError(t, Analyzer.Flags.Set("mi-scale", "vs"))
+	assert.Equal(t, []string{"f0", "f1", "f2", "f3", "f4", "f5"}, funcNames(runResult(t, "a"), notMaint))
+	MaintUnder = 110 * 100 / 171
+	assert.Equal(t, []string{"f2", "f4"}, funcNames(runResult(t, "a"), notMaint))
+
+	assert.NoError(t, Analyzer.Flags.Set("mi-coefficients", "171, 5.2, 0.23, 20"))
+	assert.Equal(t, "171,5.2,0.23,20", Analyzer.Flags.Lookup("mi-coefficients").Value.String())
+	lower := runResult(t, "a")
+	for i, f := range lower.Functions {
+		assert.Less(t, f.MaintenabilityIndex, vs.Functions[i].MaintenabilityIndex, f.FunctionName)
+	}
+
+	assert.EqualError(t, Analyzer.Flags.Set("mi-scale", "sei"), `unknown maintainability index scale "sei", valid are: vs, raw`)
+	assert.EqualError(t, Analyzer.Flags.Set("mi-coefficients", "171,5.2,0.23"), `expected 4 comma separated maintainability index coefficients a,b,c,d, got 3 in "171,5.2,0.23"`)
+	assert.Error(t, Analyzer.Flags.Set("mi-coefficients", "171,x,0.23,16.2"))
+	assert.Error(t, Analyzer.Flags.Set("mi-coefficients", "0,5.2,0.23,16.2"))
+}
+
+func TestHalsteadRaw(t *testing.T) {
+	res := runResult(t, "a")
+	assert.Zero(t, res.Functions[2].HalsteadLength, "counted only with -halstead-raw")
+
+	defer func() { HalsteadRaw = false }()
+	assert.NoError(t, Analyzer.Flags.Set("halstead-raw", "true"))
+	res = runResult(t, "a")
+	for _, f := range res.Functions {
+		assert.Equal(t, f.HalsteadDistinctOperators+f.HalsteadDistinctOperands, f.HalsteadVocabulary, f.FunctionName)
+		assert.Equal(t, f.HalsteadTotalOperators+f.HalsteadTotalOperands, f.HalsteadLength, f.FunctionName)
+		assert.InDelta(t, float64(f.HalsteadLength)*math.Log2(float64(f.HalsteadVocabulary)), f.HalsteadVolume, 1e-9, f.FunctionName)
+		if f.HalsteadDistinctOperands > 0 {
+			assert.InDelta(t, float64(f.HalsteadDistinctOperators*f.HalsteadTotalOperands)/float64(2*f.HalsteadDistinctOperands), f.HalsteadDifficulty, 1e-9, f.FunctionName)
+		}
+	}
+
+	fset, fd := parseFuncDecl(t, "package p\nfunc f(a int) int {\n\treturn a + a\n}")
+	stats := FuncStats(fset, fd)
+	opt, opd := halsteadCounts(fd, nil)
+	assert.Equal(t, len(opt), stats.HalsteadDistinctOperators)
+	assert.Equal(t, len(opd), stats.HalsteadDistinctOperands)
+}
+
+func TestFanOut(t *testing.T) {
+	fanOuts := func(res *Result) []int {
+		counts := []int{}
+		for _, f := range res.Functions {
+			counts = append(counts, f.FanOut)
+		}
+		return counts
+	}
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "fanout")[0].Result.(*Result)
+	// greet, shout, qualified, methods, shadowed, indirect
+	assert.Equal(t, []int{0, 2, 2, 2, 1, 2}, fanOuts(res))
+
+	defer func() { FanOutOver, FanOutBuiltins = 0, false }()
+	assert.NoError(t, Analyzer.Flags.Set("fanout-builtins", "true"))
+	assert.Equal(t, []int{0, 2, 3, 2, 2, 4}, fanOuts(runResult(t, "fanout")))
+
+	FanOutOver = 2
+	res = runResult(t, "fanout")
+	assert.Equal(t, []string{"qualified", "indirect"}, funcNames(res, func(f FuncResult) bool { return f.IsTooMuchFanOut }))
+	assert.Equal(t, []string{"fanout"}, Violations(res.Functions[5].FuncStatsType))
+	assert.Equal(t, "func indirect seems to depend on too many functions (fan-out=4), grade B", ToDiagnosticMsg(res.Functions[5].FuncStatsType))
+
+	// without type information the callees are told apart by their names
+	FanOutBuiltins = false
+	fset, fd := parseFuncDecl(t, "package p\nfunc f(s []string) {\n\tn := func() int { return len(s) }\n\tprintln(n(), int64(n()), []byte(\"x\"))\n\tfmt.Println(s)\n\tfmt.Println(n)\n\tg(); g()\n}\nfunc g() {}")
+	assert.Equal(t, 3, FuncStats(fset, fd).FanOut, "n, fmt.Println and g")
+}
+
+func TestLocals(t *testing.T) {
+	locals := func(res *Result) []int {
+		counts := []int{}
+		for _, f := range res.Functions {
+			counts = append(counts, f.Locals)
+		}
+		return counts
+	}
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "locals")[0].Result.(*Result)
+	// specs, redeclared, shadowed, loops, closure, localTypes
+	assert.Equal(t, []int{6, 3, 3, 5, 3, 2}, locals(res))
+
+	// the same without type information
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(analysistest.TestData(), "src", "locals", "locals.go"), nil, parser.ParseComments)
+	assert.NoError(t, err)
+	counts := []int{}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			counts = append(counts, FuncStats(fset, fd).Locals)
+		}
+	}
+	assert.Equal(t, locals(res), counts)
+
+	defer func() { LocalsOver = 0 }()
+	LocalsOver = 4
+	res = runResult(t, "locals")
+	assert.Equal(t, []string{"specs", "loops"}, funcNames(res, func(f FuncResult) bool { return f.IsTooManyLocals }))
+	assert.Equal(t, []string{"locals"}, Violations(res.Functions[0].FuncStatsType))
+	assert.Contains(t, ToDiagnosticMsg(res.Functions[0].FuncStatsType), "func specs seems to juggle too many variables (local variables=6)")
+}
+
+func TestTypeStats(t *testing.T) {
+	res := runResult(t, "typestats")
+	assert.Empty(t, res.Types, "analyzed only with -typestats")
+
+	defer func() { TypeStats, MethodsOver, FieldsOver = false, 0, 0 }()
+	TypeStats = true
+	res = analysistest.Run(t, analysistest.TestData(), Analyzer, "typestats")[0].Result.(*Result)
+	summary := []string{}
+	for _, ts := range res.Types {
+		summary = append(summary, fmt.Sprintf("%s:%d %s %s m=%d f=%d i=%d", filepath.Base(ts.Filename), ts.Line, ts.TypeName, ts.Kind, ts.Methods, ts.Fields, ts.InterfaceMethods))
+	}
+	assert.Equal(t, []string{
+		"store.go:5 base struct m=0 f=2 i=0",
+		"store.go:11 Store struct m=3 f=5 i=0",
+		"store.go:26 Reader interface m=0 f=0 i=1",
+		"store.go:31 ReadWriter interface m=0 f=0 i=3",
+		"store.go:37 Names other m=1 f=0 i=0",
+		"store_more.go:11 List struct m=1 f=1 i=0",
+	}, summary)
+
+	MethodsOver, FieldsOver = 2, 4
+	res = runResult(t, "typestats")
+	msgs := []string{}
+	for _, ts := range res.Types {
+		if msg := ToTypeDiagnosticMsg(ts.TypeStatsType); msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	assert.Equal(t, []string{"type Store seems to do too much (methods=3)", "type ReadWriter seems to do too much (methods=3)"}, msgs)
+	MethodsOver = 0
+	assert.True(t, runResult(t, "typestats").Types[1].IsTooManyFields)
+}
+
+func TestMethodsByType(t *testing.T) {
+	defer func() { TypeStats, TypeCycloOver = false, 0 }()
+	TypeStats = true
+	res := runResult(t, "typestats")
+	summary := []string{}
+	for _, ts := range res.Types {
+		summary = append(summary, fmt.Sprintf("%s m=%d cyclo=%d max=%d loc=%d", ts.TypeName, ts.Methods, ts.MethodsCyclo, ts.MaxMethodCyclo, ts.MethodsLOC))
+	}
+	// Store has pointer and value receivers, the latter in another file than the type
+	assert.Equal(t, []string{
+		"base m=0 cyclo=0 max=0 loc=0",
+		"Store m=3 cyclo=3 max=1 loc=9",
+		"Reader m=0 cyclo=0 max=0 loc=0",
+		"ReadWriter m=0 cyclo=0 max=0 loc=0",
+		"Names m=1 cyclo=1 max=1 loc=3",
+		"List m=1 cyclo=1 max=1 loc=3",
+	}, summary)
+	assert.Zero(t, res.Types[0].MethodsMaint, "no methods")
+	assert.Equal(t, float64(res.Functions[0].MaintenabilityIndex+res.Functions[1].MaintenabilityIndex+res.Functions[2].MaintenabilityIndex)/3, res.Types[1].MethodsMaint)
+
+	TypeCycloOver = 2
+	diags := analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "typestats")[0].Diagnostics
+	msgs := []string{}
+	for _, d := range diags {
+		if d.Category == TypeStatsCategory {
+			msgs = append(msgs, d.Message)
+		}
+	}
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "store.go:11: type Store seems to be complex (methods cyclomatic complexity=3)")
+}
+
+func TestSkipEntryPoints(t *testing.T) {
+	tooComplex := func(f FuncResult) bool { return len(Violations(f.FuncStatsType)) > 0 }
+	assert.Equal(t, []string{"main", "init", "helper", "(app).main"}, funcNames(runResult(t, "entrypoints"), tooComplex))
+
+	defer func() { SkipEntryPoints = false }()
+	assert.NoError(t, Analyzer.Flags.Set("skip-entrypoints", "true"))
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "entrypoints")[0].Result.(*Result)
+	assert.Equal(t, []string{"helper", "(app).main"}, funcNames(res, tooComplex))
+	assert.Equal(t, []string{"main", "init"}, funcNames(res, func(f FuncResult) bool { return f.Suppressed }))
+	assert.Equal(t, EntryPointReason, res.Functions[0].SuppressReason)
+	assert.True(t, res.Functions[0].IsTooComplex, "still measured")
+
+	_, fd := parseFuncDecl(t, "package p\nfunc main() {}")
+	assert.False(t, IsEntryPoint("p", fd), "main of another package")
+	assert.True(t, IsEntryPoint("main", fd))
+	_, fd = parseFuncDecl(t, "package p\nfunc init() {}")
+	assert.True(t, IsEntryPoint("p", fd))
+}
+
+func TestConcurrency(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "concurrency")[0].Result.(*Result)
+	counts := []string{}
+	for _, f := range res.Functions {
+		counts = append(counts, fmt.Sprintf("%s go=%d chan=%d select=%d cases=%d sync=%d score=%d",
+			f.FunctionName, f.GoStmts, f.ChanOps, f.Selects, f.SelectCases, f.SyncCalls, f.ConcurrencyScore))
+	}
+	assert.Equal(t, []string{
+		// the range over in and the send of the goroutine, the send of the jobs and the range over out
+		"pool go=1 chan=4 select=0 cases=0 sync=3 score=8",
+		// both receives of the select and both sends
+		"merge go=1 chan=4 select=1 cases=2 sync=0 score=8",
+		"(*counter).inc go=0 chan=0 select=0 cases=0 sync=2 score=2",
+		"(*counter).Add go=0 chan=0 select=0 cases=0 sync=0 score=0",
+	}, counts)
+
+	// without type information, only the syntax is recognized
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(analysistest.TestData(), "src", "concurrency", "concurrency.go"), nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ConcurrencyCounts{GoStmts: 1, ChanOps: 2}, Concurrency(nil, f.Decls[1].(*ast.FuncDecl)))
+
+	defer func() { ConcOver = 0 }()
+	ConcOver = 2
+	res = runResult(t, "concurrency")
+	assert.Equal(t, []string{"pool", "merge"}, funcNames(res, func(f FuncResult) bool { return f.IsTooConcurrent }))
+	assert.Equal(t, []string{"concurrency"}, Violations(res.Functions[0].FuncStatsType))
+	assert.Contains(t, ToDiagnosticMsg(res.Functions[0].FuncStatsType), "func pool seems to need a careful concurrency review (concurrency score=8)")
+}
+
+func TestCycloMode(t *testing.T) {
+	// the default strict mode is pinned by the fixture
+	strict := analysistest.Run(t, analysistest.TestData(), Analyzer, "cyclomode")[0].Result.(*Result).Functions[0]
+	assert.Equal(t, 9, strict.CyclomaticComplexity)
+
+	defer func() { CycloMode = CycloModeStrict }()
+	assert.NoError(t, Analyzer.Flags.Set("cyclo-mode", "classic"))
+	classic := runResult(t, "cyclomode").Functions[0]
+	// if, for, switch and the initial path
+	assert.Equal(t, 4, classic.CyclomaticComplexity)
+	_, fd := parseFuncDecl(t, "package p\nfunc f(a, b bool) bool {\n\treturn a && b || !a\n}")
+	assert.Equal(t, 1, CyclomaticComplexity(fd))
+	assert.Empty(t, BranchPoints(fd, nil))
+
+	assert.NoError(t, Analyzer.Flags.Set("cyclo-mode", "strict"))
+	assert.Equal(t, 3, CyclomaticComplexity(fd))
+	assert.EqualError(t, Analyzer.Flags.Set("cyclo-mode", "gocyclo"), `unknown cyclomatic complexity mode "gocyclo", valid are: strict, classic`)
+}
+
+func TestRecursion(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "recursion")[0].Result.(*Result)
+	notes := []string{}
+	for _, f := range res.Functions {
+		notes = append(notes, fmt.Sprintf("%s: %v %s", f.FunctionName, f.Recursive, RecursionNote(f.FuncStatsType)))
+	}
+	assert.Equal(t, []string{
+		"fact: true calls itself",
+		"isEven: true mutually recursive with isOdd",
+		"isOdd: true mutually recursive with isEven",
+		"(*node).size: true calls itself",
+		"(inner).String: false ",
+		"(outer).String: false ",
+	}, notes)
+	assert.Empty(t, ToDiagnosticMsg(res.Functions[0].FuncStatsType), "reported only with -flag-recursion")
+
+	defer func() { FlagRecursion = false }()
+	assert.NoError(t, Analyzer.Flags.Set("flag-recursion", "true"))
+	res = runResult(t, "recursion")
+	assert.Equal(t, []string{"fact", "isEven", "isOdd", "(*node).size"}, funcNames(res, func(f FuncResult) bool { return len(Violations(f.FuncStatsType)) > 0 }))
+	assert.Equal(t, []string{"recursion"}, Violations(res.Functions[1].FuncStatsType))
+	assert.Equal(t, "func isEven seems to need a termination review (mutually recursive with isOdd), grade B", ToDiagnosticMsg(res.Functions[1].FuncStatsType))
+
+	stats := FuncStatsType{FunctionName: "f", CyclomaticComplexity: 12, IsTooComplex: true, Grade: "C", Recursive: true, CallsItself: true, IsFlaggedRecursive: true}
+	assert.Equal(t, "func f seems to be complex (cyclomatic complexity=12), grade C, recursive: calls itself", ToDiagnosticMsg(stats))
+}
+
+func TestDeclarationOnly(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "declonly")[0].Result.(*Result)
+	assert.Equal(t, []string{"add", "nanotime"}, funcNames(res, func(f FuncResult) bool { return f.DeclarationOnly }))
+	for _, f := range res.Functions[:2] {
+		assert.Equal(t, 1, f.CyclomaticComplexity)
+		assert.Zero(t, f.HalsteadVolume)
+		assert.Zero(t, f.HalsteadDifficulty)
+		// lowered by the Cyclomatic complexity of 1 only
+		assert.Equal(t, 99, f.MaintenabilityIndex)
+		assert.Empty(t, Violations(f.FuncStatsType))
+	}
+
+	fset, fd := parseFuncDecl(t, "package p\nfunc add(a, b int) int")
+	assert.Equal(t, 1, CyclomaticComplexity(fd))
+	difficulty, volume := HalsteadMetrics(fd)
+	assert.Zero(t, difficulty)
+	assert.Zero(t, volume)
+	assert.True(t, FuncStats(fset, fd).DeclarationOnly)
+}
+
+func TestHalsteadLiterals(t *testing.T) {
+	byValue := runResult(t, "halstlits").Functions[0]
+
+	defer Analyzer.Flags.Set("halstead-literals", "value")
+	assert.NoError(t, Analyzer.Flags.Set("halstead-literals", "kind"))
+	res := runResult(t, "halstlits")
+	assert.Equal(t, "flatten-selectors=false,merge-literals=true,fold-case=false,literals=kind", res.HalsteadNormalization)
+	byKind := res.Functions[0]
+	assert.InDelta(t, 164.233, byValue.HalsteadVolume, 0.001)
+	// the 8 distinct strings, the integers 10 and 3 and the float 0.5 become 3 operands, the length is the same
+	assert.InDelta(t, 136.229, byKind.HalsteadVolume, 0.001)
+
+	assert.EqualError(t, Analyzer.Flags.Set("halstead-literals", "text"), `unknown Halstead literals mode "text", valid are: value, kind`)
+}
+
+func TestRiskScore(t *testing.T) {
+	res := runResult(t, "a")
+	sort.SliceStable(res.Functions, func(i, j int) bool { return res.Functions[i].Score > res.Functions[j].Score })
+	// f2 is the most complex and the least maintainable, f0 is empty
+	assert.Equal(t, []string{"f2", "f3", "f4", "f1", "f5", "f0"}, funcNames(res, func(f FuncResult) bool { return true }))
+	for _, f := range res.Functions {
+		assert.Equal(t, RiskScore(f.FuncStatsType), f.Score)
+		assert.False(t, f.IsTooRisky)
+	}
+
+	defer Analyzer.Flags.Set("scoreover", "0")
+	assert.NoError(t, Analyzer.Flags.Set("scoreover", "0.4"))
+	res = runResult(t, "a")
+	assert.Equal(t, []string{"f2"}, funcNames(res, func(f FuncResult) bool { return f.IsTooRisky }))
+
+	defer Analyzer.Flags.Set("score-weights", "cyclo=0.5,maint=0.3,loc=0.2")
+	assert.NoError(t, Analyzer.Flags.Set("score-weights", "cyclo=1"))
+	assert.Equal(t, "cyclo=1,maint=0,loc=0,halstvol=0", ScoreWeights.String())
+	stats := FuncStatsType{FunctionName: "f", CyclomaticComplexity: CycloOver, MaintenabilityIndex: 10, LOC: 500}
+	assert.InDelta(t, 0.5, RiskScore(stats), 0.001)
+	stats.Score, stats.IsTooRisky = RiskScore(stats), true
+	assert.Equal(t, "func f seems to be risky (risk score=0.500)", ToDiagnosticMsg(stats))
+
+	assert.EqualError(t, Analyzer.Flags.Set("score-weights", "cyclo=0.5,maint=0.3"), `score weights "cyclo=0.5,maint=0.3" sum to 0.8, expected 1`)
+	assert.EqualError(t, Analyzer.Flags.Set("score-weights", "cyclo=0.5,size=0.5"), `invalid score weight "size=0.5", expected one of cyclo,halstvol,loc,maint as name=weight`)
+	assert.EqualError(t, Analyzer.Flags.Set("score-weights", "cyclo=-1,maint=2"), `invalid score weight "cyclo=-1", expected a non-negative number`)
+	assert.Equal(t, "cyclo=1,maint=0,loc=0,halstvol=0", ScoreWeights.String(), "unchanged by invalid weights")
+}
+
+func TestFunctionExtent(t *testing.T) {
+	res := runResult(t, "a")
+	for _, f := range res.Functions {
+		assert.Equal(t, f.LOC, f.Span, f.FunctionName)
+		assert.Equal(t, f.Line+f.Span-1, f.EndLine, f.FunctionName)
+	}
+	f2 := res.Functions[2]
+	assert.Equal(t, []int{16, 35, 20, 16, 6}, []int{f2.Line, f2.EndLine, f2.Span, f2.NameLine, f2.NameColumn})
+
+	fset, fd := parseFuncDecl(t, "package p\n\nfunc (r *T) m(\n\ta int,\n) {\n}\n")
+	stats := FuncStats(fset, fd)
+	assert.Equal(t, []int{3, 6, 4, 3, 13}, []int{stats.Line, stats.EndLine, stats.Span, stats.NameLine, stats.NameColumn})
+
+	defer Analyzer.Flags.Set("show-lines", "false")
+	assert.NoError(t, Analyzer.Flags.Set("show-lines", "true"))
+	f2.IsTooComplex = true
+	assert.Equal(t, "func f2 seems to be complex (cyclomatic complexity=8), grade C, lines 16–35", ToDiagnosticMsg(f2.FuncStatsType))
+}
+
+func TestCallbacksUnderConcurrency(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	out := &bytes.Buffer{}
+	defer func(f func(FuncStatsType), p func(string, *Result)) { FuncStatsCallback, PackageResultCallback = f, p }(FuncStatsCallback, PackageResultCallback)
+	// writing a row field by field interleaves, unless the callbacks are serialized
+	FuncStatsCallback = func(s FuncStatsType) {
+		for _, field := range []string{s.Package, ",", s.FunctionName, ",", strconv.Itoa(s.CyclomaticComplexity), "\n"} {
+			out.WriteString(field)
+			runtime.Gosched()
+		}
+	}
+	PackageResultCallback = func(pkgPath string, res *Result) {
+		out.WriteString(pkgPath + ",totals," + strconv.Itoa(len(res.Functions)) + "\n")
+	}
+	pkgs := []string{"a", "halstead", "cognitive", "typeswitch", "generics", "composite", "fanout", "locals", "concurrency", "recursion", "defers"}
+	analysistest.Run(t, analysistest.TestData(), Analyzer, pkgs...)
+
+	rows, err := csv.NewReader(out).ReadAll()
+	assert.NoError(t, err)
+	// the rows of a package are contiguous, ending with its totals row
+	done := map[string]bool{}
+	funcs := 0
+	for i, row := range rows {
+		assert.False(t, done[row[0]], "row %d of a completed package: %v", i, row)
+		if row[1] == "totals" {
+			assert.Equal(t, strconv.Itoa(funcs), row[2], row[0])
+			done[row[0]], funcs = true, 0
+			continue
+		}
+		assert.True(t, i+1 < len(rows) && rows[i+1][0] == row[0], "row %d followed by another package: %v", i, row)
+		funcs++
+	}
+	assert.Len(t, done, len(pkgs))
+}
+
+func TestHalsteadAggregates(t *testing.T) {
+	res := runResult(t, "halstead")
+	summed := map[string]float64{}
+	for _, f := range res.Functions {
+		summed[filepath.Base(f.Filename)] += f.HalsteadVolume
+	}
+	files := map[string]HalsteadAggregate{}
+	for name, h := range res.FileHalstead {
+		files[filepath.Base(name)] = h
+	}
+	assert.Len(t, files, 2)
+	// the vocabulary is counted once, but each occurrence is coded out of it, so the union has more volume
+	assert.Equal(t, 298.940, math.Round(summed["a.go"]*1000)/1000)
+	assert.Equal(t, 411.198, math.Round(files["a.go"].Volume*1000)/1000)
+	assert.Equal(t, 578.314, math.Round(summed["b.go"]*1000)/1000)
+	assert.Equal(t, 856.536, math.Round(files["b.go"].Volume*1000)/1000)
+	assert.Equal(t, 1419.465, math.Round(res.Halstead.Volume*1000)/1000)
+	assert.Greater(t, res.Halstead.Volume, files["a.go"].Volume+files["b.go"].Volume)
+	assert.Equal(t, res.Halstead.Difficulty*res.Halstead.Volume, res.Halstead.Effort)
+
+	defer Analyzer.Flags.Set("halstead-pkg-decls", "false")
+	assert.NoError(t, Analyzer.Flags.Set("halstead-pkg-decls", "true"))
+	withDecls := runResult(t, "halstead")
+	for name, h := range withDecls.FileHalstead {
+		switch filepath.Base(name) {
+		case "a.go": // the type declaration of t1
+			assert.Greater(t, h.Volume, res.FileHalstead[name].Volume)
+		default: // imports are not counted
+			assert.Equal(t, res.FileHalstead[name], h)
+		}
+	}
+	assert.Greater(t, withDecls.Halstead.Volume, res.Halstead.Volume)
+}
+
+func TestDefers(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "defers")[0].Result.(*Result)
+	summary := []string{}
+	for _, f := range res.Functions {
+		summary = append(summary, fmt.Sprintf("%s %d %d %d", f.FunctionName, f.Defers, f.DefersInLoop, f.MaxLiveDefers))
+	}
+	assert.Equal(t, []string{"sequential 2 0 2", "branches 3 1 2", "closures 0 0 0", "switches 4 1 3"}, summary)
+
+	defer func() { DefersOver, WarnDeferInLoop = 0, false }()
+	DefersOver = 2
+	res = runResult(t, "defers")
+	assert.Equal(t, []string{"branches", "switches"}, funcNames(res, func(f FuncResult) bool { return f.IsTooManyDefers }))
+	assert.Equal(t, []string{"defers"}, Violations(res.Functions[1].FuncStatsType))
+	assert.Contains(t, ToDiagnosticMsg(res.Functions[1].FuncStatsType), "func branches seems to juggle too many resources (defer statements=3)")
+
+	DefersOver = 0
+	WarnDeferInLoop = true
+	inLoops := func() []string {
+		lines := []string{}
+		for _, d := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "defers")[0].Diagnostics {
+			if d.Category == DeferLoopCategory {
+				lines = append(lines, d.Message[strings.Index(d.Message, ".go:")+1:])
+			}
+		}
+		return lines
+	}
+	// the defers in the loops of the function literals are reported along their enclosing function
+	assert.Equal(t, []string{
+		"go:33: defer in a loop of func branches runs only when the function returns",
+		"go:47: defer in a loop of func closures runs only when the function returns",
+		"go:64: defer in a loop of func switches runs only when the function returns",
+	}, inLoops())
+
+	defer Analyzer.Flags.Set("funclit", "false")
+	assert.NoError(t, Analyzer.Flags.Set("funclit", "true"))
+	assert.Equal(t, []string{
+		"go:33: defer in a loop of func branches runs only when the function returns",
+		"go:47: defer in a loop of func closures$2 runs only when the function returns",
+		"go:64: defer in a loop of func switches runs only when the function returns",
+	}, inLoops())
+}
+
+func TestReportModes(t *testing.T) {
+	defer func() { Report = ReportAll }()
+	diagnostics := func(mode string) []string {
+		assert.NoError(t, Analyzer.Flags.Set("report", mode))
+		msgs := []string{}
+		for _, d := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "a")[0].Diagnostics {
+			msgs = append(msgs, d.Message[strings.LastIndex(d.Message, "a.go:")+len("a.go:"):])
+		}
+		return msgs
+	}
+	assert.Len(t, diagnostics(ReportAll), 6)
+	assert.Empty(t, diagnostics(ReportViolations), "under the default thresholds")
+	defer func() { CycloOver = 10 }()
+	CycloOver = 5
+	assert.Equal(t, []string{"16: func f2 seems to be complex (cyclomatic complexity=8), grade C\n"}, diagnostics(ReportViolations))
+	assert.Empty(t, diagnostics(ReportNone))
+	assert.EqualError(t, Analyzer.Flags.Set("report", "some"), `unknown report mode "some", valid are: violations, all, none`)
+}
+
+func TestExportedOnly(t *testing.T) {
+	res := runResult(t, "exported")
+	assert.Empty(t, funcNames(res, func(f FuncResult) bool { return f.Unexported }), "all functions are reported by default")
+
+	defer func() { ExportedOnly, CycloOver = false, 10 }()
+	ExportedOnly, CycloOver = true, 1
+	res = runResult(t, "exported")
+	assert.Equal(t, []string{"helper", "(*Store).reset", "(internalThing).PublicName"}, funcNames(res, func(f FuncResult) bool { return f.Unexported }))
+	assert.Equal(t, []string{"New", "(*Store).Add"}, funcNames(res, func(f FuncResult) bool { return len(Violations(f.FuncStatsType)) > 0 }))
+	assert.Equal(t, 2, res.Violations)
+
+	Report = ReportViolations
+	defer func() { Report = ReportAll }()
+	reported := []string{}
+	for _, d := range analysistest.Run(quietT{}, analysistest.TestData(), Analyzer, "exported")[0].Diagnostics {
+		reported = append(reported, d.Message[strings.Index(d.Message, "func "):strings.Index(d.Message, " seems")])
+	}
+	assert.Equal(t, []string{"func New", "func (*Store).Add"}, reported)
+}
+
+func TestImports(t *testing.T) {
+	const module = "example.com/shop"
+	for path, class := range map[string]string{
+		"fmt":                                  ImportStdlib,
+		"net/http":                             ImportStdlib,
+		"example.com/shop":                     ImportIntra,
+		"example.com/shop/internal/store":      ImportIntra,
+		"example.com/shopping":                 ImportExternal,
+		"golang.org/x/tools/go/analysis":       ImportExternal,
+		"github.com/aws/aws-sdk-go/aws":        ImportExternal,
+		"example.com/shop/vendor/github.com/x": ImportExternal,
+		"vendor/golang.org/x/net/http2/hpack":  ImportExternal,
+	} {
+		assert.Equal(t, class, ClassifyImport(path, module), path)
+	}
+	// without a module, only the standard library is recognized
+	assert.Equal(t, ImportExternal, ClassifyImport("example.com/shop/internal/store", ""))
+	// a module without a dot in its path, like of testdata, takes precedence over the standard library rule
+	assert.Equal(t, ImportIntra, ClassifyImport("shop/store", "shop"))
+
+	pkg := types.NewPackage(module+"/api", "api")
+	pkg.SetImports([]*types.Package{
+		types.NewPackage("fmt", "fmt"),
+		types.NewPackage("unsafe", "unsafe"),
+		types.NewPackage(module+"/internal/store", "store"),
+		types.NewPackage("golang.org/x/sync/errgroup", "errgroup"),
+		types.NewPackage("github.com/aws/aws-sdk-go/aws", "aws"),
+	})
+	assert.Equal(t, ImportCounts{Stdlib: 1, Intra: 1, External: 2}, CountImports(pkg, module))
+
+	assert.Equal(t, ImportCounts{Stdlib: 1}, runResult(t, "halstead").Imports)
+}
+
+func TestLOCOver(t *testing.T) {
+	res := runResult(t, "long")
+	render := res.Functions[0].FuncStatsType
+	assert.Equal(t, 34, render.SLOC)
+	assert.Greater(t, render.LOC, render.SLOC, "blank and comment lines")
+	assert.Empty(t, Violations(render), "missed by the default thresholds")
+
+	defer Analyzer.Flags.Set("locover", "0")
+	assert.NoError(t, Analyzer.Flags.Set("locover", "33"))
+	res = runResult(t, "long")
+	assert.Equal(t, []string{"render"}, funcNames(res, func(f FuncResult) bool { return f.IsTooLong }))
+	assert.Equal(t, []string{"loc"}, Violations(res.Functions[0].FuncStatsType))
+	assert.Equal(t, "func render seems to be too long (source lines of code=34), grade C", ToDiagnosticMsg(res.Functions[0].FuncStatsType))
+	assert.Equal(t, 1, res.Violations)
+
+	assert.NoError(t, Analyzer.Flags.Set("locover", "34"))
+	assert.Empty(t, funcNames(runResult(t, "long"), func(f FuncResult) bool { return f.IsTooLong }))
+}
+
+func TestPackageThresholds(t *testing.T) {
+	tooComplex := func(f FuncResult) bool { return f.IsTooComplex }
+	assert.Empty(t, funcNames(runResult(t, "a"), tooComplex))
+
+	defer func() { PackageThresholds = func(string) map[string]int { return nil } }()
+	PackageThresholds = func(pkgPath string) map[string]int {
+		if pkgPath == "a" {
+			return map[string]int{"max-cyclo": 5, "unknown": 1}
+		}
+		return nil
+	}
+	res := runResult(t, "a")
+	assert.Equal(t, []string{"f2"}, funcNames(res, tooComplex))
+	assert.Equal(t, 5, res.Functions[2].CycloOver)
+	assert.Equal(t, MaintUnder, res.Functions[2].MaintUnder)
+}
+
+func TestNesting(t *testing.T) {
+	res := analysistest.Run(t, analysistest.TestData(), Analyzer, "nesting")[0].Result.(*Result)
+	depths := []int{}
+	for _, f := range res.Functions {
+		depths = append(depths, f.MaxNesting)
+	}
+	// flat, chain, deep, closure
+	assert.Equal(t, []int{0, 1, 4, 1}, depths)
+	assert.False(t, res.Functions[2].IsTooNested)
+
+	defer func() { NestOver = 0 }()
+	NestOver = 3
+	res = analysistest.Run(t, analysistest.TestData(), Analyzer, "nesting")[0].Result.(*Result)
+	assert.True(t, res.Functions[2].IsTooNested)
+	assert.Equal(t, []string{"nesting"}, Violations(res.Functions[2].FuncStatsType))
+	assert.Contains(t, ToDiagnosticMsg(res.Functions[2].FuncStatsType), "func deep seems to be deeply nested (nesting depth=4)")
+	assert.False(t, res.Functions[3].IsTooNested)
 }
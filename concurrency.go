@@ -0,0 +1,88 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// ConcOver is the concurrency score threshold, 0 disables the check
+var ConcOver int
+
+func init() {
+	Analyzer.Flags.IntVar(&ConcOver, "concover", 0, "print functions with the concurrency score > N: go statements, channel operations, selects with their cases and sync calls (0 disables the check)")
+}
+
+// ConcurrencyCounts are the concurrency constructs of a function
+type ConcurrencyCounts struct {
+	// GoStmts are the go statements
+	GoStmts int
+	// ChanOps are the channel sends and receives, including ranging over a channel
+	ChanOps int
+	// Selects are the select statements
+	Selects int
+	// SelectCases are the communication cases of the selects, without default
+	SelectCases int
+	// SyncCalls are the calls of the methods of the sync package types, like Mutex.Lock or WaitGroup.Wait
+	SyncCalls int
+}
+
+// Score is the concurrency score, the sum of the counts
+func (c ConcurrencyCounts) Score() int {
+	return c.GoStmts + c.ChanOps + c.Selects + c.SelectCases + c.SyncCalls
+}
+
+// Concurrency counts the concurrency constructs of the function, including those of its function literals,
+// like the body of a goroutine, so a go statement counts toward the function declaring it.
+// Ranging over channels and sync calls are recognized with type information only.
+func Concurrency(info *types.Info, fd *ast.FuncDecl) ConcurrencyCounts {
+	c := ConcurrencyCounts{}
+	if fd.Body == nil {
+		return c
+	}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			c.GoStmts++
+		case *ast.SendStmt:
+			c.ChanOps++
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				c.ChanOps++
+			}
+		case *ast.RangeStmt:
+			if info != nil && isChan(info.TypeOf(n.X)) {
+				c.ChanOps++
+			}
+		case *ast.SelectStmt:
+			c.Selects++
+		case *ast.CommClause:
+			if n.Comm != nil {
+				c.SelectCases++
+			}
+		case *ast.CallExpr:
+			if info != nil && isSyncCall(info, n) {
+				c.SyncCalls++
+			}
+		}
+		return true
+	})
+	return c
+}
+
+func isChan(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Chan)
+	return ok
+}
+
+// isSyncCall tells if the call is of a method of a sync package type, also when promoted by embedding
+func isSyncCall(info *types.Info, call *ast.CallExpr) bool {
+	fn := calleeOf(info, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "sync" {
+		return false
+	}
+	return fn.Type().(*types.Signature).Recv() != nil
+}
@@ -0,0 +1,59 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// DebugCoverage enables the accounting of AST node kinds the Halstead walker does not handle
+var DebugCoverage bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&DebugCoverage, "debugcoverage", false, "account AST node kinds not handled by the Halstead metrics walker, to be reported at the end of the run")
+}
+
+var unhandledNodes = struct {
+	sync.Mutex
+	cnt map[string]int
+}{cnt: map[string]int{}}
+
+// recordUnhandledNode accounts a node visited by the Halstead walker without being handled by it
+func recordUnhandledNode(n ast.Node) {
+	if !DebugCoverage || n == nil || reflect.ValueOf(n).IsNil() {
+		return
+	}
+	unhandledNodes.Lock()
+	defer unhandledNodes.Unlock()
+	unhandledNodes.cnt[reflect.TypeOf(n).String()]++
+}
+
+// UnhandledNodes returns the AST node kinds encountered, but not handled, by the Halstead walker so far
+func UnhandledNodes() map[string]int {
+	unhandledNodes.Lock()
+	defer unhandledNodes.Unlock()
+	res := make(map[string]int, len(unhandledNodes.cnt))
+	for k, v := range unhandledNodes.cnt {
+		res[k] = v
+	}
+	return res
+}
+
+// ResetUnhandledNodes clears the accounting of unhandled AST node kinds
+func ResetUnhandledNodes() {
+	unhandledNodes.Lock()
+	defer unhandledNodes.Unlock()
+	unhandledNodes.cnt = map[string]int{}
+}
+
+// UnhandledNodesReport formats the unhandled AST node kinds, one line per kind sorted by name
+func UnhandledNodesReport(nodes map[string]int) []string {
+	lines := []string{}
+	for k, v := range nodes {
+		lines = append(lines, fmt.Sprintf("encountered %s %d times: unhandled", k[1:], v))
+	}
+	sort.Strings(lines)
+	return lines
+}
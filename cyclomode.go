@@ -0,0 +1,34 @@
+package complexity
+
+import "fmt"
+
+// Cyclomatic complexity modes
+const (
+	// CycloModeStrict counts the short-circuit operators && and || as decision points, the extended cyclomatic complexity
+	CycloModeStrict = "strict"
+	// CycloModeClassic counts the control flow statements only, the original McCabe definition
+	CycloModeClassic = "classic"
+)
+
+// CycloMode is the Cyclomatic complexity mode, CycloModeStrict or CycloModeClassic
+var CycloMode = CycloModeStrict
+
+func init() {
+	Analyzer.Flags.Var(cycloModeFlag{}, "cyclo-mode", "'strict' counts the && and || operators as decision points of the Cyclomatic complexity, wherever they are, 'classic' counts the control flow statements only")
+}
+
+// cycloModeFlag is flag.Value of the -cyclo-mode option
+type cycloModeFlag struct{}
+
+func (cycloModeFlag) String() string {
+	return CycloMode
+}
+
+func (cycloModeFlag) Set(val string) error {
+	switch val {
+	case CycloModeStrict, CycloModeClassic:
+		CycloMode = val
+		return nil
+	}
+	return fmt.Errorf("unknown cyclomatic complexity mode %q, valid are: %s, %s", val, CycloModeStrict, CycloModeClassic)
+}
@@ -0,0 +1,132 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// DeferLoopCategory is the rule id (diagnostic category) of the informational defer in loop findings
+const DeferLoopCategory = "deferloop"
+
+var (
+	// DefersOver is the defer statements threshold, 0 disables the check
+	DefersOver int
+	// WarnDeferInLoop reports each defer statement in a for or range body
+	WarnDeferInLoop bool
+)
+
+func init() {
+	Analyzer.Flags.IntVar(&DefersOver, "defersover", 0, "print functions with more than N defer statements (0 disables the check)")
+	Analyzer.Flags.BoolVar(&WarnDeferInLoop, "warn-defer-in-loop", false, "report, for information, each defer statement in a for or range body, as it runs only when the function returns")
+}
+
+// DeferCounts are the defer statements of a function
+type DeferCounts struct {
+	// Defers are all defer statements
+	Defers int
+	// InLoop are the defer statements in for and range bodies, deferred once per iteration
+	InLoop int
+	// MaxLive approximates the most defers pending along a path: the defers of a block and of
+	// its nested blocks add up, while of alternatives, like if and else or the cases of a switch,
+	// only the one with the most defers counts. A loop body counts once.
+	MaxLive int
+	// inLoopPos are the positions of the InLoop defer statements
+	inLoopPos []token.Pos
+}
+
+// Defers counts the defer statements of the function.
+// The defers of function literals run when the literal returns, they do not count toward the function.
+func Defers(fd *ast.FuncDecl) DeferCounts {
+	c := DeferCounts{}
+	if fd.Body == nil {
+		return c
+	}
+	c.MaxLive = c.walkStmt(fd.Body, 0)
+	return c
+}
+
+// walkStmt counts the defers of s within loops nested loops deep, and returns the most live defers of s
+func (c *DeferCounts) walkStmt(s ast.Stmt, loops int) int {
+	switch s := s.(type) {
+	case *ast.DeferStmt:
+		c.Defers++
+		if loops > 0 {
+			c.InLoop++
+			c.inLoopPos = append(c.inLoopPos, s.Pos())
+		}
+		return 1
+	case *ast.BlockStmt:
+		return c.walkList(s.List, loops)
+	case *ast.LabeledStmt:
+		return c.walkStmt(s.Stmt, loops)
+	case *ast.IfStmt:
+		live := c.walkStmt(s.Body, loops)
+		if s.Else != nil {
+			live = max(live, c.walkStmt(s.Else, loops))
+		}
+		return live
+	case *ast.ForStmt:
+		return c.walkStmt(s.Body, loops+1)
+	case *ast.RangeStmt:
+		return c.walkStmt(s.Body, loops+1)
+	case *ast.SwitchStmt:
+		return c.walkClauses(s.Body, loops)
+	case *ast.TypeSwitchStmt:
+		return c.walkClauses(s.Body, loops)
+	case *ast.SelectStmt:
+		return c.walkClauses(s.Body, loops)
+	}
+	return 0
+}
+
+func (c *DeferCounts) walkList(list []ast.Stmt, loops int) int {
+	live := 0
+	for _, s := range list {
+		live += c.walkStmt(s, loops)
+	}
+	return live
+}
+
+// walkClauses returns the most live defers of the case or comm clauses of body
+func (c *DeferCounts) walkClauses(body *ast.BlockStmt, loops int) int {
+	live := 0
+	for _, cl := range body.List {
+		switch cl := cl.(type) {
+		case *ast.CaseClause:
+			live = max(live, c.walkList(cl.Body, loops))
+		case *ast.CommClause:
+			live = max(live, c.walkList(cl.Body, loops))
+		}
+	}
+	return live
+}
+
+// reportDefersInLoops reports, with -warn-defer-in-loop, the defers in loops of the functions at their position.
+// Unless function literals are units of their own, their defers are reported along their enclosing function.
+func reportDefersInLoops(pass *analysis.Pass, decls []*ast.FuncDecl, funcs []FuncResult) {
+	if !WarnDeferInLoop {
+		return
+	}
+	for i, fd := range decls {
+		if funcs[i].Suppressed || funcs[i].Unchanged || funcs[i].Unexported {
+			continue
+		}
+		report := func(fd *ast.FuncDecl) {
+			for _, pos := range Defers(fd).inLoopPos {
+				p := pass.Fset.Position(pos)
+				pass.Report(analysis.Diagnostic{
+					Pos:      pos,
+					Category: DeferLoopCategory,
+					Message:  fmt.Sprintf("%s:%d: defer in a loop of func %s runs only when the function returns", p.Filename, p.Line, funcs[i].FunctionName),
+				})
+			}
+		}
+		report(fd)
+		if !FuncLitUnits {
+			visitFuncLits(fd, funcs[i].FunctionName, report)
+		}
+	}
+}
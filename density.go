@@ -0,0 +1,67 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// DensityCategory is the rule id (diagnostic category) of violation density findings
+const DensityCategory = "density"
+
+// Violation density gate options
+var (
+	// ViolationsPerKLOC is the max number of violations per thousand source lines of a package, 0 disables the gate
+	ViolationsPerKLOC float64
+	// DensityMinSLOC exempts packages with fewer source lines from the density gate
+	DensityMinSLOC = 500
+)
+
+func init() {
+	Analyzer.Flags.Float64Var(&ViolationsPerKLOC, "violationsperkloc", 0, "report packages with more than N violations per thousand source lines of code (0 disables the gate)")
+	Analyzer.Flags.IntVar(&DensityMinSLOC, "densityminsloc", 500, "exempt packages with fewer source lines of code from the -violationsperkloc gate")
+}
+
+// ViolationsPerKLOCOf returns the violation density, 0 for packages without source lines
+func ViolationsPerKLOCOf(violations, sloc int) float64 {
+	if sloc == 0 {
+		return 0
+	}
+	return float64(violations) * 1000 / float64(sloc)
+}
+
+// countPackageSLOC sums the source lines of code of the files, including package clauses, imports and declarations
+func countPackageSLOC(pass *analysis.Pass, files []*ast.File) int {
+	sloc := 0
+	for _, f := range files {
+		sloc += countSLOC(pass.Fset, f)
+	}
+	return sloc
+}
+
+// countViolations counts the violations of all functions, once per rule
+func countViolations(funcs []FuncResult) int {
+	cnt := 0
+	for _, f := range funcs {
+		cnt += len(Violations(f.FuncStatsType))
+	}
+	return cnt
+}
+
+func reportDensity(pass *analysis.Pass, files []*ast.File, res *Result) {
+	if ViolationsPerKLOC <= 0 || res.SLOC < DensityMinSLOC || len(files) == 0 {
+		return
+	}
+	density := ViolationsPerKLOCOf(res.Violations, res.SLOC)
+	if density <= ViolationsPerKLOC {
+		return
+	}
+	p := pass.Fset.Position(files[0].Package)
+	pass.Report(analysis.Diagnostic{
+		Pos:      files[0].Package,
+		Category: DensityCategory,
+		Message: fmt.Sprintf("%s:%d: package %s has %0.2f violations per KLOC (%d violations in %d source lines), over %g",
+			p.Filename, p.Line, pass.Pkg.Name(), density, res.Violations, res.SLOC, ViolationsPerKLOC),
+	})
+}
@@ -0,0 +1,294 @@
+package complexity
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ChangedLines are the changed line ranges per file name of the -diff, nil without it.
+// File names are as given in the diff, relative to its root.
+var ChangedLines map[string][]LineRange
+
+// DiffRoot is the directory the file names of ChangedLines are relative to: the root of the git
+// work tree of the working directory when -diff is given, "" outside of one.
+var DiffRoot string
+
+// rootDiffFiles are the file names of ChangedLines naming a file of DiffRoot, like a main.go at its root
+var rootDiffFiles map[string]bool
+
+// LineRange is an inclusive range of lines
+type LineRange struct {
+	From, To int
+}
+
+func init() {
+	Analyzer.Flags.Var(diffFlag{}, "diff", "report only functions intersecting the lines changed by the unified diff in the file, - for stdin")
+}
+
+// diffFlag is flag.Value reading the unified diff of the -diff option
+type diffFlag struct{}
+
+func (diffFlag) String() string {
+	return ""
+}
+
+func (diffFlag) Set(val string) error {
+	r := io.Reader(os.Stdin)
+	if val != "-" {
+		f, err := os.Open(val)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	changed, err := ParseUnifiedDiff(r)
+	if err != nil {
+		return fmt.Errorf("diff %s: %v", val, err)
+	}
+	ChangedLines = changed
+	DiffRoot = gitRoot()
+	rootDiffFiles = existingFiles(DiffRoot, changed)
+	matchedDiffFiles.Lock()
+	matchedDiffFiles.names = map[string]bool{}
+	matchedDiffFiles.Unlock()
+	return nil
+}
+
+// existingFiles returns the names of changed that are files of the root directory, none without root
+func existingFiles(root string, changed map[string][]LineRange) map[string]bool {
+	res := map[string]bool{}
+	if root == "" {
+		return res
+	}
+	for name := range changed {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err == nil {
+			res[name] = true
+		}
+	}
+	return res
+}
+
+// gitRoot returns the closest directory, from the working directory up, holding .git, "" without any
+func gitRoot() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ParseUnifiedDiff returns the changed line ranges of the new version of each file of the diff,
+// like produced by git diff or diff -u. Added lines are changed, and so are the lines
+// around removed ones. Renamed files are given by their new name, deleted files are left out.
+func ParseUnifiedDiff(r io.Reader) (map[string][]LineRange, error) {
+	changed := map[string][]LineRange{}
+	file, header := "", false
+	line, oldLeft, newLeft := 0, 0, 0
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		text := sc.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			// hunk body, its length is given by its header, so removed lines like "-- x" are not file headers
+			switch {
+			case strings.HasPrefix(text, "+"):
+				changed[file] = append(changed[file], LineRange{line, line})
+				line++
+				newLeft--
+			case strings.HasPrefix(text, "-"):
+				changed[file] = append(changed[file], LineRange{max(line-1, 1), line})
+				oldLeft--
+			case strings.HasPrefix(text, `\`): // \ No newline at end of file
+			default: // context, also an empty line when trailing spaces were stripped
+				line++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file, header = diffFileName(strings.TrimPrefix(text, "+++ ")), true
+		case strings.HasPrefix(text, "rename to "):
+			file = filepath.ToSlash(strings.TrimPrefix(text, "rename to "))
+		case strings.HasPrefix(text, "@@ "):
+			var err error
+			if line, oldLeft, newLeft, err = parseHunkHeader(text); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			if !header {
+				return nil, fmt.Errorf("line %d: hunk without file header", n)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	delete(changed, "")
+	for f, ranges := range changed {
+		changed[f] = mergeRanges(ranges)
+	}
+	return changed, nil
+}
+
+// diffFileName strips the a/ or b/ prefix of git and the timestamp of diff -u, "" for /dev/null
+func diffFileName(name string) string {
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	if name == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(name, "b/") || strings.HasPrefix(name, "a/") {
+		name = name[2:]
+	}
+	return filepath.ToSlash(name)
+}
+
+// parseHunkHeader parses "@@ -l,s +l,s @@", where the sizes default to 1
+func parseHunkHeader(text string) (newLine, oldSize, newSize int, err error) {
+	fields := strings.Fields(text)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", text)
+	}
+	_, oldSize, err = parseHunkRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %v", text, err)
+	}
+	newLine, newSize, err = parseHunkRange(fields[2][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %v", text, err)
+	}
+	return newLine, oldSize, newSize, nil
+}
+
+func parseHunkRange(s string) (start, size int, err error) {
+	from, count, found := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(from); err != nil {
+		return 0, 0, err
+	}
+	size = 1
+	if found {
+		if size, err = strconv.Atoi(count); err != nil {
+			return 0, 0, err
+		}
+	}
+	if size == 0 { // an empty range starts after the given line
+		start++
+	}
+	return start, size, nil
+}
+
+// mergeRanges sorts the ranges and merges the overlapping or adjacent ones
+func mergeRanges(ranges []LineRange) []LineRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From })
+	merged := []LineRange{}
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && r.From <= merged[last].To+1 {
+			merged[last].To = max(merged[last].To, r.To)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// diff file names matched by an analyzed file, the others are reported by UnmatchedDiffFiles
+var matchedDiffFiles = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+// diffFileOf returns the diff file name of the file, "" without any. Within DiffRoot, its path
+// relative to it is looked up first. Otherwise, like for a diff of another tree or taken with
+// git diff --relative, the longest diff file name ending its path is taken, so a main.go
+// of the diff does not hide the changes of cmd/x/main.go. The names of files of DiffRoot,
+// like its main.go, only match themselves.
+func diffFileOf(filename string) string {
+	if DiffRoot != "" && filepath.IsAbs(filename) {
+		if rel, err := filepath.Rel(DiffRoot, filename); err == nil {
+			if _, ok := ChangedLines[filepath.ToSlash(rel)]; ok {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	filename = filepath.ToSlash(filename)
+	match := ""
+	for name := range ChangedLines {
+		if rootDiffFiles[name] {
+			continue
+		}
+		if (filename == name || strings.HasSuffix(filename, "/"+name)) && len(name) > len(match) {
+			match = name
+		}
+	}
+	return match
+}
+
+// changedRangesOf returns the changed ranges of the file, accounting its diff file name as matched
+func changedRangesOf(filename string) []LineRange {
+	name := diffFileOf(filename)
+	if name != "" {
+		matchedDiffFiles.Lock()
+		matchedDiffFiles.names[name] = true
+		matchedDiffFiles.Unlock()
+	}
+	return ChangedLines[name]
+}
+
+// MarkDiffFile accounts the diff file name of the analyzed file as matched, also when it has no functions
+func MarkDiffFile(filename string) {
+	if ChangedLines != nil {
+		changedRangesOf(filename)
+	}
+}
+
+// UnmatchedDiffFiles returns the sorted .go file names of the -diff matching no analyzed file so far,
+// like when the diff was taken in another directory, leaving out the test files skipped by -skiptests.
+// Deleted files are not part of ChangedLines.
+func UnmatchedDiffFiles() []string {
+	matchedDiffFiles.Lock()
+	defer matchedDiffFiles.Unlock()
+	res := []string{}
+	for name := range ChangedLines {
+		if strings.HasSuffix(name, ".go") && !matchedDiffFiles.names[name] && !(SkipTests && IsTestFile(name)) {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// IsUnchanged tells if the -diff is given and the lines of the function do not intersect any of its changed lines
+func IsUnchanged(fset *token.FileSet, fd *ast.FuncDecl) bool {
+	if ChangedLines == nil {
+		return false
+	}
+	tf := fset.File(fd.Pos())
+	from, to := tf.Line(fd.Pos()), tf.Line(fd.End())
+	for _, r := range changedRangesOf(tf.Name()) {
+		if r.From <= to && r.To >= from {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ExcludeFuncs skips the functions whose qualified name, see QualifiedName, matches any of the expressions
+	ExcludeFuncs []*regexp.Regexp
+	// ExcludeFiles skips the files whose name matches any of the expressions
+	ExcludeFiles []*regexp.Regexp
+)
+
+func init() {
+	Analyzer.Flags.Var(regexpsFlag{&ExcludeFuncs}, "exclude-func", "skip functions whose qualified name, like pkgpath.Func or pkgpath.(*Recv).Method, matches the regexp (repeatable or comma separated)")
+	Analyzer.Flags.Var(regexpsFlag{&ExcludeFiles}, "exclude-file", "skip files whose name matches the regexp (repeatable or comma separated)")
+}
+
+// regexpsFlag is flag.Value collecting regular expressions, given repeatedly or comma separated
+type regexpsFlag struct {
+	res *[]*regexp.Regexp
+}
+
+func (f regexpsFlag) String() string {
+	if f.res == nil {
+		return ""
+	}
+	patterns := []string{}
+	for _, re := range *f.res {
+		patterns = append(patterns, re.String())
+	}
+	return strings.Join(patterns, ",")
+}
+
+func (f regexpsFlag) Set(val string) error {
+	for _, p := range strings.Split(val, ",") {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid regexp %q: %v", p, err)
+		}
+		*f.res = append(*f.res, re)
+	}
+	return nil
+}
+
+// IsExcludedFile tells if the file matches any of ExcludeFiles
+func IsExcludedFile(filename string) bool {
+	return matchesAny(ExcludeFiles, filename)
+}
+
+// IsExcludedFunc tells if the qualified name of the function matches any of ExcludeFuncs
+func IsExcludedFunc(pkgPath string, fd *ast.FuncDecl) bool {
+	return matchesAny(ExcludeFuncs, QualifiedName(pkgPath, fd))
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// QualifiedName returns the package qualified name of the function, like pkgpath.Func,
+// or pkgpath.(*Recv).Method and pkgpath.(Recv).Method for methods
+func QualifiedName(pkgPath string, fd *ast.FuncDecl) string {
+	return pkgPath + "." + DisplayName(fd)
+}
+
+// DisplayName returns the name of the function as reported, like Func,
+// or (*Recv).Method and (Recv).Method for methods, telling apart the methods of different types
+func DisplayName(fd *ast.FuncDecl) string {
+	if r := recvName(fd); r != "" {
+		return r + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+// recvName renders the receiver type of the method like (*Recv), (Recv) or (List[T]), "" for functions
+func recvName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return ""
+	}
+	return "(" + typeExprString(fd.Recv.List[0].Type) + ")"
+}
+
+func typeExprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return "*" + typeExprString(e.X)
+	case *ast.ParenExpr:
+		return typeExprString(e.X)
+	case *ast.IndexExpr:
+		return typeExprString(e.X) + "[" + typeExprString(e.Index) + "]"
+	case *ast.IndexListExpr:
+		params := []string{}
+		for _, i := range e.Indices {
+			params = append(params, typeExprString(i))
+		}
+		return typeExprString(e.X) + "[" + strings.Join(params, ", ") + "]"
+	case *ast.Ident:
+		return e.Name
+	}
+	return "?"
+}
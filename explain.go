@@ -0,0 +1,57 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Explain attaches the decision points of the Cyclomatic complexity to the diagnostics of too complex functions
+var Explain bool
+
+// ExplainMax is the most decision points attached to a diagnostic, 0 for all of them
+var ExplainMax int
+
+func init() {
+	Analyzer.Flags.BoolVar(&Explain, "explain", false, "attach the decision points of too complex functions as related information of their diagnostics")
+	Analyzer.Flags.IntVar(&ExplainMax, "explain-max", 50, "attach at most N decision points to a diagnostic with -explain (0 for all)")
+}
+
+// BranchPoint is a decision point contributing to the Cyclomatic complexity
+type BranchPoint struct {
+	Pos token.Pos
+	// Label is the construct, like if, else, for, range, switch, select, go, <-, && or ||
+	Label string
+	// Weight is the complexity it adds, 2 for go statements, 1 otherwise
+	Weight int
+}
+
+// BranchPoints returns the decision points of the function in source order, they sum to its Cyclomatic complexity - 1
+func BranchPoints(fd *ast.FuncDecl, info *types.Info) []BranchPoint {
+	points := walkFunc(fd, info).points
+	// a final else is counted along with its if
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Pos < points[j].Pos })
+	return points
+}
+
+// RelatedBranches returns the decision points of the function as related information of its diagnostic, capped to ExplainMax
+func RelatedBranches(fd *ast.FuncDecl, info *types.Info) []analysis.RelatedInformation {
+	points := BranchPoints(fd, info)
+	related := []analysis.RelatedInformation{}
+	for i, p := range points {
+		if ExplainMax > 0 && i == ExplainMax {
+			related = append(related, analysis.RelatedInformation{Pos: fd.Pos(), Message: fmt.Sprintf("and %d more decision points", len(points)-i)})
+			break
+		}
+		msg := p.Label
+		if p.Weight > 1 {
+			msg = fmt.Sprintf("%s (+%d)", p.Label, p.Weight)
+		}
+		related = append(related, analysis.RelatedInformation{Pos: p.Pos, Message: msg})
+	}
+	return related
+}
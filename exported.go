@@ -0,0 +1,16 @@
+package complexity
+
+import "go/ast"
+
+// ExportedOnly restricts the reports to the exported surface of the packages
+var ExportedOnly bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&ExportedOnly, "exported-only", false, "report only exported functions and exported methods of exported types, the others are analyzed but neither reported nor counted")
+}
+
+// IsExportedFunc tells if the function is an exported function or an exported method of an exported type,
+// so (internalThing).PublicName is not. Function literals are not, regardless of their enclosing function.
+func IsExportedFunc(fd *ast.FuncDecl) bool {
+	return isAPIFunc(fd)
+}
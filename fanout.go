@@ -0,0 +1,109 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// FanOutOver is the fan-out threshold, 0 disables the check
+var FanOutOver int
+
+// FanOutBuiltins counts the calls of built-in functions, like len or append, into the fan-out
+var FanOutBuiltins bool
+
+// indirectCallee is the single callee of all calls through function-typed values, like variables, fields or closures
+const indirectCallee = "indirect"
+
+func init() {
+	Analyzer.Flags.IntVar(&FanOutOver, "fanoutover", 0, "print functions calling more than N distinct functions (0 disables the check)")
+	Analyzer.Flags.BoolVar(&FanOutBuiltins, "fanout-builtins", false, "count calls of built-in functions like len, append or make into the fan-out")
+}
+
+// FanOut returns the number of distinct functions and methods the function calls.
+// Calls within its function literals belong to the function, calls through
+// function-typed values count as a single indirect callee and conversions are no calls.
+// Without type information, callees are told apart by their names only.
+func FanOut(info *types.Info, fd *ast.FuncDecl) int {
+	if fd.Body == nil {
+		return 0
+	}
+	callees := map[string]bool{}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if key := calleeKey(info, call); key != "" {
+				callees[key] = true
+			}
+		}
+		return true
+	})
+	return len(callees)
+}
+
+// calleeKey identifies the called function, or returns "" if the call is not counted
+func calleeKey(info *types.Info, call *ast.CallExpr) string {
+	fun := ast.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr: // generic instantiation
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	case *ast.FuncLit: // its calls are counted along with the function
+		return ""
+	}
+	if info == nil {
+		return syntacticCalleeKey(fun)
+	}
+	if tv, ok := info.Types[fun]; ok && tv.IsType() {
+		return ""
+	}
+	var obj types.Object
+	switch f := fun.(type) {
+	case *ast.Ident:
+		obj = info.Uses[f]
+	case *ast.SelectorExpr:
+		obj = info.Uses[f.Sel]
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin().FullName()
+	case *types.Builtin:
+		if FanOutBuiltins {
+			return "builtin." + obj.Name()
+		}
+		return ""
+	}
+	return indirectCallee
+}
+
+// syntacticCalleeKey tells the callees apart by their names, resolving the universe ones
+func syntacticCalleeKey(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if f.Obj == nil {
+			switch types.Universe.Lookup(f.Name).(type) {
+			case *types.Builtin:
+				if FanOutBuiltins {
+					return "builtin." + f.Name
+				}
+				return ""
+			case *types.TypeName:
+				return ""
+			}
+			return f.Name
+		}
+		switch f.Obj.Kind {
+		case ast.Fun:
+			return f.Name
+		case ast.Typ:
+			return ""
+		}
+	case *ast.SelectorExpr:
+		if x, ok := f.X.(*ast.Ident); ok {
+			return x.Name + "." + f.Sel.Name
+		}
+		return "." + f.Sel.Name
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.StructType, *ast.StarExpr:
+		return "" // conversion
+	}
+	return indirectCallee
+}
@@ -0,0 +1,58 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Function literals reporting options.
+var (
+	// FuncLitUnits makes function literals reported as units of their own,
+	// named after the enclosing function and their index, e.g. f$1, f$1$1 for one nested in it,
+	// and glob$1 for ones in package level declarations.
+	// Their metrics are then left out of the enclosing function.
+	FuncLitUnits bool
+	// FuncLitInParent keeps counting function literals into the enclosing function as well,
+	// where metrics count them at all
+	FuncLitInParent bool
+)
+
+// funcLitSep separates the enclosing function name and the index of a function literal
+const funcLitSep = "$"
+
+func init() {
+	Analyzer.Flags.BoolVar(&FuncLitUnits, "funclit", false, "report function literals as units of their own, like f$1 for the first one in f")
+	Analyzer.Flags.BoolVar(&FuncLitInParent, "funclitinparent", false, "with -funclit, count function literals into the enclosing function as well")
+}
+
+// skipFuncLits tells metrics to leave function literals out, as they are reported on their own
+func skipFuncLits() bool {
+	return FuncLitUnits && !FuncLitInParent
+}
+
+// isFuncLitDecl tells if the declaration was synthesized for a function literal
+func isFuncLitDecl(fd *ast.FuncDecl) bool {
+	return strings.Contains(fd.Name.Name, funcLitSep)
+}
+
+// visitFuncLits calls cb for each function literal in n, at any nesting level, in source order.
+// The literal is wrapped in a declaration named prefix$1, prefix$2, ... for the ones directly in n,
+// prefix$1$1, ... for the ones nested in them, so it can be measured like any function.
+// Function declarations in n, other than n itself, are not visited.
+func visitFuncLits(n ast.Node, prefix string, cb func(*ast.FuncDecl)) {
+	i := 0
+	ast.Inspect(n, func(nn ast.Node) bool {
+		switch nn := nn.(type) {
+		case *ast.FuncDecl:
+			return nn == n
+		case *ast.FuncLit:
+			i++
+			name := fmt.Sprintf("%s%s%d", prefix, funcLitSep, i)
+			cb(&ast.FuncDecl{Name: &ast.Ident{NamePos: nn.Pos(), Name: name}, Type: nn.Type, Body: nn.Body})
+			visitFuncLits(nn.Body, name, cb)
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,122 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+var (
+	GenRegionBegin = "// BEGIN GENERATED"
+	GenRegionEnd   = "// END GENERATED"
+	SkipGenRegions bool
+	// GenSource attributes functions to the nearest preceding //go:generate directive of their file
+	GenSource bool
+	// IncludeGenerated analyzes the functions of files marked as generated, instead of skipping them
+	IncludeGenerated bool
+)
+
+// generatedFileComment is the standard comment marking a whole file as generated, see https://go.dev/s/generatedcode
+var generatedFileComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// genDirective is the //go:generate directive prefix
+const genDirective = "//go:generate "
+
+func init() {
+	Analyzer.Flags.StringVar(&GenRegionBegin, "genbegin", GenRegionBegin, "comment marking the beginning of an inline generated code region")
+	Analyzer.Flags.StringVar(&GenRegionEnd, "genend", GenRegionEnd, "comment marking the end of an inline generated code region")
+	Analyzer.Flags.BoolVar(&SkipGenRegions, "skipgenregions", false, "skip functions inside generated code regions, instead of only tagging them as generated")
+	Analyzer.Flags.BoolVar(&IncludeGenerated, "include-generated", false, "analyze the functions of files marked with a // Code generated ... DO NOT EDIT. comment, instead of skipping them")
+	Analyzer.Flags.BoolVar(&GenSource, "gensource", false, "attribute functions to the generator command of the nearest preceding //go:generate directive of their file")
+}
+
+// genRegion is a range of a file delimited by generated code markers
+type genRegion struct {
+	begin, end token.Pos
+}
+
+// findGenRegions collects the generated code regions of a file.
+// Unbalanced markers are reported via warnFnc and do not form a region.
+func findGenRegions(f *ast.File, warnFnc func(pos token.Pos, msg string)) []genRegion {
+	regions := []genRegion{}
+	var open token.Pos
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			switch {
+			case strings.HasPrefix(c.Text, GenRegionBegin):
+				if open.IsValid() {
+					warnFnc(open, "unbalanced generated code marker, "+GenRegionBegin+" without "+GenRegionEnd)
+				}
+				open = c.Pos()
+			case strings.HasPrefix(c.Text, GenRegionEnd):
+				if !open.IsValid() {
+					warnFnc(c.Pos(), "unbalanced generated code marker, "+GenRegionEnd+" without "+GenRegionBegin)
+					continue
+				}
+				regions = append(regions, genRegion{begin: open, end: c.End()})
+				open = token.NoPos
+			}
+		}
+	}
+	if open.IsValid() {
+		warnFnc(open, "unbalanced generated code marker, "+GenRegionBegin+" without "+GenRegionEnd)
+	}
+	return regions
+}
+
+// isInGenRegion tells if the node is wholly inside any of the regions
+func isInGenRegion(regions []genRegion, n ast.Node) bool {
+	for _, r := range regions {
+		if r.begin < n.Pos() && n.End() < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// genCommand is a //go:generate directive of a file
+type genCommand struct {
+	pos token.Pos
+	cmd string
+}
+
+// findGenCommands collects the //go:generate directives of a file, in position order
+func findGenCommands(f *ast.File) []genCommand {
+	cmds := []genCommand{}
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, genDirective) {
+				cmds = append(cmds, genCommand{pos: c.Pos(), cmd: strings.TrimSpace(strings.TrimPrefix(c.Text, genDirective))})
+			}
+		}
+	}
+	return cmds
+}
+
+// genSourceOf returns the command of the nearest directive preceding the node, "" if there is none
+func genSourceOf(cmds []genCommand, n ast.Node) string {
+	src := ""
+	for _, c := range cmds {
+		if c.pos > n.Pos() {
+			break
+		}
+		src = c.cmd
+	}
+	return src
+}
+
+// IsGeneratedFile tells if the file has the standard generated code comment before its package clause
+func IsGeneratedFile(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() > f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if generatedFileComment.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package complexity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GradeBound is the letter grade of the functions with a Cyclomatic complexity of at most MaxCyclo
+// and a Maintainability index of at least MinMaint
+type GradeBound struct {
+	Label    string
+	MaxCyclo int
+	MinMaint int
+}
+
+// Grades are the bounds of the letter grades, from the best one, the first matching a function gives its grade
+var Grades = []GradeBound{
+	{"A", 5, 85},
+	{"B", 10, 65},
+	{"C", 20, 40},
+	{"D", 30, 20},
+	{"E", 50, 10},
+}
+
+// WorstGrade is given to the functions matching none of the Grades
+var WorstGrade = "F"
+
+func init() {
+	Analyzer.Flags.Var(gradesFlag{}, "grades", "letter grades of the functions as label:max cyclomatic complexity:min maintainability index, from the best one, optionally followed by the label of the worst one")
+}
+
+// GradeOf returns the letter grade of a function with the given Cyclomatic complexity and Maintainability index
+func GradeOf(cyclo, maint int) string {
+	for _, g := range Grades {
+		if cyclo <= g.MaxCyclo && maint >= g.MinMaint {
+			return g.Label
+		}
+	}
+	return WorstGrade
+}
+
+// GradeLabels returns the labels of all grades, from the best one to WorstGrade
+func GradeLabels() []string {
+	labels := []string{}
+	for _, g := range Grades {
+		labels = append(labels, g.Label)
+	}
+	return append(labels, WorstGrade)
+}
+
+// gradesFlag is flag.Value of the -grades option, like A:5:85,B:10:65,F
+type gradesFlag struct{}
+
+func (gradesFlag) String() string {
+	bounds := []string{}
+	for _, g := range Grades {
+		bounds = append(bounds, fmt.Sprintf("%s:%d:%d", g.Label, g.MaxCyclo, g.MinMaint))
+	}
+	return strings.Join(append(bounds, WorstGrade), ",")
+}
+
+func (gradesFlag) Set(val string) error {
+	grades, worst := []GradeBound{}, "F"
+	entries := strings.Split(val, ",")
+	for i, e := range entries {
+		fields := strings.Split(strings.TrimSpace(e), ":")
+		if len(fields) == 1 && i == len(entries)-1 && i > 0 && fields[0] != "" {
+			worst = fields[0]
+			break
+		}
+		if len(fields) != 3 || fields[0] == "" {
+			return fmt.Errorf("invalid grade %q, expected label:max cyclomatic complexity:min maintainability index", e)
+		}
+		cyclo, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid grade %q: %v", e, err)
+		}
+		maint, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("invalid grade %q: %v", e, err)
+		}
+		grades = append(grades, GradeBound{fields[0], cyclo, maint})
+	}
+	Grades, WorstGrade = grades, worst
+	return nil
+}
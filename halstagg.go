@@ -0,0 +1,77 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// HalsteadPkgDecls makes the file and package Halstead aggregates include the
+// package level const, var and type declarations, which contribute to the vocabulary too
+var HalsteadPkgDecls bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&HalsteadPkgDecls, "halstead-pkg-decls", false, "include package level const, var and type declarations in the file and package Halstead aggregates")
+}
+
+// HalsteadAggregate are the Halstead metrics of a file or package taken as a whole:
+// computed over the union of the operators and operands of its functions, so vocabulary
+// shared between functions counts once, unlike in the sums of the per-function metrics
+type HalsteadAggregate struct {
+	Difficulty float64
+	Volume     float64
+	Effort     float64
+}
+
+// merge adds the operator and operand occurrences of o
+func (w *funcWalker) merge(o *funcWalker) {
+	for k, v := range o.opt {
+		w.opt[k] += v
+	}
+	for k, v := range o.opd {
+		w.opd[k] += v
+	}
+}
+
+func (w *funcWalker) halstAggregate() HalsteadAggregate {
+	difficulty, volume := w.halstComp()
+	return HalsteadAggregate{Difficulty: difficulty, Volume: volume, Effort: difficulty * volume}
+}
+
+// halsteadAggregates returns the package and per file Halstead aggregates of the analyzed functions.
+// Function literal units are merged only when left out of their enclosing function, so no code counts twice.
+func halsteadAggregates(fset *token.FileSet, info *types.Info, files []*ast.File, decls []*ast.FuncDecl, funcs []FuncResult) (HalsteadAggregate, map[string]HalsteadAggregate) {
+	pkg := &funcWalker{opt: map[string]int{}, opd: map[string]int{}, info: info}
+	byFile := map[string]*funcWalker{}
+	add := func(filename string, w *funcWalker) {
+		fw, ok := byFile[filename]
+		if !ok {
+			fw = &funcWalker{opt: map[string]int{}, opd: map[string]int{}, info: info}
+			byFile[filename] = fw
+		}
+		fw.merge(w)
+		pkg.merge(w)
+	}
+	for i, fd := range decls {
+		if isFuncLitDecl(fd) && !skipFuncLits() {
+			continue
+		}
+		add(funcs[i].Filename, walkFunc(fd, info))
+	}
+	if HalsteadPkgDecls {
+		for _, f := range files {
+			for _, d := range f.Decls {
+				if gd, ok := d.(*ast.GenDecl); ok && gd.Tok != token.IMPORT {
+					w := &funcWalker{opt: map[string]int{}, opd: map[string]int{}, info: info}
+					w.walkDecl(gd)
+					add(fset.Position(gd.Pos()).Filename, w)
+				}
+			}
+		}
+	}
+	aggs := map[string]HalsteadAggregate{}
+	for name, w := range byFile {
+		aggs[name] = w.halstAggregate()
+	}
+	return pkg.halstAggregate(), aggs
+}
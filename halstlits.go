@@ -0,0 +1,50 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// Halstead literal modes
+const (
+	// HalstLiteralsValue makes each distinct literal value an own operand, as in Halstead's definition
+	HalstLiteralsValue = "value"
+	// HalstLiteralsKind makes all literals of a kind, like all strings, one operand
+	HalstLiteralsKind = "kind"
+)
+
+// HalstLiterals is how literals are told apart as Halstead operands, HalstLiteralsValue or HalstLiteralsKind
+var HalstLiterals = HalstLiteralsValue
+
+func init() {
+	Analyzer.Flags.Var(halstLiteralsFlag{}, "halstead-literals", "'value' counts each distinct literal value as an own Halstead operand, 'kind' counts all literals of a kind, like all strings or all integers, as one operand")
+}
+
+// halstLiteralsFlag is flag.Value of the -halstead-literals option
+type halstLiteralsFlag struct{}
+
+func (halstLiteralsFlag) String() string {
+	return HalstLiterals
+}
+
+func (halstLiteralsFlag) Set(val string) error {
+	switch val {
+	case HalstLiteralsValue, HalstLiteralsKind:
+		HalstLiterals = val
+		return nil
+	}
+	return fmt.Errorf("unknown Halstead literals mode %q, valid are: %s, %s", val, HalstLiteralsValue, HalstLiteralsKind)
+}
+
+// literalOperand is the operand key of the literal. Each occurrence still counts toward the total operands,
+// only the vocabulary depends on the mode and on -halstmergelits.
+func literalOperand(lit *ast.BasicLit) string {
+	switch {
+	case HalstLiterals == HalstLiteralsKind:
+		return "<" + lit.Kind.String() + ">"
+	case HalstMergeLiterals:
+		return lit.Value
+	default:
+		return fmt.Sprintf("%s@%d", lit.Value, lit.Pos())
+	}
+}
@@ -0,0 +1,107 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// HotspotCategory is the rule id (diagnostic category) of critical hotspot findings
+const HotspotCategory = "hotspot"
+
+// HotspotsTop is the max number of hotspots reported per package, 0 disables the rule
+var HotspotsTop int
+
+// HotspotWeights are the weights of the factors contributing to the hotspot score
+var HotspotWeights = hotspotWeights{"exported": 1, "fanin": 1, "cyclo": 1, "maint": 1}
+
+func init() {
+	Analyzer.Flags.IntVar(&HotspotsTop, "hotspots", 0, "report the top N critical hotspots per package: exported, high fan-in, complex and hard to maintain functions (0 disables the rule)")
+	Analyzer.Flags.Var(&HotspotWeights, "hotspotweights", "weights of the hotspot factors, as exported=W,fanin=W,cyclo=W,maint=W")
+}
+
+// hotspotWeights is flag.Value of hotspot factor weights
+type hotspotWeights map[string]float64
+
+func (w hotspotWeights) String() string {
+	return fmt.Sprintf("exported=%g,fanin=%g,cyclo=%g,maint=%g", w["exported"], w["fanin"], w["cyclo"], w["maint"])
+}
+
+func (w hotspotWeights) Set(val string) error {
+	for _, kv := range strings.Split(val, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if _, known := w[k]; !ok || !known {
+			return fmt.Errorf("invalid hotspot weight %q, expected one of exported,fanin,cyclo,maint as name=weight", kv)
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid hotspot weight %q, expected a non-negative number", kv)
+		}
+		w[k] = f
+	}
+	return nil
+}
+
+type hotspot struct {
+	idx     int
+	score   float64
+	factors []string
+}
+
+// calcHotspots scores the functions crossing any individual threshold, highest first.
+// Functions under all thresholds are never hotspots.
+func calcHotspots(decls []*ast.FuncDecl, funcs []FuncResult) []hotspot {
+	maxFanIn := 0
+	for _, f := range funcs {
+		maxFanIn = max(maxFanIn, f.FanIn)
+	}
+	arr := []hotspot{}
+	for i, f := range funcs {
+		if !f.IsTooComplex && !f.IsNotMaintenable {
+			continue
+		}
+		h := hotspot{idx: i}
+		if isAPIFunc(decls[i]) {
+			h.score += HotspotWeights["exported"]
+			h.factors = append(h.factors, "exported")
+		}
+		if f.FanIn > 0 {
+			h.score += HotspotWeights["fanin"] * float64(f.FanIn) / float64(maxFanIn)
+			h.factors = append(h.factors, fmt.Sprintf("fan-in=%d", f.FanIn))
+		}
+		if f.IsTooComplex {
+			h.score += HotspotWeights["cyclo"] * float64(f.CyclomaticComplexity) / float64(max(CycloOver, 1))
+			h.factors = append(h.factors, fmt.Sprintf("cyclomatic complexity=%d over %d", f.CyclomaticComplexity, CycloOver))
+		}
+		if f.IsNotMaintenable {
+			h.score += HotspotWeights["maint"] * float64(MaintUnder-f.MaintenabilityIndex) / float64(max(MaintUnder, 1))
+			h.factors = append(h.factors, fmt.Sprintf("maintainability index=%d under %d", f.MaintenabilityIndex, MaintUnder))
+		}
+		arr = append(arr, h)
+	}
+	sort.SliceStable(arr, func(i, j int) bool { return arr[i].score > arr[j].score })
+	return arr
+}
+
+// reportHotspots reports at most HotspotsTop hotspots of the package
+func reportHotspots(pass *analysis.Pass, decls []*ast.FuncDecl, funcs []FuncResult) {
+	if HotspotsTop <= 0 {
+		return
+	}
+	for i, h := range calcHotspots(decls, funcs) {
+		if i >= HotspotsTop {
+			break
+		}
+		f := funcs[h.idx]
+		pass.Report(analysis.Diagnostic{
+			Pos:      f.Pos,
+			Category: HotspotCategory,
+			Message: fmt.Sprintf("%s:%d: func %s is a critical hotspot (score=%0.2f: %s)",
+				f.Filename, f.Line, f.FunctionName, h.score, strings.Join(h.factors, ", ")),
+		})
+	}
+}
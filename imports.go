@@ -0,0 +1,95 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ImportsCategory is the rule id (diagnostic category) of package external imports findings
+const ImportsCategory = "imports"
+
+// Import classes
+const (
+	// ImportStdlib are the packages of the standard library, without a dot in their first path element
+	ImportStdlib = "stdlib"
+	// ImportIntra are the packages of the module of the importing package
+	ImportIntra = "intra"
+	// ImportExternal are all other packages, including vendored ones and golang.org/x
+	ImportExternal = "external"
+)
+
+var (
+	// ExtImportsOver is the external imports threshold of a package, 0 disables the check
+	ExtImportsOver int
+	// ModulePath is the module of the analyzed packages, see ModuleOf
+	ModulePath string
+	// ModuleOf returns the module of the package, ModulePath unless the driver knows the module of each package.
+	// Main is to define its own logic instead.
+	ModuleOf = func(pkgPath string) string { return ModulePath }
+)
+
+func init() {
+	Analyzer.Flags.IntVar(&ExtImportsOver, "extimportsover", 0, "report packages importing more than N external packages, neither of the standard library nor of their module (0 disables the check)")
+	Analyzer.Flags.StringVar(&ModulePath, "module-path", "", "module of the analyzed packages, its packages are intra-module imports (default is the module of each package when known to the driver)")
+}
+
+// ImportCounts are the imported packages of a package by their class
+type ImportCounts struct {
+	Stdlib   int
+	Intra    int
+	External int
+}
+
+// ClassifyImport returns the class of the import path for a package of the module:
+// vendored paths are external, the paths within the module are intra-module,
+// and the paths without a dot in their first element are of the standard library.
+func ClassifyImport(path, module string) string {
+	if strings.HasPrefix(path, "vendor/") || strings.Contains(path, "/vendor/") {
+		return ImportExternal
+	}
+	if module != "" && (path == module || strings.HasPrefix(path, module+"/")) {
+		return ImportIntra
+	}
+	first, _, _ := strings.Cut(path, "/")
+	if !strings.Contains(first, ".") {
+		return ImportStdlib
+	}
+	return ImportExternal
+}
+
+// CountImports counts the packages directly imported by pkg, by their class.
+// The unsafe and C pseudo packages are not counted.
+func CountImports(pkg *types.Package, module string) ImportCounts {
+	c := ImportCounts{}
+	for _, imp := range pkg.Imports() {
+		if imp.Path() == "unsafe" || imp.Path() == "C" {
+			continue
+		}
+		switch ClassifyImport(imp.Path(), module) {
+		case ImportStdlib:
+			c.Stdlib++
+		case ImportIntra:
+			c.Intra++
+		default:
+			c.External++
+		}
+	}
+	return c
+}
+
+func reportImports(pass *analysis.Pass, files []*ast.File, res *Result) {
+	if ExtImportsOver <= 0 || len(files) == 0 || res.Imports.External <= ExtImportsOver {
+		return
+	}
+	p := pass.Fset.Position(files[0].Package)
+	pass.Report(analysis.Diagnostic{
+		Pos:      files[0].Package,
+		Category: ImportsCategory,
+		Message: fmt.Sprintf("%s:%d: package %s seems to depend on too many external packages (external imports=%d), over %d",
+			p.Filename, p.Line, pass.Pkg.Name(), res.Imports.External, ExtImportsOver),
+	})
+}
@@ -0,0 +1,65 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// LocalsOver is the local variables threshold, 0 disables the check
+var LocalsOver int
+
+func init() {
+	Analyzer.Flags.IntVar(&LocalsOver, "localsover", 0, "print functions declaring more than N local variables (0 disables the check)")
+}
+
+// Locals returns the number of local variables the function declares: the named results
+// and the variables of var declarations, := assignments, range clauses and type switches.
+// Re-assigned variables of := and the blank identifier are not counted, a shadowing variable is.
+// Parameters are not counted, see Params, neither are the variables of function literals
+// nor the fields and parameters of local struct, interface and func types.
+// Without type information, the variables are resolved within the file by their names.
+func Locals(info *types.Info, fd *ast.FuncDecl) int {
+	cnt := 0
+	if fd.Type.Results != nil {
+		for _, f := range fd.Type.Results.List {
+			for _, name := range f.Names {
+				if name.Name != "_" {
+					cnt++
+				}
+			}
+		}
+	}
+	if fd.Body == nil {
+		return cnt
+	}
+	symbols := map[*ast.Ident]bool{}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.FuncType, *ast.StructType:
+			return false
+		case *ast.TypeSwitchStmt:
+			// the symbol is declared in each clause, it is counted once
+			if as, ok := n.Assign.(*ast.AssignStmt); ok && len(as.Lhs) == 1 {
+				if id, ok := as.Lhs[0].(*ast.Ident); ok && id.Name != "_" {
+					symbols[id] = true
+					cnt++
+				}
+			}
+		case *ast.Ident:
+			if n.Name != "_" && !symbols[n] && declaresVar(info, n) {
+				cnt++
+			}
+		}
+		return true
+	})
+	return cnt
+}
+
+// declaresVar tells if the identifier declares a variable
+func declaresVar(info *types.Info, id *ast.Ident) bool {
+	if info == nil {
+		return id.Obj != nil && id.Obj.Kind == ast.Var && id.Obj.Pos() == id.Pos()
+	}
+	_, ok := info.Defs[id].(*types.Var)
+	return ok
+}
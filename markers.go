@@ -0,0 +1,80 @@
+package complexity
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+var (
+	// TodoMarkers is the comma separated list of markers counted in the comments of function bodies
+	TodoMarkers = "TODO,FIXME,HACK,XXX"
+	// TodoIgnoreCase matches the markers regardless of their case
+	TodoIgnoreCase bool
+)
+
+// todoExcerptLen is the maximum length, in runes, of the excerpt of a marked comment line
+const todoExcerptLen = 60
+
+func init() {
+	Analyzer.Flags.StringVar(&TodoMarkers, "todomarkers", TodoMarkers, "comma separated list of markers, like TODO, counted in the comments of each function body, empty disables the counting")
+	Analyzer.Flags.BoolVar(&TodoIgnoreCase, "todoignorecase", false, "match the -todomarkers regardless of their case")
+}
+
+// todoMarkersRegexp matches any of the markers as a whole word, nil if there are none
+func todoMarkersRegexp() *regexp.Regexp {
+	words := []string{}
+	for _, m := range strings.Split(TodoMarkers, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			words = append(words, regexp.QuoteMeta(m))
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	expr := `\b(` + strings.Join(words, "|") + `)\b`
+	if TodoIgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// findTodoMarkers counts the markers in the comments of the function body
+// and excerpts each marked comment line, starting at its first marker.
+// String literals are not comments, so markers in them are not counted.
+func findTodoMarkers(re *regexp.Regexp, f *ast.File, fd *ast.FuncDecl) (count int, excerpts []string) {
+	if re == nil || fd.Body == nil {
+		return 0, nil
+	}
+	for _, cg := range f.Comments {
+		if cg.Pos() < fd.Body.Pos() || cg.End() > fd.Body.End() {
+			continue
+		}
+		for _, c := range cg.List {
+			for _, line := range strings.Split(c.Text, "\n") {
+				locs := re.FindAllStringIndex(line, -1)
+				if len(locs) == 0 {
+					continue
+				}
+				count += len(locs)
+				excerpts = append(excerpts, excerpt(strings.TrimSuffix(strings.TrimSpace(line[locs[0][0]:]), "*/")))
+			}
+		}
+	}
+	return count, excerpts
+}
+
+// excerpt shortens the text to todoExcerptLen runes
+func excerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if r := []rune(text); len(r) > todoExcerptLen {
+		return string(r[:todoExcerptLen]) + "..."
+	}
+	return text
+}
+
+// TodoMarkersOf counts the -todomarkers in the comments of the body of fd, declared in f.
+// The file must be parsed with comments.
+func TodoMarkersOf(f *ast.File, fd *ast.FuncDecl) (count int, excerpts []string) {
+	return findTodoMarkers(todoMarkersRegexp(), f, fd)
+}
@@ -0,0 +1,73 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+)
+
+// MIWithComments adds the comment bonus of the original Maintainability index to it
+var MIWithComments bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&MIWithComments, "mi-with-comments", false, "add the comment bonus 50*sin(sqrt(2.4*percentage of comment lines in radians)) to the Maintainability index, rewarding documented functions")
+}
+
+// CommentLinesOf counts the lines of the function, including its doc comment, holding comments
+func CommentLinesOf(fset *token.FileSet, f *ast.File, fd *ast.FuncDecl) int {
+	from, to := commentedRange(fd)
+	lines := map[int]bool{}
+	for _, cg := range f.Comments {
+		if cg.End() < from || cg.Pos() > to {
+			continue
+		}
+		for _, c := range cg.List {
+			if c.Pos() < from || c.End() > to {
+				continue
+			}
+			for l := fset.Position(c.Pos()).Line; l <= fset.Position(c.End()).Line; l++ {
+				lines[l] = true
+			}
+		}
+	}
+	return len(lines)
+}
+
+// commentedRange is the function along with its doc comment
+func commentedRange(fd *ast.FuncDecl) (from, to token.Pos) {
+	from = fd.Pos()
+	if fd.Doc != nil {
+		from = fd.Doc.Pos()
+	}
+	return from, fd.End()
+}
+
+// MaintainabilityIndexWithComments returns the Maintainability index, on the MIScale,
+// with the comment bonus, given the percentage (0-100) of comment lines.
+// The percentage is taken in radians, so the bonus grows with the comments up to about 60% of the lines,
+// instead of oscillating.
+func MaintainabilityIndexWithComments(volume float64, cyclo, loc int, perCM float64) int {
+	bonus := 50 * math.Sin(math.Sqrt(2.4*perCM*math.Pi/180))
+	return scaleMaintIndex(origMaintIndex(volume, cyclo, loc) + bonus)
+}
+
+// ApplyCommentWeight, with -mi-with-comments, counts the comment lines of the function and
+// replaces its Maintainability index by the comment-weighted one, keeping the classic one in ClassicMaintIndex
+func ApplyCommentWeight(stats *FuncStatsType, fset *token.FileSet, f *ast.File, fd *ast.FuncDecl) {
+	if !MIWithComments {
+		return
+	}
+	from, to := commentedRange(fd)
+	lines := fset.Position(to).Line - fset.Position(from).Line + 1
+	stats.CommentLines = CommentLinesOf(fset, f, fd)
+	size := stats.SLOC
+	if MIUseStatements {
+		size = stats.Statements
+	}
+	perCM := 100 * float64(stats.CommentLines) / float64(lines)
+	stats.ClassicMaintIndex = stats.MaintenabilityIndex
+	stats.MaintenabilityIndex = MaintainabilityIndexWithComments(stats.HalsteadVolume, stats.CyclomaticComplexity, size, perCM)
+	stats.IsNotMaintenable = stats.MaintenabilityIndex < stats.MaintUnder
+	stats.Grade = GradeOf(stats.CyclomaticComplexity, stats.MaintenabilityIndex)
+	applyScore(stats)
+}
@@ -0,0 +1,87 @@
+package complexity
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Maintainability index scales
+const (
+	// MIScaleVS is the Visual Studio scale, the original index normalized to 0-100 by the a coefficient
+	MIScaleVS = "vs"
+	// MIScaleRaw is the original SEI scale, up to 171 with the default coefficients and possibly negative
+	MIScaleRaw = "raw"
+)
+
+// MIScale is the scale of the Maintainability index, MIScaleVS or MIScaleRaw
+var MIScale = MIScaleVS
+
+// MICoefficients are the constants a, b, c, d of the Maintainability index a - b*ln(volume) - c*cyclo - d*ln(loc)
+var MICoefficients = [4]float64{171, 5.2, 0.23, 16.2}
+
+func init() {
+	Analyzer.Flags.Var(miScaleFlag{}, "mi-scale", "scale of the Maintainability index: 'vs' normalized to 0-100, or 'raw' original SEI scale; thresholds like -maintunder are on the selected scale")
+	Analyzer.Flags.Var(miCoefficientsFlag{}, "mi-coefficients", "comma separated constants a,b,c,d of the Maintainability index a - b*ln(volume) - c*cyclo - d*ln(loc)")
+}
+
+// scaleMaintIndex returns the original index value on the MIScale
+func scaleMaintIndex(origVal float64) int {
+	if MIScale == MIScaleRaw {
+		return int(origVal)
+	}
+	return int(math.Min(100, math.Max(0.0, origVal*100.0/MICoefficients[0])))
+}
+
+// MaxMaintIndex is the Maintainability index of code without volume, complexity nor lines, the best one on the MIScale
+func MaxMaintIndex() int {
+	return scaleMaintIndex(MICoefficients[0])
+}
+
+// miScaleFlag is flag.Value of the -mi-scale option
+type miScaleFlag struct{}
+
+func (miScaleFlag) String() string {
+	return MIScale
+}
+
+func (miScaleFlag) Set(val string) error {
+	switch val {
+	case MIScaleVS, MIScaleRaw:
+		MIScale = val
+		return nil
+	}
+	return fmt.Errorf("unknown maintainability index scale %q, valid are: %s, %s", val, MIScaleVS, MIScaleRaw)
+}
+
+// miCoefficientsFlag is flag.Value of the -mi-coefficients option
+type miCoefficientsFlag struct{}
+
+func (miCoefficientsFlag) String() string {
+	values := []string{}
+	for _, c := range MICoefficients {
+		values = append(values, strconv.FormatFloat(c, 'g', -1, 64))
+	}
+	return strings.Join(values, ",")
+}
+
+func (miCoefficientsFlag) Set(val string) error {
+	fields := strings.Split(val, ",")
+	if len(fields) != len(MICoefficients) {
+		return fmt.Errorf("expected 4 comma separated maintainability index coefficients a,b,c,d, got %d in %q", len(fields), val)
+	}
+	coefficients := [4]float64{}
+	for i, f := range fields {
+		c, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return fmt.Errorf("invalid maintainability index coefficient %q: %v", f, err)
+		}
+		coefficients[i] = c
+	}
+	if coefficients[0] <= 0 {
+		return fmt.Errorf("the maintainability index coefficient a must be positive, got %v", coefficients[0])
+	}
+	MICoefficients = coefficients
+	return nil
+}
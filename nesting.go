@@ -0,0 +1,65 @@
+package complexity
+
+import (
+	"go/ast"
+)
+
+// NestOver is the nesting depth threshold, 0 disables the check
+var NestOver int
+
+func init() {
+	Analyzer.Flags.IntVar(&NestOver, "nestover", 0, "print functions with if, for, switch or select statements nested more than N deep (0 disables the check)")
+}
+
+// MaxNesting returns the deepest nesting of the if, for, range, switch, type switch and select statements
+// of the function: 0 without any, 1 when none of them is within another.
+// An else if continues its if at the same depth, and function literals have their own nesting.
+func MaxNesting(fd *ast.FuncDecl) int {
+	if fd.Body == nil {
+		return 0
+	}
+	return maxNesting(fd.Body, 0)
+}
+
+// maxNesting returns the deepest nesting within the block, whose statements are depth deep
+func maxNesting(block *ast.BlockStmt, depth int) int {
+	res := depth
+	ast.Inspect(block, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			res = max(res, ifNesting(n, depth+1))
+			return false
+		case *ast.ForStmt:
+			res = max(res, maxNesting(n.Body, depth+1))
+			return false
+		case *ast.RangeStmt:
+			res = max(res, maxNesting(n.Body, depth+1))
+			return false
+		case *ast.SwitchStmt:
+			res = max(res, maxNesting(n.Body, depth+1))
+			return false
+		case *ast.TypeSwitchStmt:
+			res = max(res, maxNesting(n.Body, depth+1))
+			return false
+		case *ast.SelectStmt:
+			res = max(res, maxNesting(n.Body, depth+1))
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+// ifNesting returns the deepest nesting of the if statement, its else branches included
+func ifNesting(s *ast.IfStmt, depth int) int {
+	res := maxNesting(s.Body, depth)
+	switch e := s.Else.(type) {
+	case *ast.IfStmt:
+		res = max(res, ifNesting(e, depth))
+	case *ast.BlockStmt:
+		res = max(res, maxNesting(e, depth))
+	}
+	return res
+}
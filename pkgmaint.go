@@ -0,0 +1,56 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// PkgMaintCategory is the rule id (diagnostic category) of package maintainability findings
+const PkgMaintCategory = "pkgmaint"
+
+// PkgMaintUnder is the min Maintainability index of a package, 0 disables the check
+var PkgMaintUnder int
+
+func init() {
+	Analyzer.Flags.IntVar(&PkgMaintUnder, "pkgmaintunder", 0, "report packages with the Maintainability index of all their functions < N (0 disables the check)")
+}
+
+// PackageMaintainabilityIndex returns the Maintainability index of the functions taken as a whole,
+// from their summed Halstead volume, Cyclomatic complexity and source lines of code,
+// or statements with -mi-use-statements. It is the best index, 100 on the default scale, for a package without functions.
+func PackageMaintainabilityIndex(funcs []FuncResult) int {
+	volume, cyclo, size := 0.0, 0, 0
+	for _, f := range funcs {
+		if FuncLitInParent && strings.Contains(f.FunctionName, funcLitSep) {
+			// already counted into its enclosing function
+			continue
+		}
+		volume += f.HalsteadVolume
+		cyclo += f.CyclomaticComplexity
+		if MIUseStatements {
+			size += f.Statements
+		} else {
+			size += f.SLOC
+		}
+	}
+	if size == 0 {
+		return MaxMaintIndex()
+	}
+	return calcMaintIndex(volume, cyclo, size)
+}
+
+func reportPackageMaint(pass *analysis.Pass, files []*ast.File, res *Result) {
+	if PkgMaintUnder <= 0 || len(files) == 0 || res.MaintainabilityIndex >= PkgMaintUnder {
+		return
+	}
+	p := pass.Fset.Position(files[0].Package)
+	pass.Report(analysis.Diagnostic{
+		Pos:      files[0].Package,
+		Category: PkgMaintCategory,
+		Message: fmt.Sprintf("%s:%d: package %s seems to have low maintainability (maintainability index=%d), under %d",
+			p.Filename, p.Line, pass.Pkg.Name(), res.MaintainabilityIndex, PkgMaintUnder),
+	})
+}
@@ -0,0 +1,61 @@
+package complexity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FlagRecursion reports the recursive functions, also when under all thresholds
+var FlagRecursion bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&FlagRecursion, "flag-recursion", false, "report the recursive functions, calling themselves or mutually recursive with another function of the package, also when under all thresholds")
+}
+
+// recursion returns for each function whether it calls itself and
+// the functions of the package it is mutually recursive with, calling each other directly
+func (g callGraph) recursion() (self []bool, peers [][]int) {
+	self, peers = make([]bool, len(g.calls)), make([][]int, len(g.calls))
+	calls := make([]map[int]bool, len(g.calls))
+	for i, cs := range g.calls {
+		calls[i] = map[int]bool{}
+		for _, j := range cs {
+			calls[i][j] = true
+		}
+	}
+	for i := range g.calls {
+		self[i] = calls[i][i]
+		for j := range g.calls {
+			if j != i && calls[i][j] && calls[j][i] {
+				peers[i] = append(peers[i], j)
+			}
+		}
+	}
+	return
+}
+
+// applyRecursion sets the recursion of the functions, aligned with the call graph nodes
+func applyRecursion(g callGraph, funcs []FuncResult) {
+	self, peers := g.recursion()
+	for i := range funcs {
+		f := &funcs[i].FuncStatsType
+		f.CallsItself = self[i]
+		for _, j := range peers[i] {
+			f.RecursiveWith = append(f.RecursiveWith, funcs[j].FunctionName)
+		}
+		f.Recursive = f.CallsItself || len(f.RecursiveWith) > 0
+		f.IsFlaggedRecursive = FlagRecursion && f.Recursive
+	}
+}
+
+// RecursionNote describes the recursion of the function, like "calls itself" or "mutually recursive with g", "" if none
+func RecursionNote(stats FuncStatsType) string {
+	notes := []string{}
+	if stats.CallsItself {
+		notes = append(notes, "calls itself")
+	}
+	if len(stats.RecursiveWith) > 0 {
+		notes = append(notes, fmt.Sprintf("mutually recursive with %s", strings.Join(stats.RecursiveWith, ", ")))
+	}
+	return strings.Join(notes, ", ")
+}
@@ -0,0 +1,36 @@
+package complexity
+
+import "fmt"
+
+// Function diagnostics modes
+const (
+	// ReportViolations reports the functions violating a threshold, by their first violation
+	ReportViolations = "violations"
+	// ReportAll reports every function with its metrics, which analysistest fixtures can assert with want comments
+	ReportAll = "all"
+	// ReportNone reports no function, the results are consumed by the callbacks only
+	ReportNone = "none"
+)
+
+// Report is the function diagnostics mode, ReportViolations, ReportAll or ReportNone
+var Report = ReportViolations
+
+func init() {
+	Analyzer.Flags.Var(reportFlag{}, "report", "'violations' reports the functions violating a threshold, 'all' every function with its metrics, 'none' no function")
+}
+
+// reportFlag is flag.Value of the -report option
+type reportFlag struct{}
+
+func (reportFlag) String() string {
+	return Report
+}
+
+func (reportFlag) Set(val string) error {
+	switch val {
+	case ReportViolations, ReportAll, ReportNone:
+		Report = val
+		return nil
+	}
+	return fmt.Errorf("unknown report mode %q, valid are: %s, %s, %s", val, ReportViolations, ReportAll, ReportNone)
+}
@@ -0,0 +1,98 @@
+package complexity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScoreWeights are the weights of the normalized metrics combined into the risk score, summing to 1
+var ScoreWeights = scoreWeights{"cyclo": 0.5, "maint": 0.3, "loc": 0.2, "halstvol": 0}
+
+// ScoreOver is the risk score threshold, 0 disables the check
+var ScoreOver float64
+
+// the lines of code and the Halstead volume normalized to 0.5, the cyclomatic complexity is normalized by -cycloover
+const (
+	scoreLOCScale    = 50
+	scoreVolumeScale = 1000
+)
+
+func init() {
+	Analyzer.Flags.Var(&ScoreWeights, "score-weights", "weights of the risk score, summing to 1, as cyclo=W,maint=W,loc=W,halstvol=W, omitted ones are 0")
+	Analyzer.Flags.Float64Var(&ScoreOver, "scoreover", 0, "print functions with the risk score > X, between 0 and 1 (0 disables the check)")
+}
+
+// scoreWeights is flag.Value of the risk score weights
+type scoreWeights map[string]float64
+
+func (w scoreWeights) String() string {
+	return fmt.Sprintf("cyclo=%g,maint=%g,loc=%g,halstvol=%g", w["cyclo"], w["maint"], w["loc"], w["halstvol"])
+}
+
+// Set replaces all weights, the omitted ones become 0
+func (w scoreWeights) Set(val string) error {
+	weights := map[string]float64{}
+	for _, kv := range strings.Split(val, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if _, known := w[k]; !ok || !known {
+			return fmt.Errorf("invalid score weight %q, expected one of %s as name=weight", kv, strings.Join(w.names(), ","))
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid score weight %q, expected a non-negative number", kv)
+		}
+		weights[k] = f
+	}
+	total := 0.0
+	for _, f := range weights {
+		total += f
+	}
+	if math.Abs(total-1) > 1e-9 {
+		return fmt.Errorf("score weights %q sum to %g, expected 1", val, total)
+	}
+	for k := range w {
+		w[k] = weights[k]
+	}
+	return nil
+}
+
+func (w scoreWeights) names() []string {
+	names := []string{}
+	for k := range w {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// saturate normalizes the non-negative value to 0-1, with scale normalized to 0.5
+func saturate(value, scale float64) float64 {
+	if value <= 0 || scale <= 0 {
+		return 0
+	}
+	return value / (value + scale)
+}
+
+// RiskScore combines the metrics of the function into a single number between 0 and 1, higher being riskier,
+// weighted by ScoreWeights. The cyclomatic complexity, the lines of code and the Halstead volume saturate,
+// the complexity at -cycloover being 0.5, and the Maintainability index is taken as its distance from the best one.
+func RiskScore(stats FuncStatsType) float64 {
+	best := float64(MaxMaintIndex())
+	maint := 0.0
+	if best > 0 {
+		maint = math.Min(1, math.Max(0, (best-float64(stats.MaintenabilityIndex))/best))
+	}
+	return ScoreWeights["cyclo"]*saturate(float64(stats.CyclomaticComplexity), float64(max(CycloOver, 1))) +
+		ScoreWeights["maint"]*maint +
+		ScoreWeights["loc"]*saturate(float64(stats.LOC), scoreLOCScale) +
+		ScoreWeights["halstvol"]*saturate(stats.HalsteadVolume, scoreVolumeScale)
+}
+
+// applyScore sets the risk score of the function from its metrics
+func applyScore(stats *FuncStatsType) {
+	stats.Score = RiskScore(*stats)
+	stats.IsTooRisky = ScoreOver > 0 && stats.Score > ScoreOver
+}
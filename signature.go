@@ -0,0 +1,31 @@
+package complexity
+
+import "go/ast"
+
+// Parameters and results thresholds, 0 disables the check
+var (
+	ParamsOver  int
+	ResultsOver int
+)
+
+func init() {
+	Analyzer.Flags.IntVar(&ParamsOver, "paramsover", 0, "print functions with more than N parameters, receiver excluded (0 disables the check)")
+	Analyzer.Flags.IntVar(&ResultsOver, "resultsover", 0, "print functions with more than N results (0 disables the check)")
+}
+
+// countFields counts the fields of the list, expanding grouped ones like "a, b, c int" to 3.
+// Variadic parameter counts as one.
+func countFields(fl *ast.FieldList) int {
+	if fl == nil {
+		return 0
+	}
+	cnt := 0
+	for _, f := range fl.List {
+		if len(f.Names) == 0 {
+			cnt++
+		} else {
+			cnt += len(f.Names)
+		}
+	}
+	return cnt
+}
@@ -0,0 +1,194 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// suppression directives, optionally followed by a space and the reason
+const (
+	nolintDirective = "//nolint:"
+	ignoreDirective = "//complexity:ignore"
+)
+
+// SuppressionOf tells if the function is annotated with //nolint:complexity or //complexity:ignore,
+// in its doc comment or in a comment on its func line, and returns the reason given along.
+// The directives ignoring only some rules, like //complexity:ignore cyclo, are left to IgnoredRulesOf.
+// The file must be parsed with comments.
+func SuppressionOf(fset *token.FileSet, f *ast.File, fd *ast.FuncDecl) (suppressed bool, reason string) {
+	for _, c := range funcComments(fset, f, fd) {
+		if ok, rules, r, _ := parseSuppression(c.Text); ok && len(rules) == 0 {
+			return true, r
+		}
+	}
+	return false, ""
+}
+
+// IgnoredRulesOf returns the rules ignored by the //complexity:ignore <rules> directives of the function,
+// like //complexity:ignore cyclo,maint, and the reason given along. The file must be parsed with comments.
+// Directives with mistyped rules, like //complexity:ignore cylco, are reported via warnFnc and ignored.
+func IgnoredRulesOf(fset *token.FileSet, f *ast.File, fd *ast.FuncDecl, warnFnc func(pos token.Pos, msg string)) (rules []string, reason string) {
+	for _, c := range funcComments(fset, f, fd) {
+		ok, rs, r, err := parseSuppression(c.Text)
+		if err != nil {
+			warnFnc(c.Pos(), err.Error())
+		}
+		if ok && len(rs) > 0 {
+			rules = append(rules, rs...)
+			if reason == "" {
+				reason = r
+			}
+		}
+	}
+	return rules, reason
+}
+
+// ApplyIgnoredRules clears the violations of the IgnoredRules of the function, recording them as IgnoredViolations
+func ApplyIgnoredRules(stats *FuncStatsType) {
+	for _, r := range ruleFlags(stats) {
+		if *r.violated && slices.Contains(stats.IgnoredRules, r.name) {
+			*r.violated = false
+			stats.IgnoredViolations = append(stats.IgnoredViolations, r.name)
+		}
+	}
+}
+
+// funcComments are the comments of the doc comment of the function and those on its func line
+func funcComments(fset *token.FileSet, f *ast.File, fd *ast.FuncDecl) []*ast.Comment {
+	comments := []*ast.Comment{}
+	if fd.Doc != nil {
+		comments = append(comments, fd.Doc.List...)
+	}
+	tf := fset.File(fd.Pos())
+	line := tf.Line(fd.Pos())
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if c.Pos() > fd.Pos() && tf.Line(c.Pos()) == line {
+				comments = append(comments, c)
+			}
+		}
+	}
+	return comments
+}
+
+// parseSuppression parses a suppression directive comment, like "//nolint:complexity,lll // reason",
+// or "//complexity:ignore cyclo,maint reason" ignoring only the rules of its first word,
+// when they are all rule names, otherwise the whole text after the directive is the reason.
+// A first word looking like mistyped rules, a list or a near rule name, is an error rather than
+// a reason, so a typo does not widen the directive to the whole function.
+func parseSuppression(text string) (ok bool, rules []string, reason string, err error) {
+	directive, reason, _ := strings.Cut(text, " ")
+	reason = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reason), "//"))
+	if directive == ignoreDirective {
+		first, rest, _ := strings.Cut(reason, " ")
+		if rules := strings.Split(first, ","); first != "" && allRules(rules) {
+			return true, rules, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "//")), nil
+		}
+		if err := mistypedRules(first); err != nil {
+			return false, nil, "", err
+		}
+		return true, nil, reason, nil
+	}
+	if linters, ok := strings.CutPrefix(directive, nolintDirective); ok {
+		for _, l := range strings.Split(linters, ",") {
+			if l == "complexity" {
+				return true, nil, reason, nil
+			}
+		}
+	}
+	return false, nil, "", nil
+}
+
+// mistypedRules tells why the first word of a //complexity:ignore directive, which is not a list of rules,
+// looks like one: it is comma separated, or close to a rule name. Other words start the reason.
+func mistypedRules(first string) error {
+	for _, name := range strings.Split(first, ",") {
+		if allRules([]string{name}) {
+			continue
+		}
+		if near := nearRule(name); near != "" {
+			return fmt.Errorf("unknown rule %q in %s directive, did you mean %s? It is ignored", name, ignoreDirective, near)
+		}
+		if strings.Contains(first, ",") {
+			return fmt.Errorf("unknown rule %q in %s directive, valid are: %s. It is ignored", name, ignoreDirective, strings.Join(ruleNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// nearRule returns the rule name the word differs from by case or, for words of 4 letters or more,
+// by one inserted, deleted, replaced or swapped letter, "" if none
+func nearRule(word string) string {
+	for _, name := range ruleNames() {
+		if strings.EqualFold(word, name) || len(word) >= 4 && editDistance(word, name) <= 1 {
+			return name
+		}
+	}
+	return ""
+}
+
+// editDistance is the optimal string alignment distance of a and b, where swapped adjacent letters count once
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// ruleNames are the names of all rules, in the order of Violations
+func ruleNames() []string {
+	names := []string{}
+	for _, r := range ruleFlags(&FuncStatsType{}) {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// allRules tells if all names are rule names, like cyclo or maint
+func allRules(names []string) bool {
+	for _, name := range names {
+		if !slices.Contains(ruleNames(), name) {
+			return false
+		}
+	}
+	return true
+}
+
+// EntryPointReason is the suppression reason of the entry points skipped by -skip-entrypoints
+const EntryPointReason = "entry point"
+
+// SkipEntryPoints suppresses the entry points, func main of package main and the func init functions
+var SkipEntryPoints bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&SkipEntryPoints, "skip-entrypoints", false, "suppress func main of package main and all func init functions, like wiring code annotated with //complexity:ignore")
+}
+
+// IsEntryPoint tells if the function is func main of package main or a func init.
+// Methods named main or init are no entry points.
+func IsEntryPoint(pkgName string, fd *ast.FuncDecl) bool {
+	if fd.Recv != nil {
+		return false
+	}
+	return fd.Name.Name == "init" || fd.Name.Name == "main" && pkgName == "main"
+}
@@ -0,0 +1,43 @@
+package apireach
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Server struct{}
+
+func (s *Server) ServeHTTP(path string) { // want "Cyclomatic complexity: 2"
+	if path == "" {
+		return
+	}
+	s.route(path)
+}
+
+func (s *Server) route(path string) { // want "Cyclomatic complexity: 2"
+	if strings.HasPrefix(path, "/a") {
+		ping(3)
+	}
+}
+
+// ping and pong are mutually recursive
+func ping(n int) { // want "Cyclomatic complexity: 2"
+	if n > 0 {
+		pong(n - 1)
+	}
+}
+
+func pong(n int) { // want "Cyclomatic complexity: 2"
+	if n > 0 {
+		ping(n - 1)
+	}
+	fmt.Println(n)
+}
+
+func Ping() { // want "Cyclomatic complexity: 1"
+	ping(1)
+}
+
+func Alone() { // want "Cyclomatic complexity: 1"
+	fmt.Println(strings.ToUpper("x"))
+}
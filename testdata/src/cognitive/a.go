@@ -0,0 +1,66 @@
+package cognitive
+
+// flat switch is easy to read, cyclomatic and cognitive agree on little
+func flatSwitch(n int) string { // want "Cyclomatic complexity: 2, .*, Cognitive complexity: 1$"
+	switch n {
+	case 0:
+		return "zero"
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	default:
+		return "many"
+	}
+}
+
+// nested ifs are penalized by their nesting level
+func nestedIfs(a, b, c bool) { // want "Cyclomatic complexity: 4, .*, Cognitive complexity: 6$"
+	if a {
+		if b {
+			if c {
+				println()
+			}
+		}
+	}
+}
+
+// else-if and else are not penalized for nesting
+func elseIfChain(n int) { // want "Cyclomatic complexity: 5, .*, Cognitive complexity: 4$"
+	if n < 0 {
+		println("negative")
+	} else if n == 0 {
+		println("zero")
+	} else if n < 10 {
+		println("small")
+	} else {
+		println("large")
+	}
+}
+
+// closures increase nesting, mixed boolean operators count per sequence
+func withClosure(items []int) { // want "Cyclomatic complexity: 5, .*, Cognitive complexity: 7$"
+	f := func(i int) bool {
+		if i > 0 {
+			return true
+		}
+		return false
+	}
+	for _, i := range items {
+		if f(i) && i < 10 || i > 100 {
+			println(i)
+		}
+	}
+}
+
+// labeled continue is an extra jump to follow
+func labeled(m [][]int) { // want "Cyclomatic complexity: 4, .*, Cognitive complexity: 7$"
+outer:
+	for _, r := range m {
+		for _, v := range r {
+			if v < 0 {
+				continue outer
+			}
+		}
+	}
+}
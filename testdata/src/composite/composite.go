@@ -0,0 +1,25 @@
+package composite
+
+func config() map[string][]int { // want "Cyclomatic complexity: 1, Halstead difficulty: 7.500, volume: 174.229"
+	ports := map[string][]int{
+		"http":  {80, 8080},
+		"https": {443},
+	}
+	limits := [3]int{1, 10, 100}
+	ports["limits"] = limits[:]
+	return ports
+}
+
+func points() interface{} { // want "Cyclomatic complexity: 1, Halstead difficulty: 9.455, volume: 215.493"
+	type point struct {
+		x, y int
+		tag  string
+	}
+	return []struct {
+		p    point
+		next *point
+	}{
+		{p: point{1, 2, "a"}},
+		{p: point{x: 3}, next: &point{y: 4}},
+	}
+}
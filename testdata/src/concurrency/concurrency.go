@@ -0,0 +1,72 @@
+package concurrency
+
+import "sync"
+
+// pool starts n workers squaring the jobs, and waits for them
+func pool(n int, jobs []int) []int { // want "Cyclomatic complexity: 7"
+	in, out := make(chan int), make(chan int, len(jobs))
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range in {
+				out <- j * j
+			}
+		}()
+	}
+	for _, j := range jobs {
+		in <- j
+	}
+	close(in)
+	wg.Wait()
+	close(out)
+	res := []int{}
+	for r := range out {
+		res = append(res, r)
+	}
+	return res
+}
+
+// merge fans in two channels until both are closed
+func merge(a, b <-chan int) <-chan int { // want "Cyclomatic complexity: 3"
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for a != nil || b != nil {
+			select {
+			case v, ok := <-a:
+				if !ok {
+					a = nil
+					continue
+				}
+				out <- v
+			case v, ok := <-b:
+				if !ok {
+					b = nil
+					continue
+				}
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+type counter struct {
+	sync.Mutex
+	n int
+}
+
+// inc locks the embedded mutex
+func (c *counter) inc() { // want "Cyclomatic complexity: 1"
+	c.Lock()
+	defer c.Unlock()
+	c.n++
+}
+
+// sequential has no concurrency, Add is not of sync
+func (c *counter) Add(n int) int { // want "Cyclomatic complexity: 1"
+	c.n += n
+	return c.n
+}
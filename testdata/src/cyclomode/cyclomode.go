@@ -0,0 +1,17 @@
+package cyclomode
+
+// valid has 3 control flow statements and 5 short-circuit operators,
+// in an if condition, a for condition, a case expression and an assignment
+func valid(s string, n int) bool { // want "Cyclomatic complexity: 9"
+	if s == "" || n < 0 {
+		return false
+	}
+	for i := 0; i < n && i < len(s); i++ {
+		switch {
+		case s[i] == ' ' && i == 0:
+			return false
+		}
+	}
+	ok := n > 0 && len(s) >= n || n == 0
+	return ok
+}
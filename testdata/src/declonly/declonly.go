@@ -0,0 +1,17 @@
+package declonly
+
+import _ "unsafe"
+
+// add is implemented in assembly
+func add(a, b int) int // want "Cyclomatic complexity: 1, Halstead difficulty: 0.000, volume: 0.000"
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64 // want "Cyclomatic complexity: 1, Halstead difficulty: 0.000, volume: 0.000"
+
+func sum(xs []int) int { // want "Cyclomatic complexity: 2"
+	s := 0
+	for _, x := range xs {
+		s = add(s, x)
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package defers
+
+import (
+	"os"
+	"sync"
+)
+
+// sequential defers two closes, both pending when it returns
+func sequential(a, b string) error { // want "Cyclomatic complexity: 3"
+	fa, err := os.Open(a)
+	if err != nil {
+		return err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return err
+	}
+	defer fb.Close()
+	return nil
+}
+
+// branches defers in either branch, one of them pending, and once in a range body
+func branches(names []string, mu *sync.Mutex, write bool) { // want "Cyclomatic complexity: 4"
+	if write {
+		mu.Lock()
+		defer mu.Unlock()
+	} else {
+		defer println("read")
+	}
+	for _, name := range names {
+		f, _ := os.Open(name)
+		defer f.Close()
+	}
+}
+
+// closures defers only within its function literals, which do not count toward it
+func closures(names []string) { // want "Cyclomatic complexity: 2"
+	for _, name := range names {
+		func() {
+			f, _ := os.Open(name)
+			defer f.Close()
+		}()
+	}
+	cleanup := func() {
+		for i := 0; i < 2; i++ {
+			defer println(i)
+		}
+	}
+	cleanup()
+}
+
+// switches defers in two cases, one of them pending, and in a labeled loop
+func switches(n int) { // want "Cyclomatic complexity: 3"
+	switch n {
+	case 1:
+		defer println(1)
+		defer println(2)
+	case 2:
+		defer println(3)
+	}
+outer:
+	for {
+		defer println(4)
+		break outer
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import "os"
+
+var verbose, dryRun, color, quiet, force, debug, trace, strict, legacy, fast bool
+
+func main() { // want "Cyclomatic complexity: 11"
+	if len(os.Args) > 1 {
+		verbose = true
+	}
+	if len(os.Args) > 2 {
+		dryRun = true
+	}
+	if len(os.Args) > 3 {
+		color = true
+	}
+	if len(os.Args) > 4 {
+		quiet = true
+	}
+	if len(os.Args) > 5 {
+		force = true
+	}
+	if len(os.Args) > 6 {
+		debug = true
+	}
+	if len(os.Args) > 7 {
+		trace = true
+	}
+	if len(os.Args) > 8 {
+		strict = true
+	}
+	if len(os.Args) > 9 {
+		legacy = true
+	}
+	if len(os.Args) > 10 {
+		fast = true
+	}
+}
+
+func init() { // want "Cyclomatic complexity: 11"
+	v := os.Getenv("FLAGS")
+	verbose = v == "1" || v == "2" || v == "3" || v == "4" || v == "5" || v == "6" || v == "7" || v == "8" || v == "9" || v == "10" || v == "11"
+}
+
+func helper(args []string) int { // want "Cyclomatic complexity: 11"
+	n := 0
+	for _, a := range args {
+		if a == "-v" || a == "-q" || a == "-f" || a == "-d" || a == "-t" || a == "-s" || a == "-l" || a == "-x" || a == "-c" {
+			n++
+		}
+	}
+	return n
+}
+
+type app struct{}
+
+// main is a method, not the entry point
+func (app) main(a string) bool { // want "Cyclomatic complexity: 11"
+	return a == "1" || a == "2" || a == "3" || a == "4" || a == "5" || a == "6" || a == "7" || a == "8" || a == "9" || a == "10" || a == "11"
+}
@@ -0,0 +1,15 @@
+package exclude
+
+type Server struct{}
+
+type List[T any] struct{ items []T }
+
+func (s *Server) Close() error { return nil }
+
+func (s Server) Name() string { return "server" }
+
+func (l *List[T]) Len() int { return len(l.items) }
+
+func Close() {}
+
+func TestLike() {}
@@ -0,0 +1,3 @@
+package exclude
+
+func (s *Server) DeepCopy() *Server { return &Server{} }
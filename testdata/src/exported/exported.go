@@ -0,0 +1,40 @@
+package exported
+
+// Store is exported, its exported methods are part of the API
+type Store struct{ n int }
+
+// internalThing is unexported, so are its methods regardless of their name
+type internalThing struct{ n int }
+
+func New(n int) *Store { // want "Cyclomatic complexity: 2"
+	if n < 0 {
+		n = 0
+	}
+	return &Store{n: n}
+}
+
+func helper(n int) int { // want "Cyclomatic complexity: 2"
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (s *Store) Add(n int) { // want "Cyclomatic complexity: 2"
+	if n > 0 {
+		s.n += n
+	}
+}
+
+func (s *Store) reset() { // want "Cyclomatic complexity: 2"
+	if s.n != 0 {
+		s.n = 0
+	}
+}
+
+func (t internalThing) PublicName() int { // want "Cyclomatic complexity: 2"
+	if t.n > 0 {
+		return t.n
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package fanout
+
+import (
+	"fmt"
+	"strings"
+)
+
+type greeter struct {
+	name string
+	hook func(string)
+}
+
+func (g *greeter) greet() string { // want "Cyclomatic complexity: 1"
+	return "hello " + g.name
+}
+
+func (g greeter) shout() string { // want "Cyclomatic complexity: 1"
+	return strings.ToUpper(g.greet())
+}
+
+// qualified calls fmt.Println three times, strings.ToUpper once and converts once
+func qualified(names []string) { // want "Cyclomatic complexity: 2"
+	fmt.Println("names:", len(names))
+	for _, n := range names {
+		fmt.Println(strings.ToUpper(n))
+	}
+	fmt.Println(string([]byte("done")))
+}
+
+// methods calls greet twice, directly and through shout, and shout once
+func methods(g *greeter) string { // want "Cyclomatic complexity: 1"
+	s := g.greet()
+	return s + g.shout() + g.greet()
+}
+
+// shadowed calls the local len and the local print, not the built-ins
+func shadowed(names []string) int { // want "Cyclomatic complexity: 1"
+	len := func(s []string) int { return cap(s) }
+	print := fmt.Sprint
+	print(names)
+	return len(names)
+}
+
+// indirect calls a parameter, a field, a closure and a builtin, and a function within a closure
+func indirect(g *greeter, cb func() int, names []string) int { // want "Cyclomatic complexity: 1"
+	g.hook("start")
+	names = append(names, "x")
+	done := func() { fmt.Println(len(names)) }
+	done()
+	return cb()
+}
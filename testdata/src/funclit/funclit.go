@@ -0,0 +1,57 @@
+package funclit
+
+import (
+	"strings"
+	"sync"
+)
+
+var upper = func(s string) string {
+	return strings.ToUpper(s)
+}
+
+func spawn(jobs []int) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sum := 0
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			if j < 0 {
+				return
+			}
+			mu.Lock()
+			sum += j
+			mu.Unlock()
+		}(j)
+	}
+	wg.Wait()
+	return sum
+}
+
+type testingT struct{}
+
+func (t *testingT) Run(name string, f func(t *testingT)) {
+	f(t)
+}
+
+func tableDriven(t *testingT) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"lower", "a", "A"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testingT) {
+			if got := upper(tc.in); got != tc.want && tc.want != "" {
+				check := func() bool {
+					return got == ""
+				}
+				_ = check()
+			}
+		})
+	}
+}
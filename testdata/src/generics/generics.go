@@ -0,0 +1,40 @@
+package generics
+
+import "strconv"
+
+type number interface {
+	~int | ~int64 | ~float64
+}
+
+func Sum[T number](xs []T) T { // want "Cyclomatic complexity: 2, Halstead difficulty: 9.600, volume: 89.924, Cognitive complexity: 1"
+	var s T
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+func Map[T, U any](xs []T, f func(T) U) []U { // want "Cyclomatic complexity: 2, Halstead difficulty: 14.667, volume: 156.080, Cognitive complexity: 1"
+	out := make([]U, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+func Clamp[T interface{ ~int | ~float64 }](x, lo, hi T) T { // want "Cyclomatic complexity: 3, Halstead difficulty: 17.500, volume: 120.928, Cognitive complexity: 2"
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+func use() { // want "Cyclomatic complexity: 1, Halstead difficulty: 11.250, volume: 166.908, Cognitive complexity: 0"
+	ints := []int{1, 2, 3}
+	_ = Sum[int](ints)
+	_ = Map[int, string](ints, strconv.Itoa)
+	_ = Clamp(Sum(ints), 0, 5)
+}
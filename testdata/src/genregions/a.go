@@ -0,0 +1,21 @@
+package genregions
+
+func hand() { // want "Cyclomatic complexity: 1"
+}
+
+// BEGIN GENERATED
+
+func gen1() { // want "Cyclomatic complexity: 1"
+}
+
+func gen2() { // want "Cyclomatic complexity: 2"
+	if true {
+	}
+}
+
+// END GENERATED
+
+func hand2() { // want "Cyclomatic complexity: 1"
+}
+
+// END GENERATED // want "unbalanced generated code marker"
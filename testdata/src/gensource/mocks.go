@@ -0,0 +1,22 @@
+package gensource
+
+func handWritten() {
+}
+
+//go:generate mockgen -source=store.go -destination=mocks.go -package=gensource
+
+func mockGet(key string) string {
+	return key
+}
+
+func mockPut(key, value string) {
+}
+
+//go:generate stringer -type=Kind
+
+func kindString(k int) string {
+	if k == 0 {
+		return "zero"
+	}
+	return "other"
+}
@@ -12,7 +12,7 @@ func f2() { // want "Cyclomatic complexity: 1, Halstead difficulty: 6.857, volum
 	println(avg)
 }
 
-func f3() { // want "Cyclomatic complexity: 3, Halstead difficulty: 0.000, volume: 25.266"
+func f3() { // want "Cyclomatic complexity: 3, Halstead difficulty: 3.000, volume: 25.266"
 	if false {
 
 	} else {
@@ -20,7 +20,7 @@ func f3() { // want "Cyclomatic complexity: 3, Halstead difficulty: 0.000, volum
 	}
 }
 
-func f4() { // want "Cyclomatic complexity: 8, Halstead difficulty: 10.833, volume: 144.000"
+func f4() { // want "Cyclomatic complexity: 8, Halstead difficulty: 11.000, volume: 144.000"
 	for true {
 		if false {
 
@@ -19,7 +19,7 @@ func comp3() { // want "Cyclomatic complexity: 3, Halstead difficulty: 3.500, vo
 	fmt.Println("world")
 }
 
-func comp4() { // want "Cyclomatic complexity: 5, Halstead difficulty: 12.000, volume: 101.579"
+func comp4() { // want "Cyclomatic complexity: 4, Halstead difficulty: 12.000, volume: 101.579"
 	a := make(chan string)
 	go func() { a <- "ping" }()
 
@@ -44,7 +44,7 @@ func comp6() { // want "Cyclomatic complexity: 4, Halstead difficulty: 7.500, vo
 	}
 }
 
-func comp7() { // want "Cyclomatic complexity: 4, Halstead difficulty: 9.000, volume: 88.000"
+func comp7() { // want "Cyclomatic complexity: 4, Halstead difficulty: 14.167, volume: 149.278"
 	c1 := make(chan string)
 
 	for i := 0; i < 2; i++ {
@@ -54,7 +54,7 @@ func comp7() { // want "Cyclomatic complexity: 4, Halstead difficulty: 9.000, vo
 		}
 	}
 }
-func comp8() { // want "Cyclomatic complexity: 2, Halstead difficulty: 6.300, volume: 76.147"
+func comp8() { // want "Cyclomatic complexity: 2, Halstead difficulty: 7.700, volume: 88.000"
 	a := []int{0, 1, 2}
 	for b := range a {
 		fmt.Println(b)
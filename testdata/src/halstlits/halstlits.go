@@ -0,0 +1,14 @@
+package halstlits
+
+func logs(n int) { // want "Cyclomatic complexity: 2"
+	println("starting")
+	println("loading the configuration")
+	if n > 10 {
+		println("too many workers, limiting to", 10)
+		n = 10
+	}
+	println("starting workers:", n)
+	println("waiting for", 3, "seconds")
+	println("ready", 0.5)
+	println("done")
+}
@@ -0,0 +1,12 @@
+package halstnorm
+
+import "strings"
+
+type point struct{ X, Y int }
+
+func norm(p point, s string) { // want "Cyclomatic complexity: 1, Halstead difficulty: 10.000, volume: 110.361"
+	p.X = p.Y
+	Total := strings.ToUpper(s) + "x"
+	total := "x"
+	println(p.X, Total, total, "x")
+}
@@ -0,0 +1,73 @@
+package locals
+
+import "strconv"
+
+// specs declares a, b, c, d and e, the blank identifiers are not variables
+func specs(s string) (n int, _ error) { // want "Cyclomatic complexity: 1"
+	var a, b, _ int
+	var (
+		c    = 1
+		d, _ = strconv.Atoi(s)
+	)
+	e, _ := strconv.Atoi(s)
+	const f = 2
+	return a + b + c + d + e + f, nil
+}
+
+// redeclared re-assigns err, declaring only m beside n and err
+func redeclared(s string) int { // want "Cyclomatic complexity: 2"
+	n, err := strconv.Atoi(s)
+	m, err := strconv.Atoi(s + "0")
+	if err != nil {
+		return 0
+	}
+	return n + m
+}
+
+// shadowed declares x twice, the second one in the if scope, and v in its init
+func shadowed(x int) int { // want "Cyclomatic complexity: 2"
+	y := x
+	if v := y * 2; v > 10 {
+		y := v
+		return y
+	}
+	return y
+}
+
+// loops declares the range key and value, i, and the type switch symbol once
+func loops(items []interface{}) (total int) { // want "Cyclomatic complexity: 3"
+	for i := 0; i < len(items); i++ {
+		total += i
+	}
+	for k, item := range items {
+		switch v := item.(type) {
+		case int:
+			total += v + k
+		case string:
+			total += len(v)
+		}
+	}
+	return
+}
+
+// closure declares sum, add and v, the variables of the closure belong to it
+func closure(values []int) int { // want "Cyclomatic complexity: 2"
+	sum := 0
+	add := func(v int) {
+		doubled := v * 2
+		sum += doubled
+	}
+	for _, v := range values {
+		add(v)
+	}
+	return sum
+}
+
+// localTypes declares cb and anon, the fields and parameters of local types are no variables
+func localTypes() int { // want "Cyclomatic complexity: 1"
+	type pt struct{ x, y, z int }
+	var cb func(a, b, c int)
+	var anon struct{ p, q int }
+	_ = cb
+	return pt{}.x + anon.p
+}
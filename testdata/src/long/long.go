@@ -0,0 +1,47 @@
+package long
+
+import "strings"
+
+// render is long but simple, straight code the complexity thresholds miss
+func render(name string) string { // want "Cyclomatic complexity: 1"
+	var b strings.Builder
+	b.WriteString("line 0 of " + name + "\n")
+	b.WriteString("line 1 of " + name + "\n")
+	b.WriteString("line 2 of " + name + "\n")
+	b.WriteString("line 3 of " + name + "\n")
+	b.WriteString("line 4 of " + name + "\n")
+	b.WriteString("line 5 of " + name + "\n")
+	b.WriteString("line 6 of " + name + "\n")
+	b.WriteString("line 7 of " + name + "\n")
+	b.WriteString("line 8 of " + name + "\n")
+	b.WriteString("line 9 of " + name + "\n")
+	b.WriteString("line 10 of " + name + "\n")
+	b.WriteString("line 11 of " + name + "\n")
+	b.WriteString("line 12 of " + name + "\n")
+	b.WriteString("line 13 of " + name + "\n")
+	b.WriteString("line 14 of " + name + "\n")
+	b.WriteString("line 15 of " + name + "\n")
+	b.WriteString("line 16 of " + name + "\n")
+	b.WriteString("line 17 of " + name + "\n")
+	b.WriteString("line 18 of " + name + "\n")
+	b.WriteString("line 19 of " + name + "\n")
+	b.WriteString("line 20 of " + name + "\n")
+	b.WriteString("line 21 of " + name + "\n")
+	b.WriteString("line 22 of " + name + "\n")
+	b.WriteString("line 23 of " + name + "\n")
+	b.WriteString("line 24 of " + name + "\n")
+	b.WriteString("line 25 of " + name + "\n")
+	b.WriteString("line 26 of " + name + "\n")
+	b.WriteString("line 27 of " + name + "\n")
+	b.WriteString("line 28 of " + name + "\n")
+	b.WriteString("line 29 of " + name + "\n")
+
+	// the comment and blank lines are not counted
+
+	return b.String()
+}
+
+// short is under any length limit
+func short() string { // want "Cyclomatic complexity: 1"
+	return render("short")
+}
@@ -0,0 +1,52 @@
+package micomments
+
+// route picks the handler of a request.
+//
+// Requests are matched by method first, then by path prefix:
+// the most specific prefix wins, so /api/v2 beats /api.
+// Unknown methods are rejected before any path is looked at,
+// which keeps the error messages of the clients consistent.
+func route(method, path string, authenticated bool) string { // want "Cyclomatic complexity: 10"
+	// read-only methods do not need a session
+	if method == "GET" || method == "HEAD" {
+		// versioned endpoints first, they shadow the legacy ones
+		if len(path) > 7 && path[:7] == "/api/v2" {
+			return "v2"
+		}
+		// the legacy endpoints are kept for older clients
+		if len(path) > 4 && path[:4] == "/api" {
+			return "v1"
+		}
+		// everything else is a static asset
+		return "static"
+	}
+	// writes need a session, checked once here
+	if !authenticated {
+		return "denied"
+	}
+	// only the versioned api accepts writes
+	if method == "POST" && len(path) > 7 {
+		return "v2"
+	}
+	// anything else is not supported
+	return "unsupported"
+}
+
+func routeTerse(method, path string, authenticated bool) string { // want "Cyclomatic complexity: 10"
+	if method == "GET" || method == "HEAD" {
+		if len(path) > 7 && path[:7] == "/api/v2" {
+			return "v2"
+		}
+		if len(path) > 4 && path[:4] == "/api" {
+			return "v1"
+		}
+		return "static"
+	}
+	if !authenticated {
+		return "denied"
+	}
+	if method == "POST" && len(path) > 7 {
+		return "v2"
+	}
+	return "unsupported"
+}
@@ -0,0 +1,52 @@
+package nesting
+
+// flat has no control statements
+func flat(a, b int) int { // want "Cyclomatic complexity: 1"
+	return a + b
+}
+
+// chain is as deep as its first if, however long the else if chain
+func chain(n int) string { // want "Cyclomatic complexity: 5"
+	if n < 0 {
+		return "negative"
+	} else if n == 0 {
+		return "zero"
+	} else if n < 10 {
+		return "small"
+	} else {
+		return "large"
+	}
+}
+
+// deep nests an if in a switch case in a range in a for
+func deep(rows [][]int) int { // want "Cyclomatic complexity: 5"
+	sum := 0
+	for i := 0; i < 2; i++ {
+		for _, row := range rows {
+			switch len(row) {
+			case 0:
+				continue
+			default:
+				if row[0] > 0 {
+					sum += row[0]
+				}
+			}
+		}
+	}
+	return sum
+}
+
+// closure has its if nested in the literal, which has its own nesting
+func closure(xs []int) func() int { // want "Cyclomatic complexity: 2"
+	if len(xs) == 0 {
+		return nil
+	}
+	return func() int {
+		for _, x := range xs {
+			if x > 0 {
+				return x
+			}
+		}
+		return 0
+	}
+}
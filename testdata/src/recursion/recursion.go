@@ -0,0 +1,50 @@
+package recursion
+
+func fact(n int) int { // want "Cyclomatic complexity: 2"
+	if n <= 1 {
+		return 1
+	}
+	return n * fact(n-1)
+}
+
+func isEven(n int) bool { // want "Cyclomatic complexity: 2"
+	if n == 0 {
+		return true
+	}
+	return isOdd(n - 1)
+}
+
+func isOdd(n int) bool { // want "Cyclomatic complexity: 2"
+	if n == 0 {
+		return false
+	}
+	return isEven(n - 1)
+}
+
+type node struct {
+	children []*node
+}
+
+// size recurses through a closure
+func (n *node) size() int { // want "Cyclomatic complexity: 2"
+	total := 1
+	for _, c := range n.children {
+		total += func() int { return c.size() }()
+	}
+	return total
+}
+
+type inner struct{}
+
+func (inner) String() string { // want "Cyclomatic complexity: 1"
+	return "inner"
+}
+
+type outer struct {
+	in inner
+}
+
+// String calls the same-named method of another type, it is not recursive
+func (o outer) String() string { // want "Cyclomatic complexity: 1"
+	return "outer(" + o.in.String() + ")"
+}
@@ -0,0 +1,9 @@
+package skiptests_test
+
+import "testing"
+
+func TestExternal(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+}
@@ -0,0 +1,9 @@
+package skiptests
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	for _, c := range []struct{ got, want int }{{1, 1}, {2, 2}} {
+		check(t, c.got, c.want)
+	}
+}
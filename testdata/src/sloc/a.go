@@ -0,0 +1,51 @@
+package sloc
+
+// documented has the same code as terse, but explains itself at length
+func documented(items []int) int { // want "Cyclomatic complexity: 3"
+	// sum up all positive items
+	//
+	// negative items are ignored on purpose:
+	// they represent refunds which are accounted elsewhere,
+	// see the accounting package for the details.
+	//
+	// zero items are kept, they do not change the sum anyway.
+
+	sum := 0
+
+	/*
+		the loop is intentionally not parallelized,
+		the lists are short and the order of additions
+		must be stable for the floating point variant.
+	*/
+	for _, i := range items {
+
+		// skip refunds
+		if i < 0 {
+			continue
+		}
+
+		sum += i // accumulate
+	}
+
+	// done
+	return sum
+}
+
+func terse(items []int) int { // want "Cyclomatic complexity: 3"
+	sum := 0
+	for _, i := range items {
+		if i < 0 {
+			continue
+		}
+		sum += i
+	}
+	return sum
+}
+
+func multiline() string { // want "Cyclomatic complexity: 1"
+	s := `first
+second
+
+fourth`
+	return s
+}
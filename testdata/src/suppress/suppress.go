@@ -0,0 +1,41 @@
+package suppress
+
+// dispatch is a giant but simple switch
+//
+//nolint:complexity // one case per opcode
+func dispatch(op int) string {
+	switch op {
+	case 1:
+		return "add"
+	case 2:
+		return "sub"
+	case 3:
+		return "mul"
+	}
+	return "nop"
+}
+
+func decode(op int) string { //complexity:ignore generated from the opcode table
+	switch op {
+	case 1:
+		return "add"
+	case 2:
+		return "sub"
+	case 3:
+		return "mul"
+	}
+	return "nop"
+}
+
+//nolint:lll,gocyclo
+func encode(op string) int {
+	switch op {
+	case "add":
+		return 1
+	case "sub":
+		return 2
+	case "mul":
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package suppress
+
+// lookup is kept flat, like the protocol table
+//
+//complexity:ignore cyclo,maint one branch per protocol version
+func lookup(v int) string {
+	if v == 1 {
+		return "one"
+	}
+	if v == 2 {
+		return "two"
+	}
+	if v == 3 {
+		return "three"
+	}
+	if v == 4 {
+		return "four"
+	}
+	return "none"
+}
+
+//complexity:ignore maint
+func clamp(v int) int {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
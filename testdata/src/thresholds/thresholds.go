@@ -0,0 +1,38 @@
+package thresholds
+
+// loop is allowed to branch a lot
+//
+//complexity:max-cyclo=40 parser main loop
+func loop(tokens []string) int {
+	n := 0
+	for _, t := range tokens {
+		if t == "(" {
+			n++
+		} else if t == ")" {
+			n--
+		}
+	}
+	return n
+}
+
+// strict is held to a higher bar
+//
+//complexity:max-cyclo=1
+//complexity:min-maint=100
+func strict(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+// typos are reported
+//
+//complexity:max-cylco=5
+//complexity:min-maint=ten
+func typos(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
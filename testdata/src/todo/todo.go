@@ -0,0 +1,17 @@
+package todo
+
+// TODO: document, not counted as it is outside of the bodies
+func branchy(a, b int) string {
+	// TODO: split up the branches, FIXME the order
+	if a > b {
+		return "gt"
+	} else if a < b {
+		return "lt" // HACK until the comparator lands
+	}
+	return "TODO: a string, not a marker"
+}
+
+func simple() int {
+	// XXX: hard coded
+	return 42
+}
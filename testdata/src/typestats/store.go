@@ -0,0 +1,37 @@
+package typestats
+
+import "sync"
+
+type base struct {
+	id      int
+	created string
+}
+
+// Store embeds base and a mutex, each counting as one field
+type Store struct {
+	base
+	*sync.Mutex
+	items      map[string]int
+	name, kind string
+}
+
+func (s *Store) Get(k string) int { // want "Cyclomatic complexity: 1"
+	return s.items[k]
+}
+
+func (s *Store) Put(k string, v int) { // want "Cyclomatic complexity: 1"
+	s.items[k] = v
+}
+
+type Reader interface {
+	Read(k string) int
+}
+
+// ReadWriter embeds Reader, counting as one method
+type ReadWriter interface {
+	Reader
+	Write(k string, v int)
+	Close() error
+}
+
+type Names []string
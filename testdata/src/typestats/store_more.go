@@ -0,0 +1,17 @@
+package typestats
+
+func (s Store) Len() int { // want "Cyclomatic complexity: 1"
+	return len(s.items)
+}
+
+func (n Names) Len() int { // want "Cyclomatic complexity: 1"
+	return len(n)
+}
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) { // want "Cyclomatic complexity: 1"
+	l.items = append(l.items, v)
+}
@@ -0,0 +1,33 @@
+package typeswitch
+
+import "fmt"
+
+func describe(v interface{}) string { // want "Cyclomatic complexity: 1, Halstead difficulty: 12.375, volume: 197.418, Cognitive complexity: 1"
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case int, int64:
+		return fmt.Sprint("int ", x)
+	case string:
+		return "string " + x
+	case error:
+		return "error " + x.Error()
+	default:
+		return "unknown"
+	}
+}
+
+func retry(attempts int) { // want "Cyclomatic complexity: 4, Halstead difficulty: 10.800, volume: 106.274, Cognitive complexity: 6"
+	i := 0
+again:
+	if i < attempts {
+		i++
+		goto again
+	}
+outer:
+	for {
+		for {
+			break outer
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// thresholdDirective starts the doc comment directives overriding a threshold for one function,
+// like //complexity:max-cyclo=40, optionally followed by a space and the reason
+const thresholdDirective = "//complexity:"
+
+// thresholdOverrides are the thresholds a function can override, by directive key
+var thresholdOverrides = map[string]func(stats *FuncStatsType, v int){
+	"max-cyclo": func(s *FuncStatsType, v int) {
+		s.CycloOver = v
+		s.IsTooComplex = s.CyclomaticComplexity > v
+	},
+	"min-maint": func(s *FuncStatsType, v int) {
+		s.MaintUnder = v
+		s.IsNotMaintenable = s.MaintenabilityIndex < v
+	},
+}
+
+// PackageThresholds returns the thresholds overriding the flags for the functions of the package,
+// by the keys of the directives, like max-cyclo. The directives of a function still override them.
+// Main is to define its own logic instead.
+var PackageThresholds = func(pkgPath string) map[string]int { return nil }
+
+// ApplyPackageThresholds overrides the thresholds of the function by those of its package, unknown keys are ignored
+func ApplyPackageThresholds(stats *FuncStatsType, thresholds map[string]int) {
+	for key, v := range thresholds {
+		if override, ok := thresholdOverrides[key]; ok {
+			override(stats, v)
+		}
+	}
+}
+
+// DirectiveCategory is the rule id (diagnostic category) of the warnings about invalid directives,
+// like threshold directives and generated code markers
+const DirectiveCategory = "directive"
+
+// ApplyThresholdDirectives overrides the thresholds of the function by the directives of its doc comment.
+// Invalid directives are reported via warnFnc and ignored.
+func ApplyThresholdDirectives(stats *FuncStatsType, fd *ast.FuncDecl, warnFnc func(pos token.Pos, msg string)) {
+	if fd.Doc == nil {
+		return
+	}
+	for _, c := range fd.Doc.List {
+		text, ok := strings.CutPrefix(c.Text, thresholdDirective)
+		if !ok {
+			continue
+		}
+		directive, _, _ := strings.Cut(text, " ")
+		if directive == "ignore" {
+			continue // see SuppressionOf
+		}
+		key, value, _ := strings.Cut(directive, "=")
+		override, ok := thresholdOverrides[key]
+		if !ok {
+			warnFnc(c.Pos(), fmt.Sprintf("unknown threshold directive %q, valid are: %s", key, strings.Join(thresholdKeys(), ", ")))
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			warnFnc(c.Pos(), fmt.Sprintf("invalid value %q of threshold directive %s, expected a non-negative integer", value, key))
+			continue
+		}
+		override(stats, v)
+	}
+}
+
+func thresholdKeys() []string {
+	keys := []string{}
+	for k := range thresholdOverrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
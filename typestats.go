@@ -0,0 +1,162 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// TypeStatsCategory is the rule id (diagnostic category) of type size findings
+const TypeStatsCategory = "typestats"
+
+var (
+	// TypeStats analyzes the named types of the packages, besides their functions
+	TypeStats bool
+	// MethodsOver is the max number of methods of a type, 0 disables the check
+	MethodsOver int
+	// FieldsOver is the max number of fields of a struct, 0 disables the check
+	FieldsOver int
+	// TypeCycloOver is the max summed cyclomatic complexity of the methods of a type, 0 disables the check
+	TypeCycloOver int
+)
+
+func init() {
+	Analyzer.Flags.BoolVar(&TypeStats, "typestats", false, "analyze the package level named types: their methods, struct fields and interface methods")
+	Analyzer.Flags.IntVar(&MethodsOver, "methodsover", 0, "with -typestats, report types declaring more than N methods, or interfaces listing more than N (0 disables the check)")
+	Analyzer.Flags.IntVar(&FieldsOver, "fieldsover", 0, "with -typestats, report structs with more than N fields (0 disables the check)")
+	Analyzer.Flags.IntVar(&TypeCycloOver, "typecycloover", 0, "with -typestats, report types whose methods sum a cyclomatic complexity over N (0 disables the check)")
+}
+
+// TypeStatsType is statistics of a single named type
+type TypeStatsType struct {
+	Filename string
+	Line     int
+	TypeName string
+	// Kind is struct, interface or other, like a named slice or func type
+	Kind string
+	// Methods are the methods declared with the type as receiver, in any file of the package
+	Methods int
+	// Fields are the fields of a struct, an embedded one counting as one field
+	Fields int
+	// InterfaceMethods are the methods of an interface, an embedded interface counting as one method
+	InterfaceMethods int
+	IsTooManyMethods bool
+	IsTooManyFields  bool
+	// MethodsCyclo and MaxMethodCyclo are the summed and the worst cyclomatic complexity of the methods
+	MethodsCyclo   int
+	MaxMethodCyclo int
+	// MethodsMaint is the average maintainability index of the analyzed methods, 0 without any
+	MethodsMaint float64
+	// MethodsLOC are the summed lines of code of the methods
+	MethodsLOC       int
+	IsTooComplexType bool
+}
+
+// TypeResult is statistics of a single named type along with its declaration position
+type TypeResult struct {
+	Pos token.Pos
+	TypeStatsType
+}
+
+// TypeStatsCallback is called on each processed type statistics, with -typestats.
+// Main is to define its own callback logic instead.
+var TypeStatsCallback = func(s TypeStatsType) {}
+
+// CalcTypeStats returns the statistics of the package level named types declared in the files,
+// in the order of their declarations. The methods are gathered from all files by their receiver type name.
+func CalcTypeStats(fset *token.FileSet, files []*ast.File) []TypeResult {
+	methods := map[string]int{}
+	specs := []*ast.TypeSpec{}
+	for _, f := range files {
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					methods[recvTypeName(d.Recv.List[0].Type)]++
+				}
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, s := range d.Specs {
+					specs = append(specs, s.(*ast.TypeSpec))
+				}
+			}
+		}
+	}
+	res := []TypeResult{}
+	for _, ts := range specs {
+		p := fset.Position(ts.Pos())
+		stats := TypeStatsType{Filename: p.Filename, Line: p.Line, TypeName: ts.Name.Name, Kind: "other", Methods: methods[ts.Name.Name]}
+		switch t := ts.Type.(type) {
+		case *ast.StructType:
+			stats.Kind, stats.Fields = "struct", countFields(t.Fields)
+		case *ast.InterfaceType:
+			stats.Kind, stats.InterfaceMethods = "interface", countFields(t.Methods)
+		}
+		stats.IsTooManyMethods = MethodsOver > 0 && stats.Methods+stats.InterfaceMethods > MethodsOver
+		stats.IsTooManyFields = FieldsOver > 0 && stats.Fields > FieldsOver
+		res = append(res, TypeResult{Pos: ts.Pos(), TypeStatsType: stats})
+	}
+	return res
+}
+
+// aggregateMethods adds up the metrics of the methods to the statistics of their receiver type,
+// pointer and value receivers alike, wherever the type is declared in the package.
+// decls and funcs are the analyzed functions, in the same order.
+func aggregateMethods(types []TypeResult, decls []*ast.FuncDecl, funcs []FuncResult) {
+	byName := map[string]*TypeResult{}
+	analyzed := map[string]int{}
+	for i := range types {
+		byName[types[i].TypeName] = &types[i]
+	}
+	for i, fd := range decls {
+		if fd.Recv == nil || len(fd.Recv.List) == 0 {
+			continue
+		}
+		name := recvTypeName(fd.Recv.List[0].Type)
+		t, ok := byName[name]
+		if !ok {
+			continue
+		}
+		analyzed[name]++
+		f := funcs[i].FuncStatsType
+		t.MethodsCyclo += f.CyclomaticComplexity
+		t.MaxMethodCyclo = max(t.MaxMethodCyclo, f.CyclomaticComplexity)
+		t.MethodsMaint += float64(f.MaintenabilityIndex)
+		t.MethodsLOC += f.LOC
+	}
+	for i := range types {
+		if n := analyzed[types[i].TypeName]; n > 0 {
+			types[i].MethodsMaint /= float64(n)
+		}
+		types[i].IsTooComplexType = TypeCycloOver > 0 && types[i].MethodsCyclo > TypeCycloOver
+	}
+}
+
+// ToTypeDiagnosticMsg is used to form diagnostic message for too big types
+func ToTypeDiagnosticMsg(stats TypeStatsType) (msg string) {
+	if stats.IsTooManyMethods {
+		msg = fmt.Sprintf("type %s seems to do too much (methods=%d)", stats.TypeName, stats.Methods+stats.InterfaceMethods)
+	} else if stats.IsTooManyFields {
+		msg = fmt.Sprintf("type %s seems to hold too much (fields=%d)", stats.TypeName, stats.Fields)
+	} else if stats.IsTooComplexType {
+		msg = fmt.Sprintf("type %s seems to be complex (methods cyclomatic complexity=%d)", stats.TypeName, stats.MethodsCyclo)
+	}
+	return
+}
+
+// reportTypeStats reports the types over the -methodsover, -fieldsover or -typecycloover thresholds, at their declaration
+func reportTypeStats(pass *analysis.Pass, types []TypeResult) {
+	for _, t := range types {
+		if msg := ToTypeDiagnosticMsg(t.TypeStatsType); msg != "" {
+			pass.Report(analysis.Diagnostic{
+				Pos:      t.Pos,
+				Category: TypeStatsCategory,
+				Message:  fmt.Sprintf("%s:%d: %s", t.Filename, t.Line, msg),
+			})
+		}
+	}
+}